@@ -15,6 +15,15 @@ const (
 	OperationFailure   = 1009 // 操作失败
 	RoutingNotExist    = 1010 // 路由不存在
 	NotOnline          = 1011 // 用户不在线
+	AudioNotSupported  = 1012 // 接收方不支持该音频格式
+	UserBlocked        = 1013 // 已将对方加入黑名单
+	AccountLocked      = 1014 // 登录失败次数过多，账号已临时锁定
+	PermissionDenied   = 1015 // 没有权限
+	AppUnavailable     = 1016 // 应用不存在或已停用
+	MessageTooLarge    = 1017 // 消息内容过长
+	TooManyRequests    = 1018 // 请求过于频繁
+	MessageRejected    = 1019 // 消息被拦截
+	SensitiveContent   = 1020 // 内容包含敏感词
 )
 
 // GetErrorMessage 根据错误码 获取错误信息
@@ -34,6 +43,15 @@ func GetErrorMessage(code uint32, message string) string {
 		OperationFailure:   "操作失败",
 		RoutingNotExist:    "路由不存在",
 		NotOnline:          "用户不在线",
+		AudioNotSupported:  "接收方不支持该音频格式",
+		UserBlocked:        "已将对方加入黑名单",
+		AccountLocked:      "登录失败次数过多，账号已临时锁定",
+		PermissionDenied:   "没有权限",
+		AppUnavailable:     "应用不存在或已停用",
+		MessageTooLarge:    "消息内容过长",
+		TooManyRequests:    "请求过于频繁",
+		MessageRejected:    "消息被拦截",
+		SensitiveContent:   "内容包含敏感词",
 	}
 
 	if message == "" {
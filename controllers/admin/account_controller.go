@@ -0,0 +1,382 @@
+// Package admin 管理接口
+package admin
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/helper"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/lib/password"
+	"github.com/link1st/gowebsocket/v2/middleware"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	defaultSessionTTL = 8 * 3600 // 默认管理员登录有效期(秒)
+	maxLoginFailures  = 5        // 锁定前允许的连续失败次数
+	loginLockDuration = 15 * time.Minute
+	passwordMinLen    = 8
+	passwordMaxLen    = 128
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_\-.]{3,32}$`)
+
+// getSessionTTL 管理员登录有效期
+func getSessionTTL() (ttl time.Duration) {
+	seconds := viper.GetInt64("admin.sessionTTL")
+	if seconds <= 0 {
+		seconds = defaultSessionTTL
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// checkPassword 校验密码强度
+func checkPassword(value string) (msg string, ok bool) {
+	length := utf8.RuneCountInString(value)
+	if length < passwordMinLen || length > passwordMaxLen {
+		return fmt.Sprintf("密码长度需要在%d到%d个字符之间", passwordMinLen, passwordMaxLen), false
+	}
+	return "", true
+}
+
+// bootstrapAccount 还没有任何管理员时，使用配置 admin.bootstrap 的账号登录并创建为超级管理员
+func bootstrapAccount(username string, value string) (account *models.AdminAccount, err error) {
+	bootstrapUsername := viper.GetString("admin.bootstrap.username")
+	bootstrapPassword := viper.GetString("admin.bootstrap.password")
+	if bootstrapUsername == "" || username != bootstrapUsername {
+		return nil, nil
+	}
+	if _, ok := checkPassword(bootstrapPassword); !ok {
+		fmt.Println("管理员初始账号密码强度不足，忽略 admin.bootstrap")
+		return nil, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(value), []byte(bootstrapPassword)) != 1 {
+		return nil, nil
+	}
+	count, err := cache.CountAdminAccounts()
+	if err != nil || count > 0 {
+		return nil, err
+	}
+	passwordHash, err := password.Hash(value)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	account = &models.AdminAccount{
+		Username:     username,
+		PasswordHash: passwordHash,
+		Role:         models.AdminRoleSuperAdmin,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	created, err := cache.CreateAdminAccount(account)
+	if err != nil || !created {
+		return nil, err
+	}
+	fmt.Println("管理接口 创建初始超级管理员", username)
+	return account, nil
+}
+
+// LoginRequest 管理员登录请求结构体
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// Login 管理员登录，连续失败后锁定
+func Login(c *gin.Context) {
+	data := make(map[string]interface{})
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+	middleware.SetAuditDetail(c, "username=%s", req.Username)
+
+	account, code, msg := verifyLogin(req.Username, req.Password)
+	if code != common.OK {
+		controllers.Response(c, code, msg, data)
+		middleware.RecordAudit(c, "admin.login", nil)
+		return
+	}
+
+	token := helper.GetRandomID(32)
+	ttl := getSessionTTL()
+	if err := cache.CreateAdminSession(token, account.Username, ttl); err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		middleware.RecordAudit(c, "admin.login", account)
+		return
+	}
+	account.LastLoginAt = time.Now().Unix()
+	_ = cache.SaveAdminAccount(account)
+
+	fmt.Println("管理接口 管理员登录", account.Username, account.Role)
+	data["token"] = token
+	data["expiresIn"] = int64(ttl.Seconds())
+	data["account"] = account.ToMap()
+	controllers.Response(c, common.OK, "登录成功", data)
+	middleware.RecordAudit(c, "admin.login", account)
+}
+
+// verifyLogin 校验管理员用户名和密码
+func verifyLogin(username string, value string) (account *models.AdminAccount, code uint32, msg string) {
+	if ttl := cache.GetAdminLoginLockTTL(username); ttl > 0 {
+		return nil, common.AccountLocked, fmt.Sprintf("登录失败次数过多，请%d秒后再试", int64(ttl.Seconds()))
+	}
+	account, err := cache.GetAdminAccount(username)
+	if err != nil {
+		return nil, common.ServerError, ""
+	}
+	if account == nil {
+		if account, err = bootstrapAccount(username, value); err != nil {
+			return nil, common.ServerError, ""
+		}
+		if account != nil {
+			return account, common.OK, ""
+		}
+	}
+	ok := false
+	if account != nil && !account.Disabled {
+		ok, err = password.Verify(value, account.PasswordHash)
+		if err != nil {
+			fmt.Println("校验管理员密码失败", username, err)
+		}
+	}
+	if !ok {
+		if _, locked := cache.AddAdminLoginFailure(username, maxLoginFailures, loginLockDuration,
+			loginLockDuration); locked {
+			return nil, common.AccountLocked, ""
+		}
+		return nil, common.Unauthorized, "用户名或密码错误"
+	}
+	cache.ClearAdminLoginFailures(username)
+	return account, common.OK, ""
+}
+
+// Logout 管理员退出登录
+func Logout(c *gin.Context) {
+	data := make(map[string]interface{})
+	account := middleware.GetCurrentAdmin(c)
+	if err := cache.DeleteAdminSession(middleware.GetAdminToken(c), account.Username); err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	controllers.Response(c, common.OK, "退出成功", data)
+}
+
+// Me 当前管理员信息
+func Me(c *gin.Context) {
+	data := make(map[string]interface{})
+	data["account"] = middleware.GetCurrentAdmin(c).ToMap()
+	controllers.Response(c, common.OK, "", data)
+}
+
+// ListAccounts 管理员列表
+func ListAccounts(c *gin.Context) {
+	data := make(map[string]interface{})
+	accounts, err := cache.GetAdminAccounts()
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	list := make([]map[string]interface{}, 0, len(accounts))
+	for _, account := range accounts {
+		list = append(list, account.ToMap())
+	}
+	data["accounts"] = list
+	controllers.Response(c, common.OK, "", data)
+}
+
+// AccountRequest 创建、修改管理员请求结构体，修改时不传的字段保持不变
+type AccountRequest struct {
+	Username string  `json:"username"`
+	Password *string `json:"password"`
+	Role     *string `json:"role"`
+	Disabled *bool   `json:"disabled"`
+}
+
+// CreateAccount 创建管理员
+func CreateAccount(c *gin.Context) {
+	data := make(map[string]interface{})
+	var req AccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+	if !usernamePattern.MatchString(req.Username) {
+		controllers.Response(c, common.ParameterIllegal, "用户名只能包含字母、数字、下划线、中划线和点，长度3-32", data)
+		return
+	}
+	if req.Password == nil || req.Role == nil {
+		controllers.Response(c, common.ParameterIllegal, "密码和角色不能为空", data)
+		return
+	}
+	if !models.IsAdminRole(*req.Role) {
+		controllers.Response(c, common.ParameterIllegal, "角色无效", data)
+		return
+	}
+	if msg, ok := checkPassword(*req.Password); !ok {
+		controllers.Response(c, common.ParameterIllegal, msg, data)
+		return
+	}
+	middleware.SetAuditDetail(c, "username=%s role=%s", req.Username, *req.Role)
+
+	passwordHash, err := password.Hash(*req.Password)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	now := time.Now().Unix()
+	account := &models.AdminAccount{
+		Username:     req.Username,
+		PasswordHash: passwordHash,
+		Role:         *req.Role,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if req.Disabled != nil {
+		account.Disabled = *req.Disabled
+	}
+	created, err := cache.CreateAdminAccount(account)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	if !created {
+		controllers.Response(c, common.OperationFailure, "用户名已存在", data)
+		return
+	}
+
+	data["account"] = account.ToMap()
+	controllers.Response(c, common.OK, "创建成功", data)
+}
+
+// hasOtherSuperAdmin 除 username 外是否还有可用的超级管理员，避免所有人失去管理权限
+func hasOtherSuperAdmin(username string) (ok bool, err error) {
+	accounts, err := cache.GetAdminAccounts()
+	if err != nil {
+		return
+	}
+	for _, account := range accounts {
+		if account.Username != username && account.HasRole(models.AdminRoleSuperAdmin) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getAccount 获取路径中的管理员，失败时直接返回错误响应
+func getAccount(c *gin.Context, data map[string]interface{}) (account *models.AdminAccount, ok bool) {
+	account, err := cache.GetAdminAccount(c.Param("username"))
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return nil, false
+	}
+	if account == nil {
+		controllers.Response(c, common.ParameterIllegal, "管理员不存在", data)
+		return nil, false
+	}
+	return account, true
+}
+
+// UpdateAccount 修改管理员角色、密码或停用，修改密码和停用后该管理员需要重新登录
+func UpdateAccount(c *gin.Context) {
+	data := make(map[string]interface{})
+	var req AccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+	account, ok := getAccount(c, data)
+	if !ok {
+		return
+	}
+	wasSuperAdmin := account.HasRole(models.AdminRoleSuperAdmin)
+	logout := false
+	if req.Role != nil {
+		if !models.IsAdminRole(*req.Role) {
+			controllers.Response(c, common.ParameterIllegal, "角色无效", data)
+			return
+		}
+		account.Role = *req.Role
+	}
+	if req.Disabled != nil {
+		account.Disabled = *req.Disabled
+		logout = logout || account.Disabled
+	}
+	if req.Password != nil {
+		if msg, ok := checkPassword(*req.Password); !ok {
+			controllers.Response(c, common.ParameterIllegal, msg, data)
+			return
+		}
+		passwordHash, err := password.Hash(*req.Password)
+		if err != nil {
+			controllers.Response(c, common.ServerError, "", data)
+			return
+		}
+		account.PasswordHash = passwordHash
+		logout = true
+	}
+	if wasSuperAdmin && !account.HasRole(models.AdminRoleSuperAdmin) {
+		ok, err := hasOtherSuperAdmin(account.Username)
+		if err != nil {
+			controllers.Response(c, common.ServerError, "", data)
+			return
+		}
+		if !ok {
+			controllers.Response(c, common.OperationFailure, "至少需要保留一个超级管理员", data)
+			return
+		}
+	}
+	middleware.SetAuditDetail(c, "role=%s disabled=%t passwordChanged=%t", account.Role, account.Disabled,
+		req.Password != nil)
+
+	account.UpdatedAt = time.Now().Unix()
+	if err := cache.SaveAdminAccount(account); err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	if logout {
+		_ = cache.DeleteAdminSessions(account.Username)
+	}
+
+	data["account"] = account.ToMap()
+	controllers.Response(c, common.OK, "修改成功", data)
+}
+
+// DeleteAccount 删除管理员
+func DeleteAccount(c *gin.Context) {
+	data := make(map[string]interface{})
+	account, ok := getAccount(c, data)
+	if !ok {
+		return
+	}
+	if account.HasRole(models.AdminRoleSuperAdmin) {
+		ok, err := hasOtherSuperAdmin(account.Username)
+		if err != nil {
+			controllers.Response(c, common.ServerError, "", data)
+			return
+		}
+		if !ok {
+			controllers.Response(c, common.OperationFailure, "至少需要保留一个超级管理员", data)
+			return
+		}
+	}
+	if err := cache.DeleteAdminAccount(account.Username); err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	controllers.Response(c, common.OK, "删除成功", data)
+}
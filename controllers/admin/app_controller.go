@@ -0,0 +1,362 @@
+// Package admin 管理接口
+package admin
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/helper"
+	"github.com/link1st/gowebsocket/v2/lib/apps"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/models"
+	"github.com/link1st/gowebsocket/v2/servers/websocket"
+)
+
+const (
+	maxAllowedOrigins = 50      // 每个应用最多配置的浏览器来源
+	maxWebhookURLs    = 10      // 每个应用最多配置的回调地址
+	maxMessageSize    = 1 << 20 // 单条消息内容长度上限
+	maxPreSendTimeout = 5000    // 发送前回调超时时间上限(毫秒)
+)
+
+var (
+	validScopes = map[string]bool{
+		models.ScopeSendOne:       true,
+		models.ScopeBroadcast:     true,
+		models.ScopePresenceRead:  true,
+		models.ScopePasswordReset: true,
+	}
+	validWebhookEvents = map[string]bool{
+		"*":                              true,
+		"user.*":                         true,
+		"message.*":                      true,
+		"friend.*":                       true,
+		models.WebhookEventUserLogin:     true,
+		models.WebhookEventUserLogout:    true,
+		models.WebhookEventUserOnline:    true,
+		models.WebhookEventUserOffline:   true,
+		models.WebhookEventMessageSend:   true,
+		models.WebhookEventFriendRequest: true,
+		models.WebhookEventFriendAccept:  true,
+		models.WebhookEventFriendReject:  true,
+		models.WebhookEventFriendDelete:  true,
+	}
+)
+
+// AppRequest 创建、修改应用请求结构体，修改时不传的字段保持不变
+type AppRequest struct {
+	AppID          string              `json:"appID"`
+	Name           *string             `json:"name"`
+	Disabled       *bool               `json:"disabled"`
+	AllowedOrigins []string            `json:"allowedOrigins"`
+	MaxMessageSize *int                `json:"maxMessageSize"`
+	LoginPolicy    *models.LoginPolicy `json:"loginPolicy"`
+	WebhookURLs    []string            `json:"webhookURLs"`
+	WebhookEvents  []string            `json:"webhookEvents"`
+	WebhookSecret  *string             `json:"webhookSecret"`
+	PreSend        *models.PreSendHook `json:"preSend"`
+	RateLimits     *models.RateLimits  `json:"rateLimits"`
+}
+
+// apply 将请求中的字段写入应用配置
+func (r *AppRequest) apply(app *models.App) (msg string, ok bool) {
+	if r.Name != nil {
+		app.Name = *r.Name
+	}
+	if r.Disabled != nil {
+		app.Disabled = *r.Disabled
+	}
+	if r.AllowedOrigins != nil {
+		if len(r.AllowedOrigins) > maxAllowedOrigins {
+			return "浏览器来源过多", false
+		}
+		app.AllowedOrigins = r.AllowedOrigins
+	}
+	if r.MaxMessageSize != nil {
+		if *r.MaxMessageSize < 0 || *r.MaxMessageSize > maxMessageSize {
+			return fmt.Sprintf("消息长度限制需要在0到%d之间", maxMessageSize), false
+		}
+		app.MaxMessageSize = *r.MaxMessageSize
+	}
+	if r.LoginPolicy != nil {
+		app.LoginPolicy = *r.LoginPolicy
+		if app.LoginPolicy.OIDCProviders == nil {
+			app.LoginPolicy.OIDCProviders = []string{}
+		}
+	}
+	if r.WebhookURLs != nil {
+		if len(r.WebhookURLs) > maxWebhookURLs {
+			return "回调地址过多", false
+		}
+		for _, value := range r.WebhookURLs {
+			if !isValidURL(value) {
+				return "回调地址无效: " + value, false
+			}
+		}
+		app.WebhookURLs = r.WebhookURLs
+	}
+	if r.WebhookEvents != nil {
+		for _, event := range r.WebhookEvents {
+			if !validWebhookEvents[event] {
+				return "回调事件无效: " + event, false
+			}
+		}
+		app.WebhookEvents = r.WebhookEvents
+	}
+	if r.WebhookSecret != nil {
+		app.WebhookSecret = *r.WebhookSecret
+	}
+	if r.PreSend != nil {
+		if r.PreSend.URL != "" && !isValidURL(r.PreSend.URL) {
+			return "发送前回调地址无效", false
+		}
+		if r.PreSend.Timeout < 0 || r.PreSend.Timeout > maxPreSendTimeout {
+			return fmt.Sprintf("发送前回调超时时间需要在0到%d毫秒之间", maxPreSendTimeout), false
+		}
+		app.PreSend = *r.PreSend
+	}
+	if r.RateLimits != nil {
+		app.RateLimits = *r.RateLimits
+	}
+	return "", true
+}
+
+// isValidURL 是否为 http、https 地址
+func isValidURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// appInfo 返回给管理端的应用信息，不返回回调签名密钥
+func appInfo(app *models.App) map[string]interface{} {
+	return map[string]interface{}{
+		"appID":            app.AppID,
+		"name":             app.Name,
+		"disabled":         app.Disabled,
+		"allowedOrigins":   app.AllowedOrigins,
+		"maxMessageSize":   app.GetMaxMessageSize(),
+		"loginPolicy":      app.LoginPolicy,
+		"webhookURLs":      app.WebhookURLs,
+		"webhookEvents":    app.WebhookEvents,
+		"hasWebhookSecret": app.WebhookSecret != "",
+		"preSend":          app.PreSend,
+		"rateLimits":       app.RateLimits,
+		"createdAt":        app.CreatedAt,
+		"updatedAt":        app.UpdatedAt,
+	}
+}
+
+// getApp 获取路径中的应用，失败时直接返回错误响应
+func getApp(c *gin.Context, data map[string]interface{}) (app *models.App, ok bool) {
+	app, err := cache.GetApp(c.Param("appID"))
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return nil, false
+	}
+	if app == nil {
+		controllers.Response(c, common.ParameterIllegal, "应用不存在", data)
+		return nil, false
+	}
+	return app, true
+}
+
+// ListApps 应用列表
+func ListApps(c *gin.Context) {
+	data := make(map[string]interface{})
+	list, err := cache.GetApps()
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	appList := make([]map[string]interface{}, 0, len(list))
+	for _, app := range list {
+		appList = append(appList, appInfo(app))
+	}
+	data["apps"] = appList
+	controllers.Response(c, common.OK, "", data)
+}
+
+// GetApp 应用详情
+func GetApp(c *gin.Context) {
+	data := make(map[string]interface{})
+	app, ok := getApp(c, data)
+	if !ok {
+		return
+	}
+	data["app"] = appInfo(app)
+	controllers.Response(c, common.OK, "", data)
+}
+
+// CreateApp 创建应用
+// 注册表为空时任意 appID 都可以使用，创建第一个应用后只有已注册的应用可以登录
+func CreateApp(c *gin.Context) {
+	data := make(map[string]interface{})
+	var req AppRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+	if !models.IsValidAppID(req.AppID) {
+		controllers.Response(c, common.ParameterIllegal, "应用ID只能包含字母、数字、下划线和中划线，长度1-32", data)
+		return
+	}
+
+	app := models.NewApp(req.AppID, "", time.Now().Unix())
+	if msg, ok := req.apply(app); !ok {
+		controllers.Response(c, common.ParameterIllegal, msg, data)
+		return
+	}
+	created, err := cache.CreateApp(app)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	if !created {
+		controllers.Response(c, common.OperationFailure, "应用ID已存在", data)
+		return
+	}
+	apps.Invalidate()
+
+	fmt.Println("管理接口 创建应用", app.AppID)
+	data["app"] = appInfo(app)
+	controllers.Response(c, common.OK, "创建成功", data)
+}
+
+// UpdateApp 修改应用配置，停用应用时断开该应用的连接
+func UpdateApp(c *gin.Context) {
+	data := make(map[string]interface{})
+	var req AppRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+	app, ok := getApp(c, data)
+	if !ok {
+		return
+	}
+	if msg, ok := req.apply(app); !ok {
+		controllers.Response(c, common.ParameterIllegal, msg, data)
+		return
+	}
+	app.UpdatedAt = time.Now().Unix()
+	if err := cache.SaveApp(app); err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	apps.Invalidate()
+	if app.Disabled {
+		websocket.DisconnectApp(app.AppID)
+	}
+
+	fmt.Println("管理接口 修改应用", app.AppID, app.Disabled)
+	data["app"] = appInfo(app)
+	controllers.Response(c, common.OK, "修改成功", data)
+}
+
+// DeleteApp 删除应用及其服务端接口凭证，并断开该应用的连接
+func DeleteApp(c *gin.Context) {
+	data := make(map[string]interface{})
+	app, ok := getApp(c, data)
+	if !ok {
+		return
+	}
+	if err := cache.DeleteApp(app.AppID); err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	apps.Invalidate()
+	websocket.DisconnectApp(app.AppID)
+
+	fmt.Println("管理接口 删除应用", app.AppID)
+	controllers.Response(c, common.OK, "删除成功", data)
+}
+
+// CredentialRequest 创建服务端接口凭证请求结构体
+type CredentialRequest struct {
+	Scopes []string `json:"scopes" binding:"required"`
+}
+
+// CreateCredential 为应用创建服务端接口凭证，secret 只在创建时返回一次
+func CreateCredential(c *gin.Context) {
+	data := make(map[string]interface{})
+	var req CredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !validScopes[scope] {
+			controllers.Response(c, common.ParameterIllegal, "不支持的scope: "+scope, data)
+			return
+		}
+	}
+	app, ok := getApp(c, data)
+	if !ok {
+		return
+	}
+
+	credential := &models.APICredential{
+		AppKey: "ak_" + helper.GetRandomID(16),
+		AppID:  app.AppID,
+		Secret: helper.GetRandomID(32),
+		Scopes: req.Scopes,
+	}
+	if err := cache.SaveAPICredential(credential); err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	apps.Invalidate()
+
+	fmt.Println("管理接口 创建服务端接口凭证", app.AppID, credential.AppKey, credential.Scopes)
+	data["credential"] = credential
+	controllers.Response(c, common.OK, "创建成功，secret只显示一次，请妥善保存", data)
+}
+
+// ListCredentials 应用的服务端接口凭证列表，不返回 secret
+func ListCredentials(c *gin.Context) {
+	data := make(map[string]interface{})
+	app, ok := getApp(c, data)
+	if !ok {
+		return
+	}
+	credentials, err := cache.GetAppCredentials(app.AppID)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	for _, credential := range credentials {
+		credential.Secret = ""
+	}
+	data["credentials"] = credentials
+	controllers.Response(c, common.OK, "", data)
+}
+
+// DeleteCredential 删除服务端接口凭证
+func DeleteCredential(c *gin.Context) {
+	data := make(map[string]interface{})
+	credential, err := cache.GetAPICredential(c.Param("appKey"))
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	if credential == nil || credential.AppID != c.Param("appID") {
+		controllers.Response(c, common.ParameterIllegal, "凭证不存在", data)
+		return
+	}
+	if err = cache.DeleteAPICredential(credential); err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	apps.Invalidate()
+
+	fmt.Println("管理接口 删除服务端接口凭证", credential.AppID, credential.AppKey)
+	controllers.Response(c, common.OK, "删除成功", data)
+}
@@ -0,0 +1,38 @@
+// Package admin 管理接口
+package admin
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+// ListAuditLogs 查询审计日志，按时间倒序
+// 参数: admin 管理员、action 操作名称、startTime/endTime 秒级时间范围、cursor 上一页返回的游标
+func ListAuditLogs(c *gin.Context) {
+	data := make(map[string]interface{})
+	admin := c.Query("admin")
+	action := c.Query("action")
+	startTime, _ := strconv.ParseInt(c.Query("startTime"), 10, 64)
+	endTime, _ := strconv.ParseInt(c.Query("endTime"), 10, 64)
+
+	var filter func(log *models.AuditLog) bool
+	if admin != "" || action != "" {
+		filter = func(log *models.AuditLog) bool {
+			return (admin == "" || log.Admin == admin) && (action == "" || log.Action == action)
+		}
+	}
+	logs, nextCursor, err := cache.GetAuditLogs(c.Query("cursor"), startTime, endTime, filter, getPageSize(c))
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	data["logs"] = logs
+	data["cursor"] = nextCursor
+	controllers.Response(c, common.OK, "", data)
+}
@@ -0,0 +1,88 @@
+// Package admin 管理接口
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/helper"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/middleware"
+	"github.com/link1st/gowebsocket/v2/models"
+	"github.com/link1st/gowebsocket/v2/servers/websocket"
+)
+
+// GetConversationMessages 查看会话消息，按时间倒序分页
+func GetConversationMessages(c *gin.Context) {
+	data := make(map[string]interface{})
+	conversationID := c.Param("conversationID")
+	limit := getPageSize(c)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page <= 0 {
+		page = 1
+	}
+	middleware.SetAuditDetail(c, "conversationID=%s page=%d", conversationID, page)
+
+	chatKey := cache.GetChatHistoryKey(conversationID)
+	total, err := redislib.GetClient().ZCard(c.Request.Context(), chatKey).Result()
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	offset := int64((page - 1) * limit)
+	messageIDs, err := redislib.GetClient().ZRevRange(c.Request.Context(), chatKey, offset,
+		offset+int64(limit)-1).Result()
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	appID := models.GetConversationAppID(conversationID)
+	messages := make([]*models.MessageRecord, 0, len(messageIDs))
+	for _, messageID := range messageIDs {
+		record, err := cache.GetMessage(appID, messageID)
+		if err != nil {
+			continue
+		}
+		messages = append(messages, record)
+	}
+
+	data["messages"] = messages
+	data["total"] = total
+	data["hasMore"] = int64(page*limit) < total
+	controllers.Response(c, common.OK, "", data)
+}
+
+// PurgeConversation 清空会话的全部消息，并通知参与者删除本地消息
+func PurgeConversation(c *gin.Context) {
+	data := make(map[string]interface{})
+	conversationID := c.Param("conversationID")
+	records, err := cache.PurgeConversation(conversationID)
+	middleware.SetAuditDetail(c, "conversationID=%s count=%d", conversationID, len(records))
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+
+	if len(records) > 0 {
+		notice := &models.DeleteNotice{ConversationID: conversationID, Reason: "purged"}
+		for _, record := range records {
+			notice.MessageIDs = append(notice.MessageIDs, record.MessageID)
+		}
+		noticeByte, _ := json.Marshal(notice)
+		record := records[0]
+		for _, userID := range []string{record.FromUserID, record.ToUserID} {
+			_, _ = websocket.SendUserCmdMessage(record.AppID, userID, helper.GetOrderIDTime(), models.MessageCmdDelete,
+				string(noticeByte))
+		}
+	}
+
+	fmt.Println("管理接口 清空会话", middleware.GetCurrentAdmin(c).Username, conversationID, len(records))
+	data["count"] = len(records)
+	controllers.Response(c, common.OK, "清空成功", data)
+}
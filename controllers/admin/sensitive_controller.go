@@ -0,0 +1,132 @@
+// Package admin 管理接口
+package admin
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/lib/sensitive"
+	"github.com/link1st/gowebsocket/v2/middleware"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	reviewNoteMaxLen = 200 // 审核备注最大长度(字符)
+)
+
+// ListSensitiveRuleSets 当前生效的敏感词规则集
+func ListSensitiveRuleSets(c *gin.Context) {
+	data := make(map[string]interface{})
+	data["ruleSets"] = sensitive.GetRuleSets()
+	controllers.Response(c, common.OK, "", data)
+}
+
+// ReloadSensitiveWords 立即重新加载敏感词配置和词库文件，失败时保留之前的词库
+func ReloadSensitiveWords(c *gin.Context) {
+	data := make(map[string]interface{})
+	if err := sensitive.Reload(); err != nil {
+		middleware.SetAuditDetail(c, "error=%s", err.Error())
+		controllers.Response(c, common.OperationFailure, err.Error(), data)
+		return
+	}
+	data["ruleSets"] = sensitive.GetRuleSets()
+	controllers.Response(c, common.OK, "重新加载成功", data)
+}
+
+// ListReviews 审核队列，status=pending 待审核(默认，按提交时间正序)，status=resolved 已审核(按审核时间倒序)
+func ListReviews(c *gin.Context) {
+	data := make(map[string]interface{})
+	pending := c.DefaultQuery("status", models.ReviewStatusPending) == models.ReviewStatusPending
+	page, _ := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if page < 1 {
+		page = 1
+	}
+	limit := int64(getPageSize(c))
+
+	items, total, err := cache.GetReviewItems(pending, (page-1)*limit, limit)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	data["reviews"] = items
+	data["total"] = total
+	data["hasMore"] = page*limit < total
+	controllers.Response(c, common.OK, "", data)
+}
+
+// ResolveReviewRequest 审核请求结构体
+type ResolveReviewRequest struct {
+	Status string `json:"status" binding:"required"` // approved/rejected
+	Note   string `json:"note"`
+}
+
+// ResolveReview 审核内容，驳回的昵称恢复为默认昵称
+func ResolveReview(c *gin.Context) {
+	data := make(map[string]interface{})
+	var req ResolveReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+	if req.Status != models.ReviewStatusApproved && req.Status != models.ReviewStatusRejected {
+		controllers.Response(c, common.ParameterIllegal, "审核状态无效", data)
+		return
+	}
+	if utf8.RuneCountInString(req.Note) > reviewNoteMaxLen {
+		controllers.Response(c, common.ParameterIllegal, "备注过长", data)
+		return
+	}
+	reviewID := c.Param("reviewID")
+	middleware.SetAuditDetail(c, "reviewID=%s status=%s", reviewID, req.Status)
+
+	item, err := cache.GetReviewItem(reviewID)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	if item == nil || item.Status != models.ReviewStatusPending {
+		controllers.Response(c, common.ParameterIllegal, "审核内容不存在或已审核", data)
+		return
+	}
+	item.Status = req.Status
+	item.Note = req.Note
+	item.ReviewedBy = middleware.GetCurrentAdmin(c).Username
+	item.ReviewedAt = time.Now().Unix()
+	ok, err := cache.ResolveReviewItem(item)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	if !ok {
+		controllers.Response(c, common.ParameterIllegal, "审核内容不存在或已审核", data)
+		return
+	}
+
+	if item.Status == models.ReviewStatusRejected && item.Scope == models.SensitiveScopeNickname {
+		resetNickname(item)
+	}
+
+	data["review"] = item
+	controllers.Response(c, common.OK, "审核成功", data)
+}
+
+// resetNickname 昵称被驳回且用户没有再修改过时恢复为默认昵称
+func resetNickname(item *models.ReviewItem) {
+	if item.UserID == "" {
+		return
+	}
+	profile, err := cache.GetUserProfile(item.AppID, item.UserID)
+	if err != nil || profile.Nickname != item.Content {
+		return
+	}
+	_, _ = cache.UpdateUserProfile(item.AppID, profile, map[string]interface{}{
+		"nickname": fmt.Sprintf("用户%s", item.UserID),
+	})
+}
@@ -0,0 +1,77 @@
+// Package admin 管理接口
+package admin
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/helper"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/middleware"
+	"github.com/link1st/gowebsocket/v2/models"
+	"github.com/link1st/gowebsocket/v2/servers/websocket"
+)
+
+// ListNodes 集群节点列表，本节点额外返回连接数等运行状态
+func ListNodes(c *gin.Context) {
+	data := make(map[string]interface{})
+	servers, err := cache.GetServerAll(uint64(time.Now().Unix()))
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	nodes := make([]map[string]interface{}, 0, len(servers))
+	for _, server := range servers {
+		nodes = append(nodes, map[string]interface{}{
+			"ip":      server.Ip,
+			"port":    server.Port,
+			"isLocal": websocket.IsLocal(server),
+		})
+	}
+	data["nodes"] = nodes
+	data["local"] = map[string]interface{}{
+		"server":       websocket.GetServer(),
+		"numGoroutine": runtime.NumGoroutine(),
+		"numCPU":       runtime.NumCPU(),
+		"managerInfo":  websocket.GetManagerInfo(""),
+	}
+	controllers.Response(c, common.OK, "", data)
+}
+
+// AnnouncementRequest 系统公告请求结构体
+type AnnouncementRequest struct {
+	AppID   string `json:"appID" binding:"required"`
+	Content string `json:"content" binding:"required"`
+}
+
+// SendAnnouncement 向应用的全部在线用户发送系统公告
+func SendAnnouncement(c *gin.Context) {
+	data := make(map[string]interface{})
+	var req AnnouncementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+	middleware.SetAuditDetail(c, "appID=%s content=%s", req.AppID, req.Content)
+	if code := websocket.CheckMessageSize(req.AppID, req.Content); code != common.OK {
+		controllers.Response(c, code, "", data)
+		return
+	}
+
+	msgID := helper.GetOrderIDTime()
+	sendResults, err := websocket.SendUserMessageAll(req.AppID, "", msgID, models.MessageCmdAnnouncement, req.Content)
+	if err != nil {
+		data["sendResultsErr"] = err.Error()
+	}
+
+	fmt.Println("管理接口 系统公告", middleware.GetCurrentAdmin(c).Username, req.AppID, msgID)
+	data["msgID"] = msgID
+	data["sendResults"] = sendResults
+	controllers.Response(c, common.OK, "", data)
+}
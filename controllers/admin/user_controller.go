@@ -0,0 +1,174 @@
+// Package admin 管理接口
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/controllers/auth"
+	"github.com/link1st/gowebsocket/v2/lib/apps"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/middleware"
+	"github.com/link1st/gowebsocket/v2/models"
+	"github.com/link1st/gowebsocket/v2/servers/websocket"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	maxSessionList  = 1000 // 在线会话列表最多返回的数量
+)
+
+// getPageSize 读取分页大小
+func getPageSize(c *gin.Context) (limit int) {
+	limit, _ = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageSize)))
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	return
+}
+
+// getAppID 读取必填的 appID 参数，用户数据按应用隔离
+func getAppID(c *gin.Context, data map[string]interface{}) (appID string, ok bool) {
+	appID = c.Query("appID")
+	if !models.IsValidAppID(appID) {
+		controllers.Response(c, common.ParameterIllegal, "appID不能为空", data)
+		return "", false
+	}
+	return appID, true
+}
+
+// getUserSessions 用户在各个应用的在线信息，appID 为空时查询全部已注册的应用
+func getUserSessions(userID string, appID string) (sessions []interface{}) {
+	appIDs := []string{appID}
+	if appID == "" {
+		appIDs = apps.GetAppIDs()
+	}
+	sessions = make([]interface{}, 0)
+	for _, value := range appIDs {
+		userOnline, err := cache.GetUserOnlineInfo(websocket.GetUserKey(value, userID))
+		if err != nil || userOnline == nil || !userOnline.IsOnline() {
+			continue
+		}
+		sessions = append(sessions, userOnline)
+	}
+	return
+}
+
+// ListUsers 按用户ID前缀遍历应用下的用户，nickname 不为空时按昵称前缀搜索(只包含允许昵称搜索的用户)
+func ListUsers(c *gin.Context) {
+	data := make(map[string]interface{})
+	appID, ok := getAppID(c, data)
+	if !ok {
+		return
+	}
+	limit := getPageSize(c)
+	var (
+		userIDs    []string
+		nextCursor uint64
+		err        error
+	)
+	if nickname := c.Query("nickname"); nickname != "" {
+		userIDs, err = cache.SearchUserIDsByNickname(appID, nickname, int64(limit))
+	} else {
+		cursor, _ := strconv.ParseUint(c.DefaultQuery("cursor", "0"), 10, 64)
+		userIDs, nextCursor, err = cache.ScanUserIDs(appID, cursor, c.Query("keyword"), int64(limit))
+	}
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+
+	users := make([]interface{}, 0, len(userIDs))
+	for _, userID := range userIDs {
+		profile, err := cache.GetUserProfile(appID, userID)
+		if err != nil {
+			continue
+		}
+		users = append(users, profile)
+	}
+	data["users"] = users
+	data["cursor"] = strconv.FormatUint(nextCursor, 10)
+	controllers.Response(c, common.OK, "", data)
+}
+
+// GetUser 应用下的用户详情，包含资料、在线会话和登录锁定状态
+func GetUser(c *gin.Context) {
+	data := make(map[string]interface{})
+	appID, ok := getAppID(c, data)
+	if !ok {
+		return
+	}
+	userID := c.Param("userID")
+	profile, err := cache.GetUserProfile(appID, userID)
+	if errors.Is(err, redis.Nil) {
+		controllers.Response(c, common.ParameterIllegal, "用户不存在", data)
+		return
+	}
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	data["user"] = profile
+	data["sessions"] = getUserSessions(userID, appID)
+	data["lockSeconds"] = int64(cache.GetLoginLockTTL(appID, userID).Seconds())
+	controllers.Response(c, common.OK, "", data)
+}
+
+// ListSessions 应用的在线会话
+func ListSessions(c *gin.Context) {
+	data := make(map[string]interface{})
+	appID := c.Query("appID")
+	if appID == "" {
+		controllers.Response(c, common.ParameterIllegal, "appID不能为空", data)
+		return
+	}
+	userIDs := websocket.UserList(appID)
+	total := len(userIDs)
+	if len(userIDs) > maxSessionList {
+		userIDs = userIDs[:maxSessionList]
+	}
+	sessions := make([]interface{}, 0, len(userIDs))
+	for _, userID := range userIDs {
+		sessions = append(sessions, getUserSessions(userID, appID)...)
+	}
+	data["sessions"] = sessions
+	data["total"] = total
+	controllers.Response(c, common.OK, "", data)
+}
+
+// KickUser 踢用户下线，吊销该用户在应用下已签发的全部令牌，需要重新登录
+// 本机连接立即断开，其他节点上的连接由定时任务断开
+func KickUser(c *gin.Context) {
+	data := make(map[string]interface{})
+	appID, ok := getAppID(c, data)
+	if !ok {
+		return
+	}
+	userID := c.Param("userID")
+	middleware.SetAuditDetail(c, "appID=%s userID=%s", appID, userID)
+	if _, err := cache.GetUserProfile(appID, userID); err != nil {
+		if errors.Is(err, redis.Nil) {
+			controllers.Response(c, common.ParameterIllegal, "用户不存在", data)
+			return
+		}
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	if err := auth.RevokeUserTokens(appID, userID); err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+
+	fmt.Println("管理接口 踢用户下线", middleware.GetCurrentAdmin(c).Username, appID, userID)
+	controllers.Response(c, common.OK, "已踢下线", data)
+}
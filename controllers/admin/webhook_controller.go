@@ -0,0 +1,87 @@
+// Package admin 管理接口
+package admin
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/middleware"
+)
+
+const (
+	maxReplayCount = 1000 // 批量重放时最多处理的死信数量
+)
+
+// ListDeadWebhooks 应用投递失败的回调，按失败时间倒序
+func ListDeadWebhooks(c *gin.Context) {
+	data := make(map[string]interface{})
+	page, _ := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if page < 1 {
+		page = 1
+	}
+	limit := int64(getPageSize(c))
+
+	deliveries, total, err := cache.GetDeadWebhookDeliveries(c.Param("appID"), (page-1)*limit, limit)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	data["deliveries"] = deliveries
+	data["total"] = total
+	data["hasMore"] = page*limit < total
+	controllers.Response(c, common.OK, "", data)
+}
+
+// ReplayDeadWebhook 重新投递一个失败的回调，重新计算重试次数
+func ReplayDeadWebhook(c *gin.Context) {
+	data := make(map[string]interface{})
+	appID := c.Param("appID")
+	deliveryID := c.Param("deliveryID")
+	middleware.SetAuditDetail(c, "appID=%s deliveryID=%s", appID, deliveryID)
+
+	ok, err := cache.ReplayWebhookDelivery(appID, deliveryID, time.Now().Unix())
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	if !ok {
+		controllers.Response(c, common.ParameterIllegal, "回调不存在或已重放", data)
+		return
+	}
+	controllers.Response(c, common.OK, "已重新加入投递队列", data)
+}
+
+// ReplayDeadWebhooks 重新投递应用最近失败的回调，单次最多 maxReplayCount 条
+func ReplayDeadWebhooks(c *gin.Context) {
+	data := make(map[string]interface{})
+	appID := c.Param("appID")
+
+	deliveryIDs, err := cache.GetDeadWebhookDeliveryIDs(appID, maxReplayCount)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	now := time.Now().Unix()
+	count := 0
+	for _, deliveryID := range deliveryIDs {
+		var ok bool
+		if ok, err = cache.ReplayWebhookDelivery(appID, deliveryID, now); err != nil {
+			break
+		}
+		if ok {
+			count++
+		}
+	}
+	middleware.SetAuditDetail(c, "appID=%s count=%d", appID, count)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	data["count"] = count
+	controllers.Response(c, common.OK, "已重新加入投递队列", data)
+}
@@ -9,18 +9,39 @@ import (
 
 	"github.com/link1st/gowebsocket/v2/common"
 	"github.com/link1st/gowebsocket/v2/controllers"
-	"github.com/link1st/gowebsocket/v2/lib/jwtlib"
+	"github.com/link1st/gowebsocket/v2/lib/apps"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
 	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/lib/webhook"
+	"github.com/link1st/gowebsocket/v2/middleware"
+	"github.com/link1st/gowebsocket/v2/models"
+	"github.com/link1st/gowebsocket/v2/servers/websocket"
 )
 
+// checkLoginApp 检查应用是否可用以及浏览器来源是否允许，失败时直接返回错误响应
+func checkLoginApp(c *gin.Context, appID string, data map[string]interface{}) (app *models.App, ok bool) {
+	app, code := apps.CheckApp(appID)
+	if code != common.OK {
+		controllers.Response(c, code, "", data)
+		return nil, false
+	}
+	if !app.IsOriginAllowed(c.GetHeader("Origin")) {
+		controllers.Response(c, common.PermissionDenied, "来源不允许", data)
+		return nil, false
+	}
+	return app, true
+}
+
 // LoginRequest 登录请求结构体
 type LoginRequest struct {
 	UserID     string `json:"userID" binding:"required"`
+	Password   string `json:"password"` // 自动开通模式下可以为空
 	AppID      string `json:"appID"`
 	ClientInfo string `json:"clientInfo"`
 }
 
 // Login 用户登录接口
+// 校验密码登录；配置了自动开通的内部应用可以不带密码登录，用户不存在时自动创建
 func Login(c *gin.Context) {
 	var req LoginRequest
 
@@ -45,37 +66,35 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// 检查用户是否存在，不存在则创建
-	userKey := fmt.Sprintf("user:profile:%s", userID)
-	userExists, err := redislib.GetClient().Exists(c.Request.Context(), userKey).Result()
-	if err != nil {
-		fmt.Printf("检查用户存在性失败: %v\n", err)
-		controllers.Response(c, common.ServerError, "服务器错误", data)
+	app, ok := checkLoginApp(c, appID, data)
+	if !ok {
 		return
 	}
 
+	userKey := cache.GetUserProfileKey(appID, userID)
 	isNewUser := false
-	if userExists == 0 {
-		// 创建新用户
-		userProfile := map[string]interface{}{
-			"userID":      userID,
-			"nickname":    fmt.Sprintf("用户%s", userID),
-			"avatar":      "",
-			"createdAt":   time.Now().Format(time.RFC3339),
-			"lastLoginAt": time.Now().Format(time.RFC3339),
+	if req.Password == "" {
+		if !isAutoProvision(c, app) {
+			controllers.Response(c, common.ParameterIllegal, "密码不能为空", data)
+			return
 		}
-
-		err = redislib.GetClient().HMSet(c.Request.Context(), userKey, userProfile).Err()
+		var err error
+		isNewUser, err = provisionUser(c, appID, userID)
 		if err != nil {
-			fmt.Printf("创建用户失败: %v\n", err)
 			controllers.Response(c, common.ServerError, "创建用户失败", data)
 			return
 		}
-		isNewUser = true
-		fmt.Printf("新用户注册: %s\n", userID)
 	} else {
+		if !app.LoginPolicy.AllowPassword {
+			controllers.Response(c, common.PermissionDenied, "该应用不允许密码登录", data)
+			return
+		}
+		if code, msg := verifyPassword(appID, userID, req.Password); code != common.OK {
+			controllers.Response(c, code, msg, data)
+			return
+		}
 		// 更新最后登录时间
-		err = redislib.GetClient().HSet(c.Request.Context(), userKey, "lastLoginAt", time.Now().Format(time.RFC3339)).Err()
+		err := redislib.GetClient().HSet(c.Request.Context(), userKey, "lastLoginAt", time.Now().Format(time.RFC3339)).Err()
 		if err != nil {
 			fmt.Printf("更新用户登录时间失败: %v\n", err)
 		}
@@ -83,35 +102,22 @@ func Login(c *gin.Context) {
 
 	// 获取用户信息
 	userInfo, err := redislib.GetClient().HGetAll(c.Request.Context(), userKey).Result()
-	if err != nil {
+	if err != nil || len(userInfo) == 0 {
 		fmt.Printf("获取用户信息失败: %v\n", err)
 		controllers.Response(c, common.ServerError, "获取用户信息失败", data)
 		return
 	}
 
-	// 生成JWT token
-	token, err := jwtlib.GenerateToken(userID, appID, 24) // 24小时过期
+	// 维护昵称搜索索引(兼容索引上线前注册的用户)
+	_ = cache.IndexUserNickname(appID, models.NewUserProfileFromMap(userInfo))
+
+	tokens, err := issueToken(userID, appID, "")
 	if err != nil {
-		fmt.Printf("生成JWT token失败: %v\n", err)
 		controllers.Response(c, common.ServerError, "生成token失败", data)
 		return
 	}
 
-	// 缓存token到Redis（可选，用于token黑名单等功能）
-	tokenKey := fmt.Sprintf("auth:token:%s", token)
-	tokenData := map[string]interface{}{
-		"userID":  userID,
-		"appID":   appID,
-		"loginAt": time.Now().Unix(),
-	}
-	err = redislib.GetClient().HMSet(c.Request.Context(), tokenKey, tokenData).Err()
-	if err != nil {
-		fmt.Printf("缓存token失败: %v\n", err)
-	}
-	// 设置token过期时间为24小时
-	redislib.GetClient().Expire(c.Request.Context(), tokenKey, 24*time.Hour)
-
-	data["token"] = token
+	tokens.fill(data)
 	data["user"] = map[string]interface{}{
 		"userID":    userInfo["userID"],
 		"appID":     appID, // 添加appID字段
@@ -119,60 +125,105 @@ func Login(c *gin.Context) {
 		"avatar":    userInfo["avatar"],
 		"isNewUser": isNewUser,
 	}
-	data["expiresIn"] = 86400 // 24小时
+	go webhook.Emit(appID, models.WebhookEventUserLogin, map[string]interface{}{
+		"userID":    userID,
+		"isNewUser": isNewUser,
+		"method":    "password",
+	})
 
 	controllers.Response(c, common.OK, "登录成功", data)
 }
 
+// provisionUser 自动开通模式下创建用户，已存在时只更新最后登录时间
+func provisionUser(c *gin.Context, appID string, userID string) (isNewUser bool, err error) {
+	userKey := cache.GetUserProfileKey(appID, userID)
+	userExists, err := redislib.GetClient().Exists(c.Request.Context(), userKey).Result()
+	if err != nil {
+		fmt.Printf("检查用户存在性失败: %v\n", err)
+		return
+	}
+
+	if userExists == 0 {
+		// 创建新用户
+		err = redislib.GetClient().HMSet(c.Request.Context(), userKey, newUserProfile(userID, "")).Err()
+		if err != nil {
+			fmt.Printf("创建用户失败: %v\n", err)
+			return
+		}
+		isNewUser = true
+		fmt.Printf("新用户注册: %s\n", userID)
+	} else {
+		// 更新最后登录时间
+		err = redislib.GetClient().HSet(c.Request.Context(), userKey, "lastLoginAt", time.Now().Format(time.RFC3339)).Err()
+		if err != nil {
+			fmt.Printf("更新用户登录时间失败: %v\n", err)
+			err = nil
+		}
+	}
+	return
+}
+
+// newUserProfile 新用户的资料，昵称为空时使用默认昵称
+func newUserProfile(userID string, nickname string) map[string]interface{} {
+	if nickname == "" {
+		nickname = fmt.Sprintf("用户%s", userID)
+	}
+	return map[string]interface{}{
+		"userID":      userID,
+		"nickname":    nickname,
+		"avatar":      "",
+		"createdAt":   time.Now().Format(time.RFC3339),
+		"lastLoginAt": time.Now().Format(time.RFC3339),
+	}
+}
+
+// LogoutRequest 登出请求结构体
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken" form:"refreshToken"` // 可选，同时吊销刷新令牌
+}
+
 // Logout 用户登出接口
+// 吊销当前token并断开使用该token登录的长连接
 func Logout(c *gin.Context) {
-	token := c.GetHeader("Authorization")
-	if token == "" {
-		token = c.PostForm("token")
-	}
+	auth := middleware.GetAuth(c)
+	var req LogoutRequest
+	_ = c.ShouldBind(&req)
 
-	fmt.Println("API请求 用户登出", token)
+	fmt.Println("API请求 用户登出", auth.UserID)
 
 	data := make(map[string]interface{})
 
-	if token != "" {
-		// 删除token缓存
-		tokenKey := fmt.Sprintf("auth:token:%s", token)
-		err := redislib.GetClient().Del(c.Request.Context(), tokenKey).Err()
-		if err != nil {
-			fmt.Printf("删除token缓存失败: %v\n", err)
+	if auth.ExpiresAt > 0 {
+		if err := cache.RevokeToken(auth.SessionID, time.Until(time.Unix(auth.ExpiresAt, 0))); err != nil {
+			controllers.Response(c, common.ServerError, "登出失败", data)
+			return
+		}
+		websocket.DisconnectToken(auth.AppID, auth.UserID, auth.SessionID)
+	}
+	if req.RefreshToken != "" {
+		// 只吊销当前用户自己的刷新令牌，别人的令牌忽略，不提示是否存在
+		revoked, _ := cache.RevokeRefreshToken(req.RefreshToken, auth.AppID, auth.UserID)
+		if !revoked {
+			fmt.Println("用户登出 刷新令牌不属于当前用户或已失效", auth.AppID, auth.UserID)
 		}
 	}
+	go webhook.Emit(auth.AppID, models.WebhookEventUserLogout, map[string]interface{}{
+		"userID":    auth.UserID,
+		"sessionID": auth.SessionID,
+	})
 
 	controllers.Response(c, common.OK, "登出成功", data)
 }
 
 // GetCurrentUser 获取当前用户信息
 func GetCurrentUser(c *gin.Context) {
-	token := c.GetHeader("Authorization")
-	if token == "" {
-		token = c.Query("token")
-	}
+	auth := middleware.GetAuth(c)
 
-	fmt.Println("API请求 获取当前用户信息", token)
+	fmt.Println("API请求 获取当前用户信息", auth.UserID)
 
 	data := make(map[string]interface{})
 
-	if token == "" {
-		controllers.Response(c, common.Unauthorized, "未授权访问", data)
-		return
-	}
-
-	// 验证token
-	tokenKey := fmt.Sprintf("auth:token:%s", token)
-	tokenData, err := redislib.GetClient().HGetAll(c.Request.Context(), tokenKey).Result()
-	if err != nil || len(tokenData) == 0 {
-		controllers.Response(c, common.Unauthorized, "token无效", data)
-		return
-	}
-
-	userID := tokenData["userID"]
-	userKey := fmt.Sprintf("user:profile:%s", userID)
+	userKey := cache.GetUserProfileKey(auth.AppID, auth.UserID)
 	userInfo, err := redislib.GetClient().HGetAll(c.Request.Context(), userKey).Result()
 	if err != nil {
 		controllers.Response(c, common.ServerError, "获取用户信息失败", data)
@@ -184,6 +235,9 @@ func GetCurrentUser(c *gin.Context) {
 		"nickname": userInfo["nickname"],
 		"avatar":   userInfo["avatar"],
 	}
+	data["appID"] = auth.AppID
+	data["sessionID"] = auth.SessionID
+	data["expiresAt"] = auth.ExpiresAt
 
 	controllers.Response(c, common.OK, "获取成功", data)
 }
@@ -0,0 +1,244 @@
+// Package auth 用户认证接口
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/helper"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/lib/oidc"
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/lib/sensitive"
+	"github.com/link1st/gowebsocket/v2/lib/webhook"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	oidcStateTTL = 10 * time.Minute // 授权码登录的最长时间
+)
+
+// getOIDCProvider 获取身份提供方，失败时直接返回错误响应
+func getOIDCProvider(c *gin.Context, name string, data map[string]interface{}) (provider *oidc.Provider, ok bool) {
+	provider, err := oidc.GetProvider(name)
+	if errors.Is(err, oidc.ErrProviderNotFound) {
+		controllers.Response(c, common.ParameterIllegal, "身份提供方不存在", data)
+		return nil, false
+	}
+	if err != nil {
+		controllers.Response(c, common.ServerError, "身份提供方不可用", data)
+		return nil, false
+	}
+	return provider, true
+}
+
+// OIDCLogin 跳转到身份提供方登录
+func OIDCLogin(c *gin.Context) {
+	data := make(map[string]interface{})
+	provider, ok := getOIDCProvider(c, c.Param("provider"), data)
+	if !ok {
+		return
+	}
+	app, ok := checkLoginApp(c, c.Query("appID"), data)
+	if !ok {
+		return
+	}
+	if !app.IsOIDCAllowed(provider.Name) {
+		controllers.Response(c, common.PermissionDenied, "该应用不允许使用此身份提供方登录", data)
+		return
+	}
+
+	state := helper.GetRandomID(16)
+	oidcState := &models.OIDCState{
+		Provider:     provider.Name,
+		AppID:        c.Query("appID"),
+		Nonce:        helper.GetRandomID(16),
+		CodeVerifier: helper.GetRandomID(32),
+	}
+	authURL, err := provider.AuthCodeURL(state, oidcState.Nonce, oidcState.CodeVerifier)
+	if err != nil {
+		controllers.Response(c, common.OperationFailure, err.Error(), data)
+		return
+	}
+	if err = cache.SaveOIDCState(state, oidcState, oidcStateTTL); err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+
+	fmt.Println("API请求 OIDC登录", provider.Name, oidcState.AppID)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback 身份提供方回调，换取 id_token 后签发 gim token
+func OIDCCallback(c *gin.Context) {
+	data := make(map[string]interface{})
+	if errorCode := c.Query("error"); errorCode != "" {
+		controllers.Response(c, common.Unauthorized, "登录失败: "+errorCode, data)
+		return
+	}
+	oidcState, err := cache.ConsumeOIDCState(c.Query("state"))
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	if oidcState == nil || oidcState.Provider != c.Param("provider") {
+		controllers.Response(c, common.Unauthorized, "登录已过期，请重新登录", data)
+		return
+	}
+	provider, ok := getOIDCProvider(c, oidcState.Provider, data)
+	if !ok {
+		return
+	}
+
+	claims, err := provider.Exchange(c.Query("code"), oidcState.CodeVerifier, oidcState.Nonce)
+	if err != nil {
+		fmt.Println("OIDC 登录验证失败", provider.Name, err)
+		controllers.Response(c, common.Unauthorized, "身份验证失败", data)
+		return
+	}
+	loginExternal(c, provider, claims, oidcState.AppID, data)
+}
+
+// TokenExchangeRequest token exchange 请求结构体
+type TokenExchangeRequest struct {
+	Provider     string `json:"provider" binding:"required"`
+	SubjectToken string `json:"subjectToken" binding:"required"` // 上游服务签发的 JWT
+	AppID        string `json:"appID"`
+}
+
+// TokenExchange 使用可信上游服务签发的 JWT 换取 gim token
+func TokenExchange(c *gin.Context) {
+	data := make(map[string]interface{})
+	var req TokenExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+	provider, ok := getOIDCProvider(c, req.Provider, data)
+	if !ok {
+		return
+	}
+
+	claims, err := provider.VerifyToken(req.SubjectToken)
+	if err != nil {
+		fmt.Println("token exchange 验证失败", provider.Name, err)
+		controllers.Response(c, common.Unauthorized, "subjectToken无效", data)
+		return
+	}
+	loginExternal(c, provider, claims, req.AppID, data)
+}
+
+// loginExternal 外部身份映射到 gim 用户并签发 token，未绑定时按配置自动创建用户
+func loginExternal(c *gin.Context, provider *oidc.Provider, claims *oidc.Claims, appID string,
+	data map[string]interface{}) {
+	app, ok := checkLoginApp(c, appID, data)
+	if !ok {
+		return
+	}
+	if !app.IsOIDCAllowed(provider.Name) {
+		controllers.Response(c, common.PermissionDenied, "该应用不允许使用此身份提供方登录", data)
+		return
+	}
+
+	userID, err := cache.GetIdentityUser(appID, provider.Name, claims.Subject)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+	isNewUser := false
+	if userID == "" {
+		if !provider.AutoCreate {
+			controllers.Response(c, common.Unauthorized, "外部账号未绑定用户", data)
+			return
+		}
+		if userID, err = createExternalUser(provider, claims, appID); err != nil {
+			controllers.Response(c, common.ServerError, "创建用户失败", data)
+			return
+		}
+		isNewUser = true
+	}
+
+	fmt.Println("API请求 外部身份登录", provider.Name, claims.Subject, userID, appID)
+
+	userKey := cache.GetUserProfileKey(appID, userID)
+	err = redislib.GetClient().HSet(c.Request.Context(), userKey, "lastLoginAt", time.Now().Format(time.RFC3339)).Err()
+	if err != nil {
+		fmt.Printf("更新用户登录时间失败: %v\n", err)
+	}
+	userInfo, err := redislib.GetClient().HGetAll(c.Request.Context(), userKey).Result()
+	if err != nil {
+		controllers.Response(c, common.ServerError, "获取用户信息失败", data)
+		return
+	}
+
+	tokens, err := issueToken(userID, appID, "")
+	if err != nil {
+		controllers.Response(c, common.ServerError, "生成token失败", data)
+		return
+	}
+
+	tokens.fill(data)
+	data["user"] = map[string]interface{}{
+		"userID":    userID,
+		"appID":     appID,
+		"nickname":  userInfo["nickname"],
+		"avatar":    userInfo["avatar"],
+		"isNewUser": isNewUser,
+	}
+	go webhook.Emit(appID, models.WebhookEventUserLogin, map[string]interface{}{
+		"userID":    userID,
+		"isNewUser": isNewUser,
+		"method":    provider.Name,
+	})
+	controllers.Response(c, common.OK, "登录成功", data)
+}
+
+// createExternalUser 为外部身份创建用户并绑定
+// 优先使用 userIDClaim 指定的值作为用户ID，已被占用时生成新的用户ID，不会绑定到已有用户
+func createExternalUser(provider *oidc.Provider, claims *oidc.Claims, appID string) (userID string, err error) {
+	nickname := claims.Nickname
+	if utf8.RuneCountInString(nickname) > 32 {
+		nickname = string([]rune(nickname)[:32])
+	}
+	// 外部昵称包含需要拒绝的敏感词时使用默认昵称
+	nickname, code := sensitive.Filter(models.SensitiveScopeNickname, appID, "", "", nickname)
+	if code != common.OK {
+		nickname = ""
+	}
+	candidates := []string{fmt.Sprintf("%s_%s", provider.Name, helper.GetRandomID(8))}
+	if userIDPattern.MatchString(claims.UserID) {
+		candidates = append([]string{claims.UserID}, candidates...)
+	}
+	var profile map[string]interface{}
+	for _, candidate := range candidates {
+		profile = newUserProfile(candidate, nickname)
+		created, err := cache.CreateUser(appID, candidate, "", profile)
+		if err != nil {
+			return "", err
+		}
+		if created {
+			userID = candidate
+			break
+		}
+	}
+	if userID == "" {
+		return "", errors.New("用户ID已被占用")
+	}
+	_ = cache.IndexUserNickname(appID, &models.UserProfile{
+		UserID:           userID,
+		Nickname:         profile["nickname"].(string),
+		SearchByNickname: true,
+	})
+
+	// 并发登录时以先绑定的用户为准
+	userID, err = cache.BindIdentity(appID, provider.Name, claims.Subject, userID)
+	return
+}
@@ -0,0 +1,301 @@
+// Package auth 用户认证接口
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/lib/password"
+	"github.com/link1st/gowebsocket/v2/lib/sensitive"
+	"github.com/link1st/gowebsocket/v2/lib/webhook"
+	"github.com/link1st/gowebsocket/v2/middleware"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	defaultMaxFailures  = 5   // 默认锁定前允许的连续失败次数
+	defaultLockSeconds  = 900 // 默认锁定时长(秒)，也是失败次数的统计窗口
+	passwordMinLen      = 8   // 密码最小长度(字符)
+	passwordMaxLen      = 128 // 密码最大长度(字符)
+	provisionHeaderName = "X-Provision-Secret"
+)
+
+var userIDPattern = regexp.MustCompile(`^[A-Za-z0-9_\-]{3,32}$`)
+
+// getMaxFailures 连续登录失败多少次后锁定
+func getMaxFailures() (maxFailures int64) {
+	maxFailures = viper.GetInt64("auth.maxFailures")
+	if maxFailures <= 0 {
+		maxFailures = defaultMaxFailures
+	}
+	return
+}
+
+// getLockDuration 锁定时长
+func getLockDuration() (duration time.Duration) {
+	seconds := viper.GetInt64("auth.lockSeconds")
+	if seconds <= 0 {
+		seconds = defaultLockSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// isAutoProvision 是否允许免密登录并自动开通账号
+// 需要应用的登录策略开启自动开通，且请求头带上配置的密钥 auth.autoProvision.secret
+func isAutoProvision(c *gin.Context, app *models.App) bool {
+	if !app.LoginPolicy.AutoProvision {
+		return false
+	}
+	secret := viper.GetString("auth.autoProvision.secret")
+	if secret == "" || subtle.ConstantTimeCompare([]byte(c.GetHeader(provisionHeaderName)), []byte(secret)) != 1 {
+		return false
+	}
+	return true
+}
+
+// checkPassword 校验密码强度
+func checkPassword(value string) (msg string, ok bool) {
+	length := utf8.RuneCountInString(value)
+	if length < passwordMinLen || length > passwordMaxLen {
+		return fmt.Sprintf("密码长度需要在%d到%d个字符之间", passwordMinLen, passwordMaxLen), false
+	}
+	return "", true
+}
+
+// verifyPassword 校验登录密码，连续失败后锁定账号
+// 用户不存在和密码错误返回相同的错误，避免探测用户ID
+func verifyPassword(appID string, userID string, value string) (code uint32, msg string) {
+	if ttl := cache.GetLoginLockTTL(appID, userID); ttl > 0 {
+		return common.AccountLocked, fmt.Sprintf("登录失败次数过多，请%d秒后再试", int64(ttl.Seconds()))
+	}
+	passwordHash, err := cache.GetPasswordHash(appID, userID)
+	if err != nil {
+		return common.ServerError, ""
+	}
+	ok := false
+	if passwordHash != "" {
+		ok, err = password.Verify(value, passwordHash)
+		if err != nil {
+			fmt.Println("校验密码失败", userID, err)
+		}
+	}
+	if !ok {
+		_, locked := cache.AddLoginFailure(appID, userID, getMaxFailures(), getLockDuration(), getLockDuration())
+		if locked {
+			return common.AccountLocked, ""
+		}
+		return common.Unauthorized, "用户ID或密码错误"
+	}
+	cache.ClearLoginFailures(appID, userID)
+	return common.OK, ""
+}
+
+// RegisterRequest 注册请求结构体
+type RegisterRequest struct {
+	UserID   string `json:"userID" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Nickname string `json:"nickname"`
+	AppID    string `json:"appID"`
+}
+
+// Register 注册用户并直接登录
+func Register(c *gin.Context) {
+	data := make(map[string]interface{})
+
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+
+	fmt.Println("API请求 用户注册", req.UserID, req.AppID)
+
+	if !userIDPattern.MatchString(req.UserID) {
+		controllers.Response(c, common.ParameterIllegal, "用户ID只能包含字母、数字、下划线和中划线，长度3-32", data)
+		return
+	}
+	if msg, ok := checkPassword(req.Password); !ok {
+		controllers.Response(c, common.ParameterIllegal, msg, data)
+		return
+	}
+	if utf8.RuneCountInString(req.Nickname) > 32 {
+		controllers.Response(c, common.ParameterIllegal, "昵称过长", data)
+		return
+	}
+	app, ok := checkLoginApp(c, req.AppID, data)
+	if !ok {
+		return
+	}
+	if !app.LoginPolicy.AllowRegister {
+		controllers.Response(c, common.PermissionDenied, "该应用不允许注册", data)
+		return
+	}
+	nickname, code := sensitive.Filter(models.SensitiveScopeNickname, req.AppID, req.UserID, "", req.Nickname)
+	if code != common.OK {
+		controllers.Response(c, code, "昵称包含敏感词", data)
+		return
+	}
+
+	passwordHash, err := password.Hash(req.Password)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "注册失败", data)
+		return
+	}
+	profile := newUserProfile(req.UserID, nickname)
+	created, err := cache.CreateUser(req.AppID, req.UserID, passwordHash, profile)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "注册失败", data)
+		return
+	}
+	if !created {
+		controllers.Response(c, common.OperationFailure, "用户ID已被注册", data)
+		return
+	}
+	_ = cache.IndexUserNickname(req.AppID, &models.UserProfile{
+		UserID:           req.UserID,
+		Nickname:         profile["nickname"].(string),
+		SearchByNickname: true,
+	})
+
+	tokens, err := issueToken(req.UserID, req.AppID, "")
+	if err != nil {
+		controllers.Response(c, common.ServerError, "生成token失败", data)
+		return
+	}
+
+	tokens.fill(data)
+	data["user"] = map[string]interface{}{
+		"userID":    req.UserID,
+		"appID":     req.AppID,
+		"nickname":  profile["nickname"],
+		"avatar":    "",
+		"isNewUser": true,
+	}
+	go webhook.Emit(req.AppID, models.WebhookEventUserLogin, map[string]interface{}{
+		"userID":    req.UserID,
+		"isNewUser": true,
+		"method":    "register",
+	})
+	controllers.Response(c, common.OK, "注册成功", data)
+}
+
+// ChangePasswordRequest 修改密码请求结构体
+type ChangePasswordRequest struct {
+	OldPassword string `json:"oldPassword"` // 自动开通的账号首次设置密码时可以为空
+	NewPassword string `json:"newPassword" binding:"required"`
+}
+
+// ChangePassword 修改密码，需要校验旧密码
+func ChangePassword(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+
+	fmt.Println("API请求 修改密码", userID)
+
+	if msg, ok := checkPassword(req.NewPassword); !ok {
+		controllers.Response(c, common.ParameterIllegal, msg, data)
+		return
+	}
+	passwordHash, err := cache.GetPasswordHash(appID, userID)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "修改密码失败", data)
+		return
+	}
+	if passwordHash != "" {
+		if code, msg := verifyPassword(appID, userID, req.OldPassword); code != common.OK {
+			controllers.Response(c, code, msg, data)
+			return
+		}
+	}
+
+	if code := setPassword(appID, userID, req.NewPassword); code != common.OK {
+		controllers.Response(c, code, "修改密码失败", data)
+		return
+	}
+
+	// 其他设备全部下线，当前设备换发新的token
+	if err = RevokeUserTokens(appID, userID); err != nil {
+		controllers.Response(c, common.ServerError, "吊销旧token失败", data)
+		return
+	}
+	tokens, err := issueToken(userID, appID, "")
+	if err != nil {
+		controllers.Response(c, common.ServerError, "生成token失败", data)
+		return
+	}
+	tokens.fill(data)
+	controllers.Response(c, common.OK, "修改成功", data)
+}
+
+// ResetPasswordRequest 重置密码请求结构体
+type ResetPasswordRequest struct {
+	ResetToken  string `json:"resetToken" binding:"required"` // 由服务端接口 /user/password/resetToken 签发
+	NewPassword string `json:"newPassword" binding:"required"`
+}
+
+// ResetPassword 使用重置凭证设置新密码，同时解除登录锁定
+func ResetPassword(c *gin.Context) {
+	data := make(map[string]interface{})
+
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+
+	if msg, ok := checkPassword(req.NewPassword); !ok {
+		controllers.Response(c, common.ParameterIllegal, msg, data)
+		return
+	}
+	appID, userID, err := cache.ConsumePasswordResetToken(req.ResetToken)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "重置密码失败", data)
+		return
+	}
+	if userID == "" {
+		controllers.Response(c, common.Unauthorized, "重置凭证无效或已过期", data)
+		return
+	}
+
+	fmt.Println("API请求 重置密码", appID, userID)
+
+	if code := setPassword(appID, userID, req.NewPassword); code != common.OK {
+		controllers.Response(c, code, "重置密码失败", data)
+		return
+	}
+	cache.ClearLoginFailures(appID, userID)
+	if err = RevokeUserTokens(appID, userID); err != nil {
+		fmt.Println("重置密码 吊销旧token失败", userID, err)
+	}
+	controllers.Response(c, common.OK, "重置成功", data)
+}
+
+func setPassword(appID string, userID string, value string) (code uint32) {
+	passwordHash, err := password.Hash(value)
+	if err != nil {
+		return common.ServerError
+	}
+	if err = cache.SetPasswordHash(appID, userID, passwordHash); err != nil {
+		return common.ModelStoreError
+	}
+	return common.OK
+}
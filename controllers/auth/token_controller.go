@@ -0,0 +1,165 @@
+// Package auth 用户认证接口
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/helper"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/lib/jwtlib"
+	"github.com/link1st/gowebsocket/v2/models"
+	"github.com/link1st/gowebsocket/v2/servers/websocket"
+)
+
+const (
+	defaultAccessTokenTTL  = 3600    // 默认访问令牌有效期(秒)
+	defaultRefreshTokenTTL = 2592000 // 默认刷新令牌有效期(秒)，30天
+)
+
+// getAccessTokenTTL 访问令牌有效期
+func getAccessTokenTTL() (ttl time.Duration) {
+	seconds := viper.GetInt64("auth.accessTokenTTL")
+	if seconds <= 0 {
+		seconds = defaultAccessTokenTTL
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// getRefreshTokenTTL 刷新令牌有效期，每次轮换后顺延
+func getRefreshTokenTTL() (ttl time.Duration) {
+	seconds := viper.GetInt64("auth.refreshTokenTTL")
+	if seconds <= 0 {
+		seconds = defaultRefreshTokenTTL
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// tokenPair 登录凭证: 短期的访问令牌(JWT) + 长期的刷新令牌(随机串，服务端保存)
+type tokenPair struct {
+	AccessToken      string
+	RefreshToken     string
+	ExpiresIn        int64
+	RefreshExpiresIn int64
+}
+
+// fill 写入接口返回数据
+func (t *tokenPair) fill(data map[string]interface{}) {
+	data["token"] = t.AccessToken
+	data["refreshToken"] = t.RefreshToken
+	data["expiresIn"] = t.ExpiresIn
+	data["refreshExpiresIn"] = t.RefreshExpiresIn
+}
+
+// issueToken 生成访问令牌和刷新令牌，familyID 为空时开启新的令牌族
+func issueToken(userID string, appID string, familyID string) (tokens *tokenPair, err error) {
+	accessTTL := getAccessTokenTTL()
+	refreshTTL := getRefreshTokenTTL()
+
+	// 生成JWT token
+	accessToken, _, err := jwtlib.NewToken(userID, appID, accessTTL)
+	if err != nil {
+		fmt.Printf("生成JWT token失败: %v\n", err)
+		return
+	}
+
+	if familyID == "" {
+		familyID = helper.GetRandomID(16)
+		if err = cache.CreateRefreshFamily(familyID, refreshTTL); err != nil {
+			return
+		}
+	}
+	refreshToken := helper.GetRandomID(32)
+	err = cache.SaveRefreshToken(refreshToken, &models.RefreshToken{
+		UserID:   userID,
+		AppID:    appID,
+		FamilyID: familyID,
+	}, refreshTTL)
+	if err != nil {
+		return
+	}
+
+	tokens = &tokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        int64(accessTTL.Seconds()),
+		RefreshExpiresIn: int64(refreshTTL.Seconds()),
+	}
+	return
+}
+
+// RevokeUserTokens 吊销用户已签发的全部访问令牌和刷新令牌，并断开本机上的长连接
+// 其他节点上的长连接由定时任务断开
+func RevokeUserTokens(appID string, userID string) (err error) {
+	ttl := getRefreshTokenTTL()
+	if accessTTL := getAccessTokenTTL(); accessTTL > ttl {
+		ttl = accessTTL
+	}
+	if err = cache.RevokeUserTokens(appID, userID, ttl); err != nil {
+		return
+	}
+	websocket.DisconnectUser(appID, userID)
+	return
+}
+
+// RefreshRequest 刷新令牌请求结构体
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// Refresh 使用刷新令牌换取新的访问令牌和刷新令牌
+// 刷新令牌只能使用一次，旧令牌被再次使用时吊销整个令牌族，需要重新登录
+func Refresh(c *gin.Context) {
+	data := make(map[string]interface{})
+
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+
+	status, refreshToken, err := cache.RotateRefreshToken(req.RefreshToken)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "刷新token失败", data)
+		return
+	}
+	switch status {
+	case models.RefreshTokenRotated:
+	case models.RefreshTokenReused:
+		fmt.Println("API请求 刷新令牌被重复使用，吊销令牌族", refreshToken.UserID, refreshToken.FamilyID)
+		controllers.Response(c, common.Unauthorized, "refreshToken已失效，请重新登录", data)
+		return
+	default:
+		controllers.Response(c, common.Unauthorized, "refreshToken无效或已过期", data)
+		return
+	}
+
+	fmt.Println("API请求 刷新token", refreshToken.UserID, refreshToken.AppID)
+
+	if _, ok := checkLoginApp(c, refreshToken.AppID, data); !ok {
+		return
+	}
+
+	tokens, err := issueToken(refreshToken.UserID, refreshToken.AppID, refreshToken.FamilyID)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "生成token失败", data)
+		return
+	}
+
+	tokens.fill(data)
+	controllers.Response(c, common.OK, "刷新成功", data)
+}
+
+// JWKS 公布签名公钥，供其他服务自行验证 token
+// 返回标准的 JWK Set 格式，HS256 密钥不会公开
+func JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": jwtlib.GetJWKS()})
+}
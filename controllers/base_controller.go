@@ -9,6 +9,9 @@ import (
 	"github.com/link1st/gowebsocket/v2/common"
 )
 
+// ResponseCodeKey 上下文中本次请求返回的错误码，供审计等中间件读取
+const ResponseCodeKey = "responseCode"
+
 type BaseController struct {
 	gin.Context
 }
@@ -16,6 +19,7 @@ type BaseController struct {
 // Response 获取全部请求解析到map
 func Response(c *gin.Context, code uint32, msg string, data map[string]interface{}) {
 	message := common.Response(code, msg, data)
+	c.Set(ResponseCodeKey, code)
 	// 允许跨域
 	c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 	c.Header("Access-Control-Allow-Origin", "*") // 这是允许访问所有域
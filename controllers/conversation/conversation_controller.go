@@ -0,0 +1,197 @@
+// Package conversation 会话管理接口
+package conversation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/helper"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/middleware"
+	"github.com/link1st/gowebsocket/v2/models"
+	"github.com/link1st/gowebsocket/v2/servers/websocket"
+)
+
+const (
+	conversationMaxLimit = 100 // 会话列表每页最多返回的会话数
+)
+
+// GetExpirePolicy 获取会话过期策略
+func GetExpirePolicy(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+	friendID := c.Query("friendID")
+	if friendID == "" {
+		controllers.Response(c, common.ParameterIllegal, "好友ID不能为空", data)
+		return
+	}
+
+	conversationID := models.GetConversationID(appID, userID, friendID)
+	policy, err := cache.GetExpirePolicy(conversationID)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "获取过期策略失败", data)
+		return
+	}
+	if policy == nil {
+		policy, _ = models.NewExpirePolicy(models.ExpirePolicyOff, "", 0)
+	}
+
+	data["conversationID"] = conversationID
+	data["policy"] = policy
+	controllers.Response(c, common.OK, "获取成功", data)
+}
+
+// SetExpirePolicyRequest 设置过期策略请求结构体
+type SetExpirePolicyRequest struct {
+	FriendID string `json:"friendID" binding:"required"`
+	Policy   string `json:"policy" binding:"required"` // off/1d/1w/burn
+}
+
+// SetExpirePolicy 设置会话过期策略，对会话双方生效
+func SetExpirePolicy(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+
+	var req SetExpirePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+
+	fmt.Println("API请求 设置会话过期策略", userID, req.FriendID, req.Policy)
+
+	// 策略对双方生效，只能对好友设置，拉黑后不能再修改
+	isFriend, err := cache.IsFriend(appID, userID, req.FriendID)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "服务器错误", data)
+		return
+	}
+	if !isFriend {
+		controllers.Response(c, common.ParameterIllegal, "不是好友关系", data)
+		return
+	}
+	if code := websocket.CheckBlocked(appID, userID, req.FriendID); code != common.OK {
+		controllers.Response(c, code, "", data)
+		return
+	}
+
+	policy, err := models.NewExpirePolicy(req.Policy, userID, time.Now().Unix())
+	if err != nil {
+		controllers.Response(c, common.ParameterIllegal, err.Error(), data)
+		return
+	}
+
+	conversationID := models.GetConversationID(appID, userID, req.FriendID)
+	if err = cache.SetExpirePolicy(conversationID, policy); err != nil {
+		controllers.Response(c, common.ModelStoreError, "设置过期策略失败", data)
+		return
+	}
+	if !policy.IsBurnAfterRead() {
+		cache.ClearBurnMessages(conversationID, userID, req.FriendID)
+	}
+
+	// 通知对方策略变更
+	policyByte, _ := json.Marshal(map[string]interface{}{
+		"conversationID": conversationID,
+		"policy":         policy,
+	})
+	_, _ = websocket.SendUserCmdMessage(appID, req.FriendID, helper.GetOrderIDTime(), models.MessageCmdPolicy,
+		string(policyByte))
+
+	data["conversationID"] = conversationID
+	data["policy"] = policy
+	controllers.Response(c, common.OK, "设置成功", data)
+}
+
+// GetConversationList 获取最近会话列表
+// archived=1 时返回已归档的会话，置顶会话排在最前
+func GetConversationList(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+	archived := c.Query("archived") == "1" || c.Query("archived") == "true"
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > conversationMaxLimit {
+		limit = conversationMaxLimit
+	}
+
+	conversations, total, err := cache.GetConversations(appID, userID, archived, (page-1)*limit, limit)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "获取会话列表失败", data)
+		return
+	}
+
+	data["conversations"] = conversations
+	data["total"] = total
+	data["hasMore"] = page*limit < total
+	controllers.Response(c, common.OK, "获取成功", data)
+}
+
+// UpdateSettingRequest 修改会话设置请求结构体，未传的字段保持不变
+type UpdateSettingRequest struct {
+	ConversationID string `json:"conversationID" binding:"required"`
+	Pinned         *bool  `json:"pinned"`
+	Muted          *bool  `json:"muted"`
+	Archived       *bool  `json:"archived"`
+}
+
+// UpdateSetting 置顶、免打扰、归档会话
+func UpdateSetting(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+
+	var req UpdateSettingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+
+	fmt.Println("API请求 修改会话设置", userID, req.ConversationID)
+
+	if !models.IsConversationMember(req.ConversationID, appID, userID) {
+		controllers.Response(c, common.ParameterIllegal, "会话不存在", data)
+		return
+	}
+
+	setting, err := cache.GetConversationSetting(appID, userID, req.ConversationID)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "获取会话设置失败", data)
+		return
+	}
+	if req.Pinned != nil {
+		setting.Pinned = *req.Pinned
+	}
+	if req.Muted != nil {
+		setting.Muted = *req.Muted
+	}
+	if req.Archived != nil {
+		setting.Archived = *req.Archived
+	}
+	setting.UpdatedAt = time.Now().Unix()
+	if err = cache.SetConversationSetting(appID, userID, req.ConversationID, setting); err != nil {
+		controllers.Response(c, common.ModelStoreError, "修改会话设置失败", data)
+		return
+	}
+
+	// 同步到用户的其他设备
+	go websocket.PushConversation(appID, userID, req.ConversationID)
+
+	data["conversationID"] = req.ConversationID
+	data["setting"] = setting
+	controllers.Response(c, common.OK, "设置成功", data)
+}
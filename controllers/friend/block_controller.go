@@ -0,0 +1,98 @@
+// Package friend 好友管理接口
+package friend
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/middleware"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+// BlockRequest 拉黑请求结构体
+type BlockRequest struct {
+	UserID string `json:"userID" binding:"required"`
+}
+
+// BlockUser 拉黑用户，对方待处理的好友申请同时被拒绝
+func BlockUser(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+
+	var req BlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+
+	fmt.Println("API请求 拉黑用户", userID, req.UserID)
+
+	if req.UserID == userID {
+		controllers.Response(c, common.ParameterIllegal, "不能拉黑自己", data)
+		return
+	}
+
+	now := time.Now().Unix()
+	if err := cache.BlockUser(appID, userID, req.UserID, now); err != nil {
+		controllers.Response(c, common.ModelStoreError, "拉黑失败", data)
+		return
+	}
+
+	request, err := cache.GetPendingFriendRequest(appID, req.UserID, userID)
+	if err == nil && request != nil {
+		_, _ = cache.UpdateFriendRequestStatus(request, models.FriendRequestRejected, now)
+	}
+
+	data["userID"] = req.UserID
+	data["blockedAt"] = now
+	controllers.Response(c, common.OK, "拉黑成功", data)
+}
+
+// UnblockUser 取消拉黑
+func UnblockUser(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+	targetID := c.Param("userID")
+
+	fmt.Println("API请求 取消拉黑", userID, targetID)
+
+	if err := cache.UnblockUser(appID, userID, targetID); err != nil {
+		controllers.Response(c, common.ModelDeleteError, "取消拉黑失败", data)
+		return
+	}
+
+	data["userID"] = targetID
+	controllers.Response(c, common.OK, "取消拉黑成功", data)
+}
+
+// GetBlockList 获取黑名单
+func GetBlockList(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+
+	list, err := cache.GetBlockList(appID, userID)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "获取黑名单失败", data)
+		return
+	}
+
+	users := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		users = append(users, map[string]interface{}{
+			"userID":    item.Member,
+			"blockedAt": int64(item.Score),
+		})
+	}
+
+	data["users"] = users
+	controllers.Response(c, common.OK, "获取成功", data)
+}
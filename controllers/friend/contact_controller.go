@@ -0,0 +1,257 @@
+// Package friend 好友管理接口
+package friend
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/helper"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/lib/sensitive"
+	"github.com/link1st/gowebsocket/v2/middleware"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	remarkMaxLen    = 32  // 备注名最大长度(字符)
+	tagMaxLen       = 16  // 标签最大长度(字符)
+	tagMaxCount     = 20  // 每个好友最多的标签数
+	groupNameMaxLen = 32  // 分组名称最大长度(字符)
+	groupMaxCount   = 100 // 每个用户最多的分组数
+)
+
+// UpdateFriendMetaRequest 修改好友备注请求结构体，未传的字段保持不变
+type UpdateFriendMetaRequest struct {
+	Remark  *string   `json:"remark"`
+	Tags    *[]string `json:"tags"`
+	Starred *bool     `json:"starred"`
+	Groups  *[]string `json:"groups"`
+}
+
+// UpdateFriendMeta 修改好友的备注、标签、星标、分组
+func UpdateFriendMeta(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+	friendID := c.Param("friendID")
+
+	var req UpdateFriendMetaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+
+	fmt.Println("API请求 修改好友备注", userID, friendID)
+
+	isFriend, err := cache.IsFriend(appID, userID, friendID)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "服务器错误", data)
+		return
+	}
+	if !isFriend {
+		controllers.Response(c, common.ParameterIllegal, "不是好友关系", data)
+		return
+	}
+
+	meta, err := cache.GetFriendMeta(appID, userID, friendID)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "获取好友信息失败", data)
+		return
+	}
+	if req.Remark != nil {
+		remark := strings.TrimSpace(*req.Remark)
+		if utf8.RuneCountInString(remark) > remarkMaxLen {
+			controllers.Response(c, common.ParameterIllegal, "备注名过长", data)
+			return
+		}
+		meta.Remark = remark
+	}
+	if req.Tags != nil {
+		tags, ok := normalizeTags(*req.Tags)
+		if !ok {
+			controllers.Response(c, common.ParameterIllegal, "标签过长或过多", data)
+			return
+		}
+		meta.Tags = tags
+	}
+	if req.Starred != nil {
+		meta.Starred = *req.Starred
+	}
+	if req.Groups != nil {
+		groups := make([]string, 0, len(*req.Groups))
+		for _, groupID := range *req.Groups {
+			if _, err := cache.GetContactGroup(appID, userID, groupID); err != nil {
+				controllers.Response(c, common.ParameterIllegal, "分组不存在", data)
+				return
+			}
+			if !(&models.FriendMeta{Groups: groups}).InGroup(groupID) {
+				groups = append(groups, groupID)
+			}
+		}
+		meta.Groups = groups
+	}
+	meta.UpdatedAt = time.Now().Unix()
+
+	if err = cache.SetFriendMeta(appID, userID, friendID, meta); err != nil {
+		controllers.Response(c, common.ModelStoreError, "修改好友信息失败", data)
+		return
+	}
+
+	data["friendID"] = friendID
+	data["meta"] = meta
+	controllers.Response(c, common.OK, "修改成功", data)
+}
+
+// normalizeTags 去除空白和重复的标签
+func normalizeTags(values []string) (tags []string, ok bool) {
+	tags = make([]string, 0, len(values))
+	seen := make(map[string]bool)
+	for _, value := range values {
+		tag := strings.TrimSpace(value)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > tagMaxLen {
+			return nil, false
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > tagMaxCount {
+		return nil, false
+	}
+	return tags, true
+}
+
+// GetContactGroups 获取好友分组列表
+func GetContactGroups(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+
+	groups, err := cache.GetContactGroups(appID, userID)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "获取好友分组失败", data)
+		return
+	}
+
+	data["groups"] = groups
+	controllers.Response(c, common.OK, "获取成功", data)
+}
+
+// ContactGroupRequest 创建、修改好友分组请求结构体
+type ContactGroupRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// CreateContactGroup 创建好友分组
+func CreateContactGroup(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+
+	name, ok := bindGroupName(c, data)
+	if !ok {
+		return
+	}
+
+	groups, err := cache.GetContactGroups(appID, userID)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "获取好友分组失败", data)
+		return
+	}
+	if len(groups) >= groupMaxCount {
+		controllers.Response(c, common.OperationFailure, "分组数量已达上限", data)
+		return
+	}
+
+	group := &models.ContactGroup{
+		GroupID:   helper.GetRandomID(8),
+		Name:      name,
+		CreatedAt: time.Now().Unix(),
+	}
+	if err = cache.SetContactGroup(appID, userID, group); err != nil {
+		controllers.Response(c, common.ModelStoreError, "创建好友分组失败", data)
+		return
+	}
+
+	data["group"] = group
+	controllers.Response(c, common.OK, "创建成功", data)
+}
+
+// RenameContactGroup 修改好友分组名称
+func RenameContactGroup(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+	groupID := c.Param("groupID")
+
+	name, ok := bindGroupName(c, data)
+	if !ok {
+		return
+	}
+
+	group, err := cache.GetContactGroup(appID, userID, groupID)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			controllers.Response(c, common.ParameterIllegal, "分组不存在", data)
+			return
+		}
+		controllers.Response(c, common.ServerError, "获取好友分组失败", data)
+		return
+	}
+	group.Name = name
+	if err = cache.SetContactGroup(appID, userID, group); err != nil {
+		controllers.Response(c, common.ModelStoreError, "修改好友分组失败", data)
+		return
+	}
+
+	data["group"] = group
+	controllers.Response(c, common.OK, "修改成功", data)
+}
+
+// DeleteContactGroup 删除好友分组，分组内的好友不受影响
+func DeleteContactGroup(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+	groupID := c.Param("groupID")
+
+	if err := cache.DelContactGroup(appID, userID, groupID); err != nil {
+		controllers.Response(c, common.ModelDeleteError, "删除好友分组失败", data)
+		return
+	}
+
+	data["groupID"] = groupID
+	controllers.Response(c, common.OK, "删除成功", data)
+}
+
+func bindGroupName(c *gin.Context, data map[string]interface{}) (name string, ok bool) {
+	var req ContactGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+	name = strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > groupNameMaxLen {
+		controllers.Response(c, common.ParameterIllegal, "分组名称不能为空或过长", data)
+		return
+	}
+	name, code := sensitive.Filter(models.SensitiveScopeGroup, middleware.GetCurrentAppID(c),
+		middleware.GetCurrentUserID(c), c.Param("groupID"), name)
+	if code != common.OK {
+		controllers.Response(c, code, "分组名称包含敏感词", data)
+		return
+	}
+	return name, true
+}
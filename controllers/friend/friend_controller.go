@@ -2,67 +2,88 @@
 package friend
 
 import (
-	"context"
 	"fmt"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/link1st/gowebsocket/v2/common"
 	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
 	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/lib/webhook"
+	"github.com/link1st/gowebsocket/v2/middleware"
+	"github.com/link1st/gowebsocket/v2/models"
 	"github.com/link1st/gowebsocket/v2/servers/websocket"
 )
 
 // GetFriendList 获取好友列表
+// 可选过滤: groupID 好友分组 tag 标签 starred=1 星标好友
 func GetFriendList(c *gin.Context) {
 	data := make(map[string]interface{})
-	userRow, exists := c.Get("userID")
-	if !exists {
-		controllers.Response(c, common.Unauthorized, "未授权访问", data)
-		return
-	}
-	appRow, exists := c.Get("appID")
-	if !exists {
-		controllers.Response(c, common.Unauthorized, "未授权访问", data)
-		return
-	}
-	appID, _ := appRow.(string)
-	userID, _ := userRow.(string)
+	appID := middleware.GetCurrentAppID(c)
+	userID := middleware.GetCurrentUserID(c)
 	fmt.Println("获取好友列表", appID, userID)
 
 	// 获取好友列表
-	friendsKey := fmt.Sprintf("user:friends:%s", userID)
-	friendIDs, err := redislib.GetClient().SMembers(c.Request.Context(), friendsKey).Result()
+	friendIDs, err := cache.GetFriendIDs(appID, userID)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "获取好友列表失败", data)
+		return
+	}
+
+	// 备注、标签、分组
+	metas, err := cache.GetFriendMetas(appID, userID)
 	if err != nil {
-		fmt.Printf("获取好友列表失败: %v\n", err)
 		controllers.Response(c, common.ServerError, "获取好友列表失败", data)
 		return
 	}
+	groupID := c.Query("groupID")
+	tag := c.Query("tag")
+	starred := c.Query("starred") == "1" || c.Query("starred") == "true"
 
 	var friends []map[string]interface{}
 
 	fmt.Println("friendIDs", friendIDs)
 	for _, friendID := range friendIDs {
+		meta, ok := metas[friendID]
+		if !ok {
+			meta = &models.FriendMeta{Tags: []string{}, Groups: []string{}}
+		}
+		if groupID != "" && !meta.InGroup(groupID) {
+			continue
+		}
+		if (tag != "" && !meta.HasTag(tag)) || (starred && !meta.Starred) {
+			continue
+		}
+
 		// 获取好友基本信息
-		friendKey := fmt.Sprintf("user:profile:%s", friendID)
+		friendKey := cache.GetUserProfileKey(appID, friendID)
 		friendInfo, err := redislib.GetClient().HGetAll(c.Request.Context(), friendKey).Result()
 		if err != nil || len(friendInfo) == 0 {
 			continue
 		}
 
-		// 检查好友在线状态
-		isOnline := websocket.CheckUserOnline(appID, friendID)
+		// 检查好友在线状态，拉黑关系下不展示
+		isOnline, lastSeen := false, ""
+		if !websocket.IsPresenceHidden(appID, userID, friendID) {
+			isOnline = websocket.CheckUserOnline(appID, friendID)
+			lastSeen = friendInfo["lastLoginAt"]
+		}
 
 		// 获取未读消息数量
-		unreadCount := getUnreadCount(userID, friendID)
+		unreadCount := getUnreadCount(appID, userID, friendID)
 
 		friendData := map[string]interface{}{
 			"userID":      friendInfo["userID"],
 			"nickname":    friendInfo["nickname"],
 			"avatar":      friendInfo["avatar"],
 			"isOnline":    isOnline,
-			"lastSeen":    friendInfo["lastLoginAt"],
+			"lastSeen":    lastSeen,
 			"unreadCount": unreadCount,
+			"remark":      meta.Remark,
+			"tags":        meta.Tags,
+			"starred":     meta.Starred,
+			"groups":      meta.Groups,
 		}
 
 		friends = append(friends, friendData)
@@ -76,160 +97,42 @@ func GetFriendList(c *gin.Context) {
 	controllers.Response(c, common.OK, "获取成功", data)
 }
 
-// AddFriendRequest 添加好友请求结构体
-type AddFriendRequest struct {
-	FriendID string `json:"friendID" binding:"required"`
-}
-
-// AddFriend 添加好友
-func AddFriend(c *gin.Context) {
-	data := make(map[string]interface{})
-	userID, exists := c.Get("userID")
-	if !exists {
-		controllers.Response(c, common.Unauthorized, "未授权访问", data)
-		return
-	}
-
-	var req AddFriendRequest
-	// 绑定JSON请求参数
-	if err := c.ShouldBindJSON(&req); err != nil {
-		fmt.Printf("参数绑定失败: %v\n", err)
-		data := make(map[string]interface{})
-		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
-		return
-	}
-
-	friendID := req.FriendID
-	fmt.Println("API请求 添加好友", friendID)
-
-	if friendID == "" {
-		controllers.Response(c, common.ParameterIllegal, "好友ID不能为空", data)
-		return
-	}
-
-	if userID == friendID {
-		controllers.Response(c, common.ParameterIllegal, "不能添加自己为好友", data)
-		return
-	}
-
-	// 检查要添加的用户是否存在
-	friendKey := fmt.Sprintf("user:profile:%s", friendID)
-	friendExists, err := redislib.GetClient().Exists(c.Request.Context(), friendKey).Result()
-	if err != nil {
-		controllers.Response(c, common.ServerError, "服务器错误", data)
-		return
-	}
-
-	if friendExists == 0 {
-		controllers.Response(c, common.ParameterIllegal, "用户不存在", data)
-		return
-	}
-
-	// 检查是否已经是好友
-	friendsKey := fmt.Sprintf("user:friends:%s", userID)
-	isFriend, err := redislib.GetClient().SIsMember(c.Request.Context(), friendsKey, friendID).Result()
-	if err != nil {
-		controllers.Response(c, common.ServerError, "服务器错误", data)
-		return
-	}
-
-	if isFriend {
-		controllers.Response(c, common.ParameterIllegal, "已经是好友关系", data)
-		return
-	}
-
-	// 添加好友关系 (双向)
-	userFriendsKey := fmt.Sprintf("user:friends:%s", userID)
-	friendFriendsKey := fmt.Sprintf("user:friends:%s", friendID)
-
-	err = redislib.GetClient().SAdd(c.Request.Context(), userFriendsKey, friendID).Err()
-	if err != nil {
-		controllers.Response(c, common.ServerError, "添加好友失败", data)
-		return
-	}
-
-	err = redislib.GetClient().SAdd(c.Request.Context(), friendFriendsKey, userID).Err()
-	if err != nil {
-		// 回滚
-		redislib.GetClient().SRem(c.Request.Context(), userFriendsKey, friendID)
-		controllers.Response(c, common.ServerError, "添加好友失败", data)
-		return
-	}
-
-	// 获取新添加的好友信息
-	friendInfo, err := redislib.GetClient().HGetAll(c.Request.Context(), friendKey).Result()
-	if err != nil {
-		controllers.Response(c, common.ServerError, "获取好友信息失败", data)
-		return
-	}
-
-	data["friend"] = map[string]interface{}{
-		"userID":      friendInfo["userID"],
-		"nickname":    friendInfo["nickname"],
-		"avatar":      friendInfo["avatar"],
-		"isOnline":    false,
-		"lastSeen":    friendInfo["lastLoginAt"],
-		"unreadCount": 0,
-	}
-
-	controllers.Response(c, common.OK, "添加好友成功", data)
-}
-
 // DeleteFriend 删除好友
 func DeleteFriend(c *gin.Context) {
-	token := c.GetHeader("Authorization")
-	if token == "" {
-		token = c.Query("token")
-	}
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
 	friendID := c.Param("friendID")
 
-	fmt.Println("API请求 删除好友", token, friendID)
+	fmt.Println("API请求 删除好友", userID, friendID)
 
 	data := make(map[string]interface{})
 
-	if token == "" {
-		controllers.Response(c, common.Unauthorized, "未授权访问", data)
-		return
-	}
-
 	if friendID == "" {
 		controllers.Response(c, common.ParameterIllegal, "好友ID不能为空", data)
 		return
 	}
 
-	// 验证token并获取用户ID
-	tokenKey := fmt.Sprintf("auth:token:%s", token)
-	tokenData, err := redislib.GetClient().HGetAll(c.Request.Context(), tokenKey).Result()
-	if err != nil || len(tokenData) == 0 {
-		controllers.Response(c, common.Unauthorized, "token无效", data)
-		return
-	}
-
-	userID := tokenData["userID"]
-
 	// 删除好友关系 (双向)
-	userFriendsKey := fmt.Sprintf("user:friends:%s", userID)
-	friendFriendsKey := fmt.Sprintf("user:friends:%s", friendID)
-
-	err = redislib.GetClient().SRem(c.Request.Context(), userFriendsKey, friendID).Err()
-	if err != nil {
+	if err := cache.RemoveFriend(appID, userID, friendID); err != nil {
 		controllers.Response(c, common.ServerError, "删除好友失败", data)
 		return
 	}
 
-	err = redislib.GetClient().SRem(c.Request.Context(), friendFriendsKey, userID).Err()
-	if err != nil {
-		fmt.Printf("删除对方好友关系失败: %v\n", err)
-	}
+	// 清除双方对彼此的备注、标签、分组
+	_ = cache.DelFriendMeta(appID, userID, friendID)
+	_ = cache.DelFriendMeta(appID, friendID, userID)
+
+	go webhook.Emit(appID, models.WebhookEventFriendDelete, map[string]interface{}{
+		"userID":   userID,
+		"friendID": friendID,
+	})
 
 	controllers.Response(c, common.OK, "删除好友成功", data)
 }
 
-// 获取未读消息数量 (简化实现)
-func getUnreadCount(userID, friendID string) int {
-	// 这里简化实现，实际项目中应该从消息记录中统计
-	unreadKey := fmt.Sprintf("message:unread:%s:%s", userID, friendID)
-	count, err := redislib.GetClient().Get(context.Background(), unreadKey).Int()
+// 获取未读消息数量
+func getUnreadCount(appID, userID, friendID string) int64 {
+	count, err := cache.GetUnreadCount(appID, userID, models.GetConversationID(appID, userID, friendID))
 	if err != nil {
 		return 0
 	}
@@ -0,0 +1,240 @@
+// Package friend 好友管理接口
+package friend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/helper"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/lib/webhook"
+	"github.com/link1st/gowebsocket/v2/middleware"
+	"github.com/link1st/gowebsocket/v2/models"
+	"github.com/link1st/gowebsocket/v2/servers/websocket"
+)
+
+const (
+	defaultRequestTTL = 7 * 24 * 60 * 60 // 好友申请默认有效期(秒)
+	greetingMaxLen    = 100              // 验证消息最大长度(字符)
+	requestMaxLimit   = 50               // 申请列表每页最多返回的条数
+)
+
+// getRequestTTL 好友申请有效期(秒)，过期未处理的申请自动失效
+func getRequestTTL() (ttl int64) {
+	ttl = viper.GetInt64("friend.requestTTL")
+	if ttl <= 0 {
+		ttl = defaultRequestTTL
+	}
+	return
+}
+
+// AddFriendRequest 添加好友请求结构体
+type AddFriendRequest struct {
+	FriendID string `json:"friendID" binding:"required"`
+	Greeting string `json:"greeting"` // 验证消息
+}
+
+// AddFriend 发送好友申请，对方同意后才建立好友关系
+// 对方已经向自己发出了待处理的申请时直接成为好友
+func AddFriend(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+
+	var req AddFriendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+
+	friendID := req.FriendID
+	fmt.Println("API请求 添加好友", userID, friendID)
+
+	if userID == friendID {
+		controllers.Response(c, common.ParameterIllegal, "不能添加自己为好友", data)
+		return
+	}
+	if utf8.RuneCountInString(req.Greeting) > greetingMaxLen {
+		controllers.Response(c, common.ParameterIllegal, "验证消息过长", data)
+		return
+	}
+
+	// 检查要添加的用户是否存在
+	friendKey := cache.GetUserProfileKey(appID, friendID)
+	friendExists, err := redislib.GetClient().Exists(c.Request.Context(), friendKey).Result()
+	if err != nil {
+		controllers.Response(c, common.ServerError, "服务器错误", data)
+		return
+	}
+	if friendExists == 0 {
+		controllers.Response(c, common.ParameterIllegal, "用户不存在", data)
+		return
+	}
+
+	// 检查黑名单
+	if code := websocket.CheckBlocked(appID, userID, friendID); code != common.OK {
+		controllers.Response(c, code, "", data)
+		return
+	}
+
+	// 检查是否已经是好友
+	isFriend, err := cache.IsFriend(appID, userID, friendID)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "服务器错误", data)
+		return
+	}
+	if isFriend {
+		controllers.Response(c, common.ParameterIllegal, "已经是好友关系", data)
+		return
+	}
+
+	now := time.Now().Unix()
+
+	// 对方已申请添加自己，视为同意对方的申请
+	reverse, err := cache.GetPendingFriendRequest(appID, friendID, userID)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "服务器错误", data)
+		return
+	}
+	if reverse != nil {
+		if updated, err := cache.UpdateFriendRequestStatus(reverse, models.FriendRequestAccepted, now); err == nil &&
+			updated {
+			notifyRequest(appID, reverse.FromUserID, models.MessageCmdFriendAccepted, reverse)
+			go webhook.Emit(appID, models.WebhookEventFriendAccept, reverse)
+			data["request"] = reverse
+			controllers.Response(c, common.OK, "添加好友成功", data)
+			return
+		}
+	}
+
+	// 已有待处理的申请时刷新验证消息和有效期
+	request, err := cache.GetPendingFriendRequest(appID, userID, friendID)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "服务器错误", data)
+		return
+	}
+	ttl := getRequestTTL()
+	if request != nil && request.IsPending(now) {
+		request.Greeting = req.Greeting
+		request.UpdatedAt = now
+		request.ExpireAt = now + ttl
+	} else {
+		request = models.NewFriendRequest(helper.GetRandomID(16), appID, userID, friendID, req.Greeting, now,
+			ttl)
+	}
+	if err = cache.SaveFriendRequest(request); err != nil {
+		controllers.Response(c, common.ModelStoreError, "发送好友申请失败", data)
+		return
+	}
+
+	notifyRequest(appID, friendID, models.MessageCmdFriendRequest, request)
+	go webhook.Emit(appID, models.WebhookEventFriendRequest, request)
+
+	data["request"] = request
+	controllers.Response(c, common.OK, "好友申请已发送", data)
+}
+
+// GetFriendRequests 获取好友申请列表
+// direction=incoming 收到的申请(默认)，direction=outgoing 发出的申请
+func GetFriendRequests(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+	incoming := c.DefaultQuery("direction", "incoming") != "outgoing"
+	page, _ := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	limit, _ := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > requestMaxLimit {
+		limit = requestMaxLimit
+	}
+
+	requests, total, err := cache.GetFriendRequests(appID, userID, incoming, (page-1)*limit, limit)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "获取好友申请失败", data)
+		return
+	}
+
+	data["requests"] = requests
+	data["total"] = total
+	data["hasMore"] = page*limit < total
+	controllers.Response(c, common.OK, "获取成功", data)
+}
+
+// AcceptFriendRequest 同意好友申请，建立双向好友关系并通知申请人
+func AcceptFriendRequest(c *gin.Context) {
+	handleFriendRequest(c, models.FriendRequestAccepted)
+}
+
+// RejectFriendRequest 拒绝好友申请，不通知申请人
+func RejectFriendRequest(c *gin.Context) {
+	handleFriendRequest(c, models.FriendRequestRejected)
+}
+
+func handleFriendRequest(c *gin.Context, status string) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+	requestID := c.Param("requestID")
+
+	fmt.Println("API请求 处理好友申请", userID, requestID, status)
+
+	request, err := cache.GetFriendRequest(requestID)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			controllers.Response(c, common.ParameterIllegal, "好友申请不存在", data)
+			return
+		}
+		controllers.Response(c, common.ServerError, "获取好友申请失败", data)
+		return
+	}
+	if request.AppID != appID || request.ToUserID != userID {
+		controllers.Response(c, common.ParameterIllegal, "好友申请不存在", data)
+		return
+	}
+
+	updated, err := cache.UpdateFriendRequestStatus(request, status, time.Now().Unix())
+	if err != nil {
+		controllers.Response(c, common.ModelStoreError, "处理好友申请失败", data)
+		return
+	}
+	if !updated {
+		controllers.Response(c, common.OperationFailure, "好友申请已处理或已过期", data)
+		return
+	}
+
+	if status == models.FriendRequestAccepted {
+		notifyRequest(appID, request.FromUserID, models.MessageCmdFriendAccepted, request)
+		go webhook.Emit(appID, models.WebhookEventFriendAccept, request)
+	} else {
+		go webhook.Emit(appID, models.WebhookEventFriendReject, request)
+	}
+
+	data["request"] = request
+	controllers.Response(c, common.OK, "处理成功", data)
+}
+
+// notifyRequest 实时通知好友申请变化
+func notifyRequest(appID string, userID string, cmd string, request *models.FriendRequest) {
+	requestByte, err := json.Marshal(request)
+	if err != nil {
+		fmt.Println("通知好友申请 json Marshal", request.RequestID, err)
+		return
+	}
+	go func() {
+		_, _ = websocket.SendUserCmdMessage(appID, userID, helper.GetOrderIDTime(), cmd, string(requestByte))
+	}()
+}
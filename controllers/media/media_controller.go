@@ -0,0 +1,196 @@
+// Package media 媒体文件接口
+package media
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/helper"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/lib/media"
+	"github.com/link1st/gowebsocket/v2/middleware"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+// Upload 上传图片
+func Upload(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+	if userID == "" {
+		controllers.Response(c, common.Unauthorized, "未授权访问", data)
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		controllers.Response(c, common.ParameterIllegal, "缺少上传文件", data)
+		return
+	}
+	if fileHeader.Size > media.GetMaxSize() {
+		controllers.Response(c, common.ParameterIllegal, "文件过大", data)
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		controllers.Response(c, common.ServerError, "读取上传文件失败", data)
+		return
+	}
+	defer func() { _ = file.Close() }()
+	content, err := media.ReadLimit(file)
+	if err != nil {
+		if errors.Is(err, media.ErrFileTooLarge) {
+			controllers.Response(c, common.ParameterIllegal, "文件过大", data)
+			return
+		}
+		controllers.Response(c, common.ServerError, "读取上传文件失败", data)
+		return
+	}
+
+	fmt.Println("API请求 上传图片", appID, userID, fileHeader.Filename, len(content))
+
+	contentType, ext, width, height, err := media.DetectImage(content)
+	if err != nil {
+		fmt.Println("上传图片 格式不支持", userID, err)
+		controllers.Response(c, common.ParameterIllegal, "不支持的图片格式", data)
+		return
+	}
+	if err = media.CheckPixels(width, height); err != nil {
+		fmt.Println("上传图片 尺寸过大", userID, width, height)
+		controllers.Response(c, common.ParameterIllegal, "图片尺寸过大", data)
+		return
+	}
+
+	// 去除位置等隐私元数据
+	content, err = media.StripMetadata(contentType, content)
+	if err != nil {
+		fmt.Println("上传图片 去除元数据失败", userID, err)
+		controllers.Response(c, common.ParameterIllegal, "图片数据损坏", data)
+		return
+	}
+
+	mediaID := helper.GetRandomID(16)
+	mediaInfo := models.NewImageMedia(mediaID, userID, appID, contentType, int64(len(content)), time.Now().Unix())
+	mediaInfo.Width = width
+	mediaInfo.Height = height
+	mediaInfo.File = mediaID + ext
+	if err = media.SaveFile(mediaInfo.File, content); err != nil {
+		controllers.Response(c, common.ServerError, "保存文件失败", data)
+		return
+	}
+	if err = cache.SetMediaInfo(mediaInfo); err != nil {
+		controllers.Response(c, common.ModelStoreError, "保存文件信息失败", data)
+		return
+	}
+
+	// 异步生成缩略图
+	if err = media.Enqueue(mediaID); err != nil {
+		fmt.Println("上传图片 加入处理队列失败", mediaID, err)
+		mediaInfo.Status = models.MediaStatusFailed
+		_ = cache.SetMediaInfo(mediaInfo)
+	}
+
+	data["media"] = mediaInfo
+	controllers.Response(c, common.OK, "上传成功", data)
+}
+
+// canAccess 同一应用下的上传者和引用该文件的会话参与者可以访问，头像对应用内的用户公开
+func canAccess(mediaInfo *models.MediaInfo, appID string, userID string) bool {
+	if mediaInfo.AppID != appID {
+		return false
+	}
+	if mediaInfo.OwnerID == userID || mediaInfo.Avatar {
+		return true
+	}
+	conversationIDs, err := cache.GetMediaConversations(mediaInfo.MediaID)
+	if err != nil {
+		return false
+	}
+	for _, conversationID := range conversationIDs {
+		if models.IsConversationMember(conversationID, appID, userID) {
+			return true
+		}
+	}
+	return false
+}
+
+// getMediaInfo 获取有权限访问的媒体元数据，没有权限和不存在返回相同的错误
+func getMediaInfo(c *gin.Context) (mediaInfo *models.MediaInfo, ok bool) {
+	mediaID := c.Param("mediaID")
+	mediaInfo, err := cache.GetMediaInfo(mediaID)
+	if err != nil {
+		return nil, false
+	}
+	if !canAccess(mediaInfo, middleware.GetCurrentAppID(c), middleware.GetCurrentUserID(c)) {
+		fmt.Println("媒体文件 无权访问", mediaID, middleware.GetCurrentAppID(c), middleware.GetCurrentUserID(c))
+		return nil, false
+	}
+	return mediaInfo, true
+}
+
+// Info 获取媒体元数据
+func Info(c *gin.Context) {
+	data := make(map[string]interface{})
+	mediaInfo, ok := getMediaInfo(c)
+	if !ok {
+		controllers.Response(c, common.NotData, "文件不存在", data)
+		return
+	}
+	data["media"] = mediaInfo
+	controllers.Response(c, common.OK, "获取成功", data)
+}
+
+// Download 下载文件
+// 图片: size 为缩略图尺寸，不传返回原图
+// 语音: format=wav 返回 WAV 封装，不传返回上传时的原始格式
+func Download(c *gin.Context) {
+	data := make(map[string]interface{})
+	mediaInfo, ok := getMediaInfo(c)
+	if !ok {
+		controllers.Response(c, common.NotData, "文件不存在", data)
+		return
+	}
+	mediaID := mediaInfo.MediaID
+
+	if mediaInfo.Kind == models.MediaKindAudio && c.Query("format") == "wav" {
+		wav, err := media.ReadAudioWAV(mediaInfo)
+		if err != nil {
+			fmt.Println("下载语音 读取失败", mediaID, err)
+			controllers.Response(c, common.NotData, "文件不存在", data)
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.wav", mediaID))
+		c.Data(http.StatusOK, "audio/wav", wav)
+		return
+	}
+
+	file := mediaInfo.File
+	contentType := mediaInfo.ContentType
+	if sizeStr := c.Query("size"); sizeStr != "" {
+		size, _ := strconv.Atoi(sizeStr)
+		thumbnail := mediaInfo.GetThumbnail(size)
+		if thumbnail == nil {
+			controllers.Response(c, common.NotData, "缩略图未生成", data)
+			return
+		}
+		file = thumbnail.File
+	}
+	content, err := media.ReadFile(file)
+	if err != nil {
+		fmt.Println("下载文件 读取失败", mediaID, file, err)
+		controllers.Response(c, common.NotData, "文件不存在", data)
+		return
+	}
+	if file != mediaInfo.File {
+		// 缩略图可能与原图格式不同
+		contentType = http.DetectContentType(content)
+	}
+	c.Data(http.StatusOK, contentType, content)
+}
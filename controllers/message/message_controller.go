@@ -7,20 +7,21 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/redis/go-redis/v9"
 
 	"github.com/link1st/gowebsocket/v2/common"
 	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
 	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/lib/webhook"
+	"github.com/link1st/gowebsocket/v2/middleware"
+	"github.com/link1st/gowebsocket/v2/models"
 	"github.com/link1st/gowebsocket/v2/servers/websocket"
 )
 
 // GetChatHistory 获取聊天记录
 func GetChatHistory(c *gin.Context) {
-	token := c.GetHeader("Authorization")
-	if token == "" {
-		token = c.Query("token")
-	}
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
 	friendID := c.Query("friendID")
 	pageStr := c.DefaultQuery("page", "1")
 	limitStr := c.DefaultQuery("limit", "20")
@@ -28,37 +29,17 @@ func GetChatHistory(c *gin.Context) {
 	page, _ := strconv.Atoi(pageStr)
 	limit, _ := strconv.Atoi(limitStr)
 
-	fmt.Println("API请求 获取聊天记录", token, friendID, page, limit)
+	fmt.Println("API请求 获取聊天记录", userID, friendID, page, limit)
 
 	data := make(map[string]interface{})
 
-	if token == "" {
-		controllers.Response(c, common.Unauthorized, "未授权访问", data)
-		return
-	}
-
 	if friendID == "" {
 		controllers.Response(c, common.ParameterIllegal, "好友ID不能为空", data)
 		return
 	}
 
-	// 验证token并获取用户ID
-	tokenKey := fmt.Sprintf("auth:token:%s", token)
-	tokenData, err := redislib.GetClient().HGetAll(c.Request.Context(), tokenKey).Result()
-	if err != nil || len(tokenData) == 0 {
-		controllers.Response(c, common.Unauthorized, "token无效", data)
-		return
-	}
-
-	userID := tokenData["userID"]
-
 	// 生成聊天记录的key (保证两个用户之间的聊天记录key一致)
-	var chatKey string
-	if userID < friendID {
-		chatKey = fmt.Sprintf("chat:history:%s:%s", userID, friendID)
-	} else {
-		chatKey = fmt.Sprintf("chat:history:%s:%s", friendID, userID)
-	}
+	chatKey := cache.GetChatHistoryKey(models.GetConversationID(appID, userID, friendID))
 
 	// 获取聊天记录总数
 	total, err := redislib.GetClient().ZCard(c.Request.Context(), chatKey).Result()
@@ -81,24 +62,28 @@ func GetChatHistory(c *gin.Context) {
 
 	var messages []map[string]interface{}
 
+	now := time.Now().Unix()
 	for _, messageID := range messageIDs {
-		messageKey := fmt.Sprintf("message:detail:%s", messageID)
-		messageInfo, err := redislib.GetClient().HGetAll(c.Request.Context(), messageKey).Result()
-		if err != nil || len(messageInfo) == 0 {
+		record, err := cache.GetMessage(appID, messageID)
+		if err != nil {
 			continue
 		}
 
-		// 解析时间戳
-		timestamp, _ := strconv.ParseInt(messageInfo["timestamp"], 10, 64)
+		// 已过期但尚未被清理的消息不再返回
+		if record.ExpireAt > 0 && record.ExpireAt <= now {
+			continue
+		}
 
 		messageData := map[string]interface{}{
-			"messageID":   messageInfo["messageID"],
-			"fromUserID":  messageInfo["fromUserID"],
-			"toUserID":    messageInfo["toUserID"],
-			"content":     messageInfo["content"],
-			"messageType": messageInfo["messageType"],
-			"timestamp":   time.Unix(timestamp, 0).Format(time.RFC3339),
-			"isRead":      messageInfo["isRead"] == "true",
+			"messageID":   record.MessageID,
+			"seq":         record.Seq,
+			"fromUserID":  record.FromUserID,
+			"toUserID":    record.ToUserID,
+			"content":     record.Content,
+			"messageType": record.MessageType,
+			"timestamp":   time.Unix(record.Timestamp, 0).Format(time.RFC3339),
+			"isRead":      record.IsRead,
+			"expireAt":    record.ExpireAt,
 		}
 
 		messages = append(messages, messageData)
@@ -130,10 +115,7 @@ type SendMessageRequest struct {
 
 // SendMessage 发送消息
 func SendMessage(c *gin.Context) {
-	token := c.GetHeader("Authorization")
-	if token == "" {
-		token = c.Query("token")
-	}
+	userID := middleware.GetCurrentUserID(c)
 
 	var req SendMessageRequest
 	// 绑定JSON请求参数
@@ -148,111 +130,111 @@ func SendMessage(c *gin.Context) {
 	content := req.Content
 	messageType := req.MessageType
 	if messageType == "" {
-		messageType = "text"
+		messageType = models.MessageTypeText
 	}
 
-	fmt.Println("API请求 发送消息", token, friendID, content, messageType)
+	fmt.Println("API请求 发送消息", userID, friendID, content, messageType)
 
 	data := make(map[string]interface{})
 
-	if token == "" {
-		controllers.Response(c, common.Unauthorized, "未授权访问", data)
-		return
-	}
-
 	if friendID == "" || content == "" {
 		controllers.Response(c, common.ParameterIllegal, "参数不能为空", data)
 		return
 	}
 
-	// 验证token并获取用户ID
-	tokenKey := fmt.Sprintf("auth:token:%s", token)
-	tokenData, err := redislib.GetClient().HGetAll(c.Request.Context(), tokenKey).Result()
-	if err != nil || len(tokenData) == 0 {
-		controllers.Response(c, common.Unauthorized, "token无效", data)
+	// 接口只推送文本，图片和语音消息的内容为 mediaID，需要通过 WebSocket 发送并校验文件归属，
+	// 否则引用他人的 mediaID 就能让会话成员获得下载权限
+	if messageType != models.MessageTypeText {
+		controllers.Response(c, common.ParameterIllegal, "接口只支持发送文本消息", data)
 		return
 	}
 
-	userID := tokenData["userID"]
-	appID := tokenData["appID"]
-	// appIDUint64, _ := strconv.ParseInt(appIDStr, 10, 32)
-	// appID := uint32(appIDUint64)
-
-	// 生成消息ID
-	messageID := fmt.Sprintf("msg_%d_%s_%s", time.Now().UnixNano(), userID, friendID)
+	appID := middleware.GetCurrentAppID(c)
 
-	// 保存消息详情
-	messageKey := fmt.Sprintf("message:detail:%s", messageID)
-	messageInfo := map[string]interface{}{
-		"messageID":   messageID,
-		"fromUserID":  userID,
-		"toUserID":    friendID,
-		"content":     content,
-		"messageType": messageType,
-		"timestamp":   time.Now().Unix(),
-		"isRead":      false,
+	// 检查消息长度
+	if code := websocket.CheckMessageSize(appID, content); code != common.OK {
+		controllers.Response(c, code, "", data)
+		return
 	}
 
-	err = redislib.GetClient().HMSet(c.Request.Context(), messageKey, messageInfo).Err()
-	if err != nil {
-		fmt.Printf("保存消息失败: %v\n", err)
-		controllers.Response(c, common.ServerError, "发送消息失败", data)
+	// 检查黑名单
+	if code := websocket.CheckBlocked(appID, userID, friendID); code != common.OK {
+		controllers.Response(c, code, "", data)
 		return
 	}
 
-	// 添加到聊天记录
-	var chatKey string
-	if userID < friendID {
-		chatKey = fmt.Sprintf("chat:history:%s:%s", userID, friendID)
-	} else {
-		chatKey = fmt.Sprintf("chat:history:%s:%s", friendID, userID)
+	// 发送前回调，文本消息的内容可能被替换
+	content, code, msg := websocket.BeforeSend(&models.PreSendMessage{
+		AppID:       appID,
+		Source:      models.PreSendSourceAPI,
+		FromUserID:  userID,
+		ToUserID:    friendID,
+		MessageType: messageType,
+		Content:     content,
+	})
+	if code != common.OK {
+		controllers.Response(c, code, msg, data)
+		return
 	}
 
-	// 使用时间戳作为score
-	err = redislib.GetClient().ZAdd(c.Request.Context(), chatKey, redis.Z{
-		Score:  float64(time.Now().Unix()),
-		Member: messageID,
-	}).Err()
+	// 生成消息ID
+	messageID := models.NewMessageID(userID, friendID)
+
+	// 保存消息详情并添加到聊天记录，按会话策略登记过期
+	record := &models.MessageRecord{
+		MessageID:      messageID,
+		AppID:          appID,
+		ConversationID: models.GetConversationID(appID, userID, friendID),
+		FromUserID:     userID,
+		ToUserID:       friendID,
+		Content:        content,
+		MessageType:    messageType,
+		Timestamp:      time.Now().Unix(),
+		IsRead:         false,
+	}
+	err := cache.SaveMessage(record)
 	if err != nil {
-		fmt.Printf("添加到聊天记录失败: %v\n", err)
+		fmt.Printf("保存消息失败: %v\n", err)
+		controllers.Response(c, common.ServerError, "发送消息失败", data)
+		return
 	}
 
 	// 通过WebSocket发送实时消息
 	go func() {
-		_, err := websocket.SendUserMessage(appID, friendID, messageID, content)
+		_, err := websocket.SendUserMessageFrom(appID, userID, friendID, messageID, content)
 		if err != nil {
 			fmt.Printf("WebSocket发送消息失败: %v\n", err)
 		}
+		websocket.NotifyConversation(appID, record)
 	}()
-
-	// 更新未读消息计数
-	unreadKey := fmt.Sprintf("message:unread:%s:%s", friendID, userID)
-	redislib.GetClient().Incr(c.Request.Context(), unreadKey)
+	go webhook.Emit(appID, models.WebhookEventMessageSend, record)
 
 	data["message"] = map[string]interface{}{
 		"messageID":   messageID,
+		"seq":         record.Seq,
 		"fromUserID":  userID,
 		"toUserID":    friendID,
 		"content":     content,
 		"messageType": messageType,
-		"timestamp":   time.Now().Format(time.RFC3339),
+		"timestamp":   time.Unix(record.Timestamp, 0).Format(time.RFC3339),
 		"isRead":      false,
+		"expireAt":    record.ExpireAt,
 	}
 
 	controllers.Response(c, common.OK, "发送成功", data)
 }
 
 // MarkAsReadRequest 标记消息已读请求结构体
+// 传 friendID 或 conversationID，seq 为已读到的消息序号，不传表示全部已读
 type MarkAsReadRequest struct {
-	FriendID string `json:"friendID" binding:"required"`
+	FriendID       string `json:"friendID"`
+	ConversationID string `json:"conversationID"`
+	Seq            int64  `json:"seq"`
 }
 
 // MarkAsRead 标记消息已读
 func MarkAsRead(c *gin.Context) {
-	token := c.GetHeader("Authorization")
-	if token == "" {
-		token = c.Query("token")
-	}
+	userID := middleware.GetCurrentUserID(c)
 
 	var req MarkAsReadRequest
 	// 绑定JSON请求参数
@@ -263,91 +245,53 @@ func MarkAsRead(c *gin.Context) {
 		return
 	}
 
-	friendID := req.FriendID
-
-	fmt.Println("API请求 标记消息已读", token, friendID)
+	fmt.Println("API请求 标记消息已读", userID, req.FriendID, req.ConversationID, req.Seq)
 
 	data := make(map[string]interface{})
 
-	if token == "" {
-		controllers.Response(c, common.Unauthorized, "未授权访问", data)
+	if req.FriendID == "" && req.ConversationID == "" {
+		controllers.Response(c, common.ParameterIllegal, "会话ID不能为空", data)
 		return
 	}
 
-	if friendID == "" {
-		controllers.Response(c, common.ParameterIllegal, "好友ID不能为空", data)
-		return
+	appID := middleware.GetCurrentAppID(c)
+	conversationID := req.ConversationID
+	if conversationID == "" {
+		conversationID = models.GetConversationID(appID, userID, req.FriendID)
 	}
 
-	// 验证token并获取用户ID
-	tokenKey := fmt.Sprintf("auth:token:%s", token)
-	tokenData, err := redislib.GetClient().HGetAll(c.Request.Context(), tokenKey).Result()
-	if err != nil || len(tokenData) == 0 {
-		controllers.Response(c, common.Unauthorized, "token无效", data)
+	readSeq, burnCount, code := websocket.MarkConversationRead(appID, userID, conversationID, req.Seq)
+	if code != common.OK {
+		controllers.Response(c, code, "", data)
 		return
 	}
 
-	userID := tokenData["userID"]
-
-	// 清除未读消息计数
-	unreadKey := fmt.Sprintf("message:unread:%s:%s", userID, friendID)
-	err = redislib.GetClient().Del(c.Request.Context(), unreadKey).Err()
-	if err != nil {
-		fmt.Printf("清除未读消息计数失败: %v\n", err)
+	data["conversationID"] = conversationID
+	data["readSeq"] = readSeq
+	if burnCount > 0 {
+		data["burnCount"] = burnCount
 	}
-
 	controllers.Response(c, common.OK, "标记成功", data)
 }
 
-// GetUnreadCount 获取未读消息统计
+// GetUnreadCount 获取未读消息统计，unreadCounts 按会话ID返回，badge 不含免打扰会话
 func GetUnreadCount(c *gin.Context) {
-	token := c.GetHeader("Authorization")
-	if token == "" {
-		token = c.Query("token")
-	}
+	userID := middleware.GetCurrentUserID(c)
 
-	fmt.Println("API请求 获取未读消息统计", token)
+	fmt.Println("API请求 获取未读消息统计", userID)
 
 	data := make(map[string]interface{})
 
-	if token == "" {
-		controllers.Response(c, common.Unauthorized, "未授权访问", data)
-		return
-	}
-
-	// 验证token并获取用户ID
-	tokenKey := fmt.Sprintf("auth:token:%s", token)
-	tokenData, err := redislib.GetClient().HGetAll(c.Request.Context(), tokenKey).Result()
-	if err != nil || len(tokenData) == 0 {
-		controllers.Response(c, common.Unauthorized, "token无效", data)
-		return
-	}
-
-	userID := tokenData["userID"]
-
-	// 获取好友列表
-	friendsKey := fmt.Sprintf("user:friends:%s", userID)
-	friendIDs, err := redislib.GetClient().SMembers(c.Request.Context(), friendsKey).Result()
+	// 按已读游标计算每个会话的未读数
+	unreadCounts, totalUnread, badge, err := cache.GetUnreadCounts(middleware.GetCurrentAppID(c), userID)
 	if err != nil {
-		controllers.Response(c, common.ServerError, "获取好友列表失败", data)
+		controllers.Response(c, common.ServerError, "获取未读消息统计失败", data)
 		return
 	}
 
-	unreadCounts := make(map[string]int)
-	totalUnread := 0
-
-	for _, friendID := range friendIDs {
-		unreadKey := fmt.Sprintf("message:unread:%s:%s", userID, friendID)
-		count, err := redislib.GetClient().Get(c.Request.Context(), unreadKey).Int()
-		if err != nil {
-			count = 0
-		}
-		unreadCounts[friendID] = count
-		totalUnread += count
-	}
-
 	data["unreadCounts"] = unreadCounts
 	data["totalUnread"] = totalUnread
+	data["badge"] = badge
 
 	controllers.Response(c, common.OK, "获取成功", data)
 }
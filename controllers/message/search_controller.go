@@ -0,0 +1,92 @@
+// Package message 消息管理接口
+package message
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/lib/search"
+	"github.com/link1st/gowebsocket/v2/middleware"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	searchMaxLimit = 50 // 每页最多返回的消息数
+)
+
+// SearchMessages 全文检索聊天记录
+// q 关键词，可选过滤: friendID 好友 groupID 群 senderID 发送者 startTime/endTime 时间范围(unix 秒)
+func SearchMessages(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+	query := c.Query("q")
+	friendID := c.Query("friendID")
+	groupID := c.Query("groupID")
+	senderID := c.Query("senderID")
+	startTime, _ := strconv.ParseInt(c.Query("startTime"), 10, 64)
+	endTime, _ := strconv.ParseInt(c.Query("endTime"), 10, 64)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > searchMaxLimit {
+		limit = searchMaxLimit
+	}
+
+	fmt.Println("API请求 检索聊天记录", userID, query, friendID, groupID, senderID, startTime, endTime, page, limit)
+
+	terms, keywords := search.QueryTerms(query)
+	if len(terms) == 0 {
+		controllers.Response(c, common.ParameterIllegal, "关键词不能为空", data)
+		return
+	}
+
+	conversationID := ""
+	if friendID != "" {
+		conversationID = models.GetConversationID(appID, userID, friendID)
+	} else if groupID != "" {
+		conversationID = models.GetGroupConversationID(appID, groupID)
+	}
+	filter := func(record *models.MessageRecord) bool {
+		if conversationID != "" && record.ConversationID != conversationID {
+			return false
+		}
+		if senderID != "" && record.FromUserID != senderID {
+			return false
+		}
+		return true
+	}
+
+	records, hasMore, err := cache.SearchMessages(appID, userID, terms, startTime, endTime, filter, (page-1)*limit,
+		limit)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "检索失败", data)
+		return
+	}
+
+	messages := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		messages = append(messages, map[string]interface{}{
+			"messageID":      record.MessageID,
+			"conversationID": record.ConversationID,
+			"fromUserID":     record.FromUserID,
+			"toUserID":       record.ToUserID,
+			"content":        record.Content,
+			"highlight":      search.Highlight(record.Content, keywords),
+			"messageType":    record.MessageType,
+			"timestamp":      time.Unix(record.Timestamp, 0).Format(time.RFC3339),
+		})
+	}
+
+	data["messages"] = messages
+	data["hasMore"] = hasMore
+	controllers.Response(c, common.OK, "获取成功", data)
+}
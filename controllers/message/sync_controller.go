@@ -0,0 +1,36 @@
+// Package message 消息管理接口
+package message
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/middleware"
+	"github.com/link1st/gowebsocket/v2/models"
+	"github.com/link1st/gowebsocket/v2/servers/websocket"
+)
+
+// SyncMessages 按会话 seq 同步消息
+// {"conversations": {"app:a:b": 10}} 增量同步；{"conversationID": "app:a:b", "beforeSeq": 100} 向前翻页
+func SyncMessages(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+
+	request := &models.SyncMessages{}
+	if err := c.ShouldBindJSON(request); err != nil {
+		fmt.Println("同步消息 参数绑定失败", userID, err)
+		controllers.Response(c, common.ParameterIllegal, "参数错误", data)
+		return
+	}
+
+	results, code := websocket.SyncMessages(middleware.GetCurrentAppID(c), userID, request)
+	if code != common.OK {
+		controllers.Response(c, code, "", data)
+		return
+	}
+	data["conversations"] = results
+	controllers.Response(c, common.OK, "", data)
+}
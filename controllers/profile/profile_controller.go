@@ -0,0 +1,261 @@
+// Package profile 用户资料接口
+package profile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/helper"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/lib/sensitive"
+	"github.com/link1st/gowebsocket/v2/middleware"
+	"github.com/link1st/gowebsocket/v2/models"
+	"github.com/link1st/gowebsocket/v2/servers/websocket"
+)
+
+const (
+	nicknameMaxLen  = 32  // 昵称最大长度(字符)
+	signatureMaxLen = 100 // 个性签名最大长度(字符)
+	searchMaxLimit  = 50  // 搜索最多返回的用户数
+)
+
+// GetProfile 获取自己的资料(包含隐私设置)
+func GetProfile(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+
+	profile, err := cache.GetUserProfile(middleware.GetCurrentAppID(c), userID)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			controllers.Response(c, common.NotData, "用户不存在", data)
+			return
+		}
+		controllers.Response(c, common.ServerError, "获取用户资料失败", data)
+		return
+	}
+
+	data["profile"] = profile
+	controllers.Response(c, common.OK, "获取成功", data)
+}
+
+// UpdateProfileRequest 修改资料请求结构体，未传的字段保持不变
+type UpdateProfileRequest struct {
+	Nickname      *string `json:"nickname"`
+	AvatarMediaID *string `json:"avatarMediaID"` // 先通过 /api/media/upload 上传图片
+	Signature     *string `json:"signature"`
+}
+
+// UpdateProfile 修改昵称、头像、个性签名，并通知在线好友
+func UpdateProfile(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+
+	fmt.Println("API请求 修改用户资料", userID)
+
+	old, err := cache.GetUserProfile(appID, userID)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "获取用户资料失败", data)
+		return
+	}
+
+	fields := make(map[string]interface{})
+	if req.Nickname != nil {
+		nickname := strings.TrimSpace(*req.Nickname)
+		if nickname == "" || utf8.RuneCountInString(nickname) > nicknameMaxLen {
+			controllers.Response(c, common.ParameterIllegal, "昵称不能为空或过长", data)
+			return
+		}
+		nickname, code := sensitive.Filter(models.SensitiveScopeNickname, appID, userID, "", nickname)
+		if code != common.OK {
+			controllers.Response(c, code, "昵称包含敏感词", data)
+			return
+		}
+		fields["nickname"] = nickname
+	}
+	if req.Signature != nil {
+		signature := strings.TrimSpace(*req.Signature)
+		if utf8.RuneCountInString(signature) > signatureMaxLen {
+			controllers.Response(c, common.ParameterIllegal, "个性签名过长", data)
+			return
+		}
+		fields["signature"] = signature
+	}
+	if req.AvatarMediaID != nil {
+		mediaInfo, err := cache.GetMediaInfo(*req.AvatarMediaID)
+		if err != nil || mediaInfo.Kind != models.MediaKindImage || mediaInfo.AppID != appID ||
+			mediaInfo.OwnerID != userID {
+			controllers.Response(c, common.ParameterIllegal, "头像图片不存在", data)
+			return
+		}
+		if !mediaInfo.Avatar {
+			mediaInfo.Avatar = true
+			if err = cache.SetMediaInfo(mediaInfo); err != nil {
+				controllers.Response(c, common.ModelStoreError, "修改资料失败", data)
+				return
+			}
+		}
+		fields["avatarMediaID"] = mediaInfo.MediaID
+		fields["avatar"] = fmt.Sprintf("/api/media/%s/file", mediaInfo.MediaID)
+	}
+	if len(fields) == 0 {
+		controllers.Response(c, common.ParameterIllegal, "没有需要修改的资料", data)
+		return
+	}
+
+	profile, err := cache.UpdateUserProfile(appID, old, fields)
+	if err != nil {
+		controllers.Response(c, common.ModelStoreError, "修改用户资料失败", data)
+		return
+	}
+
+	go notifyFriends(appID, profile)
+
+	data["profile"] = profile
+	controllers.Response(c, common.OK, "修改成功", data)
+}
+
+// UpdatePrivacyRequest 修改隐私设置请求结构体，未传的字段保持不变
+type UpdatePrivacyRequest struct {
+	SearchByID       *bool `json:"searchByID"`
+	SearchByNickname *bool `json:"searchByNickname"`
+}
+
+// UpdatePrivacy 修改是否允许通过ID、昵称搜索到自己
+func UpdatePrivacy(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+
+	var req UpdatePrivacyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+
+	old, err := cache.GetUserProfile(appID, userID)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "获取用户资料失败", data)
+		return
+	}
+
+	fields := make(map[string]interface{})
+	if req.SearchByID != nil {
+		fields["searchByID"] = boolToFlag(*req.SearchByID)
+	}
+	if req.SearchByNickname != nil {
+		fields["searchByNickname"] = boolToFlag(*req.SearchByNickname)
+	}
+	if len(fields) == 0 {
+		controllers.Response(c, common.ParameterIllegal, "没有需要修改的设置", data)
+		return
+	}
+
+	profile, err := cache.UpdateUserProfile(appID, old, fields)
+	if err != nil {
+		controllers.Response(c, common.ModelStoreError, "修改隐私设置失败", data)
+		return
+	}
+
+	data["profile"] = profile
+	controllers.Response(c, common.OK, "修改成功", data)
+}
+
+// SearchUsers 搜索用户：精确匹配用户ID，或按昵称前缀匹配
+// 只返回允许被搜索到的用户，拉黑了自己的用户不会出现在结果中
+func SearchUsers(c *gin.Context) {
+	data := make(map[string]interface{})
+	userID := middleware.GetCurrentUserID(c)
+	appID := middleware.GetCurrentAppID(c)
+	query := strings.TrimSpace(c.Query("q"))
+	limit, _ := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	if limit < 1 || limit > searchMaxLimit {
+		limit = searchMaxLimit
+	}
+
+	fmt.Println("API请求 搜索用户", userID, query, limit)
+
+	if query == "" {
+		controllers.Response(c, common.ParameterIllegal, "搜索内容不能为空", data)
+		return
+	}
+
+	users := make([]map[string]interface{}, 0)
+	seen := make(map[string]bool)
+	add := func(profile *models.UserProfile) {
+		if seen[profile.UserID] || int64(len(users)) >= limit {
+			return
+		}
+		if blocked, _ := cache.IsBlocked(appID, profile.UserID, userID); blocked {
+			return
+		}
+		seen[profile.UserID] = true
+		user := profile.Public()
+		user["isFriend"], _ = cache.IsFriend(appID, userID, profile.UserID)
+		users = append(users, user)
+	}
+
+	// 精确匹配用户ID
+	if profile, err := cache.GetUserProfile(appID, query); err == nil && profile.SearchByID {
+		add(profile)
+	}
+
+	// 昵称前缀匹配，索引可能滞后于隐私设置，再检查一次
+	userIDs, err := cache.SearchUserIDsByNickname(appID, query, limit*2)
+	if err != nil {
+		controllers.Response(c, common.ServerError, "搜索用户失败", data)
+		return
+	}
+	for _, id := range userIDs {
+		profile, err := cache.GetUserProfile(appID, id)
+		if err != nil || !profile.SearchByNickname || !strings.HasPrefix(strings.ToLower(profile.Nickname),
+			strings.ToLower(query)) {
+			continue
+		}
+		add(profile)
+	}
+
+	data["users"] = users
+	controllers.Response(c, common.OK, "搜索成功", data)
+}
+
+// notifyFriends 把新资料推送给在线好友
+func notifyFriends(appID string, profile *models.UserProfile) {
+	friendIDs, err := cache.GetFriendIDs(appID, profile.UserID)
+	if err != nil {
+		return
+	}
+	profileByte, err := json.Marshal(profile.Public())
+	if err != nil {
+		fmt.Println("通知好友资料变更 json Marshal", profile.UserID, err)
+		return
+	}
+	for _, friendID := range friendIDs {
+		_, _ = websocket.SendUserCmdMessage(appID, friendID, helper.GetOrderIDTime(), models.MessageCmdProfile,
+			string(profileByte))
+	}
+}
+
+func boolToFlag(value bool) string {
+	if value {
+		return "1"
+	}
+	return "0"
+}
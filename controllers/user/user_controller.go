@@ -2,17 +2,32 @@
 package user
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 
 	"github.com/link1st/gowebsocket/v2/common"
 	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/helper"
 	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/middleware"
 	"github.com/link1st/gowebsocket/v2/models"
 	"github.com/link1st/gowebsocket/v2/servers/websocket"
 )
 
+// checkAppID 服务端凭证只能操作所属 appID 的数据
+func checkAppID(c *gin.Context, appID string, data map[string]interface{}) (ok bool) {
+	credential := middleware.GetCurrentCredential(c)
+	if credential == nil || credential.AppID != appID {
+		controllers.Response(c, common.PermissionDenied, "凭证不属于该应用", data)
+		return false
+	}
+	return true
+}
+
 // List 查看全部在线用户
 func List(c *gin.Context) {
 	appID := c.Query("appID")
@@ -20,6 +35,9 @@ func List(c *gin.Context) {
 	// appID := uint32(appIDUint64)
 	fmt.Println("http_request 查看全部在线用户", appID)
 	data := make(map[string]interface{})
+	if !checkAppID(c, appID, data) {
+		return
+	}
 	userList := websocket.UserList(appID)
 	data["userList"] = userList
 	data["userCount"] = len(userList)
@@ -34,6 +52,9 @@ func Online(c *gin.Context) {
 	// appID := uint32(appIDUint64)
 	fmt.Println("http_request 查看用户是否在线", userID, appID)
 	data := make(map[string]interface{})
+	if !checkAppID(c, appID, data) {
+		return
+	}
 	online := websocket.CheckUserOnline(appID, userID)
 	data["userID"] = userID
 	data["online"] = online
@@ -66,14 +87,32 @@ func SendMessage(c *gin.Context) {
 
 	fmt.Println("http_request 给用户发送消息", appID, userID, msgID, message)
 
-	// TODO::进行用户权限认证，一般是客户端传入TOKEN，然后检验TOKEN是否合法，通过TOKEN解析出来用户ID
-	// 本项目只是演示，所以直接过去客户端传入的用户ID(userID)
+	// 请求已通过服务端签名认证，只能给凭证所属应用的用户发送
 	data := make(map[string]interface{})
-	if cache.SeqDuplicates(msgID) {
+	if !checkAppID(c, appID, data) {
+		return
+	}
+	if code := websocket.CheckMessageSize(appID, message); code != common.OK {
+		controllers.Response(c, code, "", data)
+		return
+	}
+	// 先去重再调用发送前回调，重复提交不会重复触发回调
+	if cache.SeqDuplicates(appID, msgID) {
 		fmt.Println("给用户发送消息 重复提交:", msgID)
 		controllers.Response(c, common.OK, "", data)
 		return
 	}
+	message, code, msg := websocket.BeforeSend(&models.PreSendMessage{
+		AppID:       appID,
+		Source:      models.PreSendSourceServer,
+		ToUserID:    userID,
+		MessageType: models.MessageTypeText,
+		Content:     message,
+	})
+	if code != common.OK {
+		controllers.Response(c, code, msg, data)
+		return
+	}
 	sendResults, err := websocket.SendUserMessage(appID, userID, msgID, message)
 	if err != nil {
 		data["sendResultsErr"] = err.Error()
@@ -100,11 +139,29 @@ func SendMessageAll(c *gin.Context) {
 
 	fmt.Println("http_request 给全体用户发送消息", appID, userID, msgID, message)
 	data := make(map[string]interface{})
-	if cache.SeqDuplicates(msgID) {
+	if !checkAppID(c, appID, data) {
+		return
+	}
+	if code := websocket.CheckMessageSize(appID, message); code != common.OK {
+		controllers.Response(c, code, "", data)
+		return
+	}
+	if cache.SeqDuplicates(appID, msgID) {
 		fmt.Println("给用户发送消息 重复提交:", msgID)
 		controllers.Response(c, common.OK, "", data)
 		return
 	}
+	message, code, msg := websocket.BeforeSend(&models.PreSendMessage{
+		AppID:       appID,
+		Source:      models.PreSendSourceBroadcast,
+		FromUserID:  userID,
+		MessageType: models.MessageTypeText,
+		Content:     message,
+	})
+	if code != common.OK {
+		controllers.Response(c, code, msg, data)
+		return
+	}
 	sendResults, err := websocket.SendUserMessageAll(appID, userID, msgID, models.MessageCmdMsg, message)
 	if err != nil {
 		data["sendResultsErr"] = err.Error()
@@ -113,3 +170,50 @@ func SendMessageAll(c *gin.Context) {
 	controllers.Response(c, common.OK, "", data)
 
 }
+
+const (
+	resetTokenTTL = 15 * time.Minute
+)
+
+// IssueResetTokenRequest 签发重置密码凭证请求结构体
+type IssueResetTokenRequest struct {
+	UserID string `json:"userID" binding:"required"`
+	AppID  string `json:"appID" binding:"required"`
+}
+
+// IssueResetToken 由业务服务端在完成身份核验(短信、邮件等)后签发一次性的重置密码凭证
+// 需要有 password-reset 权限的服务端凭证，只能给凭证所属应用下已注册的用户签发
+func IssueResetToken(c *gin.Context) {
+	data := make(map[string]interface{})
+
+	var req IssueResetTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fmt.Printf("参数绑定失败: %v\n", err)
+		controllers.Response(c, common.ParameterIllegal, "请求参数格式错误", data)
+		return
+	}
+
+	fmt.Println("http_request 签发重置密码凭证", req.AppID, req.UserID)
+
+	if !checkAppID(c, req.AppID, data) {
+		return
+	}
+
+	if _, err := cache.GetUserProfile(req.AppID, req.UserID); err != nil {
+		if errors.Is(err, redis.Nil) {
+			controllers.Response(c, common.NotData, "用户不存在", data)
+			return
+		}
+		controllers.Response(c, common.ServerError, "", data)
+		return
+	}
+
+	token := helper.GetRandomID(32)
+	if err := cache.SetPasswordResetToken(token, req.AppID, req.UserID, resetTokenTTL); err != nil {
+		controllers.Response(c, common.ModelStoreError, "", data)
+		return
+	}
+	data["resetToken"] = token
+	data["expiresIn"] = int64(resetTokenTTL.Seconds())
+	controllers.Response(c, common.OK, "", data)
+}
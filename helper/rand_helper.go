@@ -0,0 +1,20 @@
+// Package helper 帮助函数
+package helper
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// GetRandomID 获取随机 ID，n 为随机字节数，返回 2n 位十六进制字符串
+func GetRandomID(n int) (id string) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		// 随机数不可用时退化为时间戳
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	id = hex.EncodeToString(b)
+	return
+}
@@ -0,0 +1,159 @@
+// Package apps 应用(租户)注册表，带本地缓存
+// 注册表为空时兼容老版本，任意 appID 都可以使用，登录策略读取配置文件
+package apps
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	localCacheTime = 10 * time.Second // 本地缓存时间，其他节点修改应用配置后最多延迟这么久生效
+)
+
+type appEntry struct {
+	app      *models.App
+	loadedAt time.Time
+}
+
+type credentialEntry struct {
+	credential *models.APICredential
+	loadedAt   time.Time
+}
+
+var (
+	cacheRWMutex sync.RWMutex
+	appCache     = make(map[string]*appEntry)
+	credCache    = make(map[string]*credentialEntry)
+	appIDs       []string
+	appIDsAt     time.Time
+
+	configCredentialsOnce sync.Once
+	configCredentials     map[string]*models.APICredential
+)
+
+// Invalidate 清除本地缓存，修改应用配置后调用
+func Invalidate() {
+	cacheRWMutex.Lock()
+	defer cacheRWMutex.Unlock()
+	appCache = make(map[string]*appEntry)
+	credCache = make(map[string]*credentialEntry)
+	appIDs = nil
+	appIDsAt = time.Time{}
+}
+
+// GetAppIDs 全部已注册的应用ID
+func GetAppIDs() (ids []string) {
+	cacheRWMutex.RLock()
+	if time.Since(appIDsAt) < localCacheTime {
+		ids = appIDs
+		cacheRWMutex.RUnlock()
+		return
+	}
+	cacheRWMutex.RUnlock()
+
+	ids, err := cache.GetAppIDs()
+	if err != nil {
+		return []string{}
+	}
+	cacheRWMutex.Lock()
+	appIDs, appIDsAt = ids, time.Now()
+	cacheRWMutex.Unlock()
+	return
+}
+
+// GetApp 获取应用，不存在返回 nil
+func GetApp(appID string) (app *models.App, err error) {
+	cacheRWMutex.RLock()
+	entry, ok := appCache[appID]
+	cacheRWMutex.RUnlock()
+	if ok && time.Since(entry.loadedAt) < localCacheTime {
+		return entry.app, nil
+	}
+
+	app, err = cache.GetApp(appID)
+	if err != nil {
+		return
+	}
+	cacheRWMutex.Lock()
+	appCache[appID] = &appEntry{app: app, loadedAt: time.Now()}
+	cacheRWMutex.Unlock()
+	return
+}
+
+// CheckApp 检查应用是否可用
+// 注册表为空时返回按配置文件生成的默认应用，appID 同样需要符合应用ID的格式
+func CheckApp(appID string) (app *models.App, code uint32) {
+	app, err := GetApp(appID)
+	if err != nil {
+		return nil, common.ServerError
+	}
+	if app == nil {
+		if len(GetAppIDs()) > 0 || !models.IsValidAppID(appID) {
+			return nil, common.AppUnavailable
+		}
+		return getLegacyApp(appID), common.OK
+	}
+	if app.Disabled {
+		return nil, common.AppUnavailable
+	}
+	return app, common.OK
+}
+
+// getLegacyApp 注册表为空时使用的应用配置
+func getLegacyApp(appID string) (app *models.App) {
+	app = models.NewApp(appID, "", 0)
+	if viper.GetBool("auth.autoProvision.enabled") {
+		for _, value := range viper.GetStringSlice("auth.autoProvision.apps") {
+			if value == appID {
+				app.LoginPolicy.AutoProvision = true
+			}
+		}
+	}
+	return
+}
+
+// GetCredential 根据 appKey 获取服务端接口凭证，注册表中没有时查找配置文件 serverAPI.credentials
+func GetCredential(appKey string) (credential *models.APICredential, err error) {
+	cacheRWMutex.RLock()
+	entry, ok := credCache[appKey]
+	cacheRWMutex.RUnlock()
+	if ok && time.Since(entry.loadedAt) < localCacheTime {
+		return entry.credential, nil
+	}
+
+	credential, err = cache.GetAPICredential(appKey)
+	if err != nil {
+		return
+	}
+	if credential == nil {
+		configCredentialsOnce.Do(loadConfigCredentials)
+		credential = configCredentials[appKey]
+	}
+	cacheRWMutex.Lock()
+	credCache[appKey] = &credentialEntry{credential: credential, loadedAt: time.Now()}
+	cacheRWMutex.Unlock()
+	return
+}
+
+// loadConfigCredentials 加载配置文件中的服务端接口凭证
+func loadConfigCredentials() {
+	var list []*models.APICredential
+	if err := viper.UnmarshalKey("serverAPI.credentials", &list); err != nil {
+		fmt.Println("读取服务端接口凭证失败", err)
+	}
+	configCredentials = make(map[string]*models.APICredential, len(list))
+	for _, credential := range list {
+		if credential.AppKey == "" || credential.Secret == "" {
+			continue
+		}
+		configCredentials[credential.AppKey] = credential
+	}
+}
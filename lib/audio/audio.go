@@ -0,0 +1,202 @@
+// Package audio 音频处理(解码、时长、波形、WAV 封装)
+package audio
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// FormatPCM8k 8k 采样率 16bit 单声道小端 PCM
+	FormatPCM8k = "pcm_8k"
+	// FormatPCM16k 16k 采样率 16bit 单声道小端 PCM
+	FormatPCM16k = "pcm_16k"
+	// FormatPCM48k 48k 采样率 16bit 单声道小端 PCM
+	FormatPCM48k = "pcm_48k"
+	// FormatWAV RIFF/WAVE 封装的 16bit PCM
+	FormatWAV = "wav"
+	// FormatOpus Ogg 封装的 Opus
+	FormatOpus = "opus"
+
+	defaultDurationTolerance = 200 // 默认允许的时长误差(毫秒)
+	defaultWaveformBars      = 64  // 默认波形柱数量
+	waveformMax              = 100 // 波形最大值
+)
+
+var (
+	// ErrUnsupportedFormat 不支持的音频格式
+	ErrUnsupportedFormat = errors.New("不支持的音频格式")
+	// ErrDurationMismatch 音频时长与声明不一致
+	ErrDurationMismatch = errors.New("音频时长不匹配")
+	// ErrInvalidData 音频数据不合法
+	ErrInvalidData = errors.New("音频数据不合法")
+	// ErrNoDecoder 没有可用的解码器
+	ErrNoDecoder = errors.New("没有可用的音频解码器")
+
+	// 全部支持的格式
+	formats = []string{FormatPCM8k, FormatPCM16k, FormatPCM48k, FormatWAV, FormatOpus}
+)
+
+// IsInvalid 是否为客户端数据问题导致的错误
+func IsInvalid(err error) bool {
+	return errors.Is(err, ErrUnsupportedFormat) || errors.Is(err, ErrDurationMismatch) || errors.Is(err, ErrInvalidData)
+}
+
+// GetFormats 全部支持的格式
+func GetFormats() []string {
+	return formats
+}
+
+// IsSupported 格式是否支持
+func IsSupported(format string) bool {
+	for _, value := range formats {
+		if value == format {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDecodable 格式是否可以发送，服务端需要解码出 PCM 才能计算时长、波形并转码给其他接收方
+// opus 只有在启动时注册了解码器后才可以发送，接收方仍然可以声明 opus 接收原样转发的语音
+func IsDecodable(format string) bool {
+	if format == FormatOpus {
+		return getOpusDecoder() != nil
+	}
+	return IsSupported(format)
+}
+
+// IsPCM 是否为裸 PCM 格式
+func IsPCM(format string) bool {
+	_, ok := pcmFormats[format]
+	return ok
+}
+
+// Clip 解析后的音频
+type Clip struct {
+	Format     string // 原始格式
+	SampleRate int    // 采样率
+	Channels   int    // 声道数
+	Duration   int    // 时长(毫秒)
+	PCM        []byte // 16bit 小端交错 PCM，opus 未注册解码器时为空(此时不允许发送 opus)
+}
+
+// DecodeBase64 解码 base64 编码的音频数据
+func DecodeBase64(audioData string) (data []byte, err error) {
+	data, err = base64.StdEncoding.DecodeString(audioData)
+	if err != nil {
+		err = fmt.Errorf("%w: base64 解码失败", ErrInvalidData)
+		return
+	}
+	if len(data) == 0 {
+		err = fmt.Errorf("%w: 数据为空", ErrInvalidData)
+		return
+	}
+	return
+}
+
+// Parse 按格式校验头部和帧并解析音频
+func Parse(format string, data []byte) (clip *Clip, err error) {
+	if pcmFormat, ok := pcmFormats[format]; ok {
+		return pcmFormat.Parse(format, data)
+	}
+	switch format {
+	case FormatWAV:
+		return parseWAV(data)
+	case FormatOpus:
+		return parseOggOpus(data)
+	default:
+		err = ErrUnsupportedFormat
+		return
+	}
+}
+
+// ToPCM 转码为指定 PCM 格式(单声道、目标采样率)
+func (c *Clip) ToPCM(format string) (pcm []byte, err error) {
+	pcmFormat, ok := pcmFormats[format]
+	if !ok {
+		err = ErrUnsupportedFormat
+		return
+	}
+	if c.PCM == nil {
+		err = ErrNoDecoder
+		return
+	}
+	samples := downmix(c.PCM, c.Channels)
+	samples = resample(samples, c.SampleRate, pcmFormat.SampleRate)
+	pcm = samplesToBytes(samples)
+	return
+}
+
+// GetDurationTolerance 允许的时长误差(毫秒)
+func GetDurationTolerance() (tolerance int) {
+	tolerance = viper.GetInt("audio.durationTolerance")
+	if tolerance <= 0 {
+		tolerance = defaultDurationTolerance
+	}
+	return
+}
+
+// CheckDuration 校验客户端声明的时长，claimed 为 0 表示未声明
+func CheckDuration(claimed, actual int) (err error) {
+	if claimed == 0 {
+		return
+	}
+	diff := claimed - actual
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > GetDurationTolerance() {
+		err = ErrDurationMismatch
+	}
+	return
+}
+
+// Waveform 计算降采样后的振幅波形，每个值为对应区间峰值，范围 0-100
+func (c *Clip) Waveform() (waveform []int) {
+	bars := viper.GetInt("audio.waveformBars")
+	if bars <= 0 {
+		bars = defaultWaveformBars
+	}
+	if c.PCM == nil || c.Channels <= 0 {
+		return
+	}
+	blockAlign := c.Channels * 2
+	frames := len(c.PCM) / blockAlign
+	if frames < bars {
+		bars = frames
+	}
+	waveform = make([]int, bars)
+	for i := 0; i < bars; i++ {
+		start := i * frames / bars
+		end := (i + 1) * frames / bars
+		peak := 0
+		for frame := start; frame < end; frame++ {
+			// 多声道取第一个声道
+			sample := int(int16(binary.LittleEndian.Uint16(c.PCM[frame*blockAlign:])))
+			if sample < 0 {
+				sample = -sample
+			}
+			if sample > peak {
+				peak = sample
+			}
+		}
+		waveform[i] = peak * waveformMax / 32768
+	}
+	return
+}
+
+// ToWAV 封装为 WAV，无 PCM 数据时返回 ErrNoDecoder
+func (c *Clip) ToWAV() (wav []byte, err error) {
+	if c.PCM == nil {
+		err = ErrNoDecoder
+		return
+	}
+	pcmFormat := &PCMFormat{SampleRate: c.SampleRate, Channels: c.Channels, BitsPerSample: 16}
+	wav = pcmFormat.ToWAV(c.PCM)
+	return
+}
@@ -0,0 +1,315 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// wavChunk RIFF 块，奇数长度补齐一个字节
+func wavChunk(id string, body []byte) (chunk []byte) {
+	chunk = append([]byte(id), make([]byte, 4)...)
+	binary.LittleEndian.PutUint32(chunk[4:], uint32(len(body)))
+	chunk = append(chunk, body...)
+	if len(body)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return
+}
+
+func wavFmt(audioFormat, channels, sampleRate, bitsPerSample int) (body []byte) {
+	blockAlign := channels * bitsPerSample / 8
+	body = make([]byte, 16)
+	binary.LittleEndian.PutUint16(body[0:], uint16(audioFormat))
+	binary.LittleEndian.PutUint16(body[2:], uint16(channels))
+	binary.LittleEndian.PutUint32(body[4:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(body[8:], uint32(sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(body[12:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(body[14:], uint16(bitsPerSample))
+	return
+}
+
+func wavFile(chunks ...[]byte) (data []byte) {
+	body := []byte("WAVE")
+	for _, chunk := range chunks {
+		body = append(body, chunk...)
+	}
+	return wavChunk("RIFF", body)
+}
+
+func TestParseWAV(t *testing.T) {
+	mono := wavChunk("fmt ", wavFmt(1, 1, 8000, 16))
+	tests := []struct {
+		name         string
+		data         []byte
+		wantErr      error
+		wantChannels int
+		wantDuration int
+	}{
+		{name: "单声道", data: wavFile(mono, wavChunk("data", make([]byte, 16000))), wantChannels: 1,
+			wantDuration: 1000},
+		{name: "双声道", data: wavFile(wavChunk("fmt ", wavFmt(1, 2, 16000, 16)), wavChunk("data", make([]byte, 32000))),
+			wantChannels: 2, wantDuration: 500},
+		{name: "跳过奇数长度的其他块", data: wavFile(mono, wavChunk("LIST", []byte("abc")), wavChunk("data", make([]byte, 800))),
+			wantChannels: 1, wantDuration: 50},
+		{name: "不是 RIFF", data: []byte("RIFX\x00\x00\x00\x00WAVE"), wantErr: ErrInvalidData},
+		{name: "太短", data: []byte("RIFF"), wantErr: ErrInvalidData},
+		{name: "8bit", data: wavFile(wavChunk("fmt ", wavFmt(1, 1, 8000, 8)), wavChunk("data", make([]byte, 8))),
+			wantErr: ErrUnsupportedFormat},
+		{name: "浮点格式", data: wavFile(wavChunk("fmt ", wavFmt(3, 1, 8000, 16)), wavChunk("data", make([]byte, 8))),
+			wantErr: ErrUnsupportedFormat},
+		{name: "三声道", data: wavFile(wavChunk("fmt ", wavFmt(1, 3, 8000, 16)), wavChunk("data", make([]byte, 12))),
+			wantErr: ErrUnsupportedFormat},
+		{name: "采样率过高", data: wavFile(wavChunk("fmt ", wavFmt(1, 1, 96000, 16)), wavChunk("data", make([]byte, 8))),
+			wantErr: ErrUnsupportedFormat},
+		{name: "data 在 fmt 之前", data: wavFile(wavChunk("data", make([]byte, 8)), mono), wantErr: ErrInvalidData},
+		{name: "缺少 data", data: wavFile(mono), wantErr: ErrInvalidData},
+		{name: "data 为空", data: wavFile(mono, wavChunk("data", nil)), wantErr: ErrInvalidData},
+		{name: "data 未按帧对齐", data: wavFile(wavChunk("fmt ", wavFmt(1, 2, 8000, 16)), wavChunk("data", make([]byte, 6))),
+			wantErr: ErrInvalidData},
+		{name: "块长度越界", data: append(wavFile(mono), []byte("data\xff\x00\x00\x00")...), wantErr: ErrInvalidData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clip, err := Parse(FormatWAV, tt.data)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Parse() err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() err = %v", err)
+			}
+			if clip.Channels != tt.wantChannels || clip.Duration != tt.wantDuration {
+				t.Fatalf("Parse() = %d 声道 %dms, want %d 声道 %dms", clip.Channels, clip.Duration, tt.wantChannels,
+					tt.wantDuration)
+			}
+		})
+	}
+}
+
+func TestParsePCM(t *testing.T) {
+	tests := []struct {
+		name         string
+		format       string
+		size         int
+		wantErr      error
+		wantDuration int
+	}{
+		{name: "8k", format: FormatPCM8k, size: 16000, wantDuration: 1000},
+		{name: "16k", format: FormatPCM16k, size: 16000, wantDuration: 500},
+		{name: "48k", format: FormatPCM48k, size: 9600, wantDuration: 100},
+		{name: "空数据", format: FormatPCM8k, size: 0, wantErr: ErrInvalidData},
+		{name: "未按帧对齐", format: FormatPCM16k, size: 3, wantErr: ErrInvalidData},
+		{name: "未知格式", format: "mp3", size: 2, wantErr: ErrUnsupportedFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clip, err := Parse(tt.format, make([]byte, tt.size))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Parse() err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() err = %v", err)
+			}
+			if clip.Duration != tt.wantDuration {
+				t.Fatalf("Parse() duration = %d, want %d", clip.Duration, tt.wantDuration)
+			}
+		})
+	}
+}
+
+// 封装出的 WAV 可以被重新解析
+func TestToWAV(t *testing.T) {
+	pcm := samplesToBytes([]int16{0, 100, -100, 32767, -32768, 0, 1, -1})
+	clip := &Clip{Format: FormatPCM8k, SampleRate: 8000, Channels: 2, Duration: 0, PCM: pcm}
+	wav, err := clip.ToWAV()
+	if err != nil {
+		t.Fatalf("ToWAV() err = %v", err)
+	}
+	parsed, err := Parse(FormatWAV, wav)
+	if err != nil {
+		t.Fatalf("Parse() err = %v", err)
+	}
+	if parsed.SampleRate != 8000 || parsed.Channels != 2 || !bytes.Equal(parsed.PCM, pcm) {
+		t.Fatalf("Parse(ToWAV()) = %d %d %v", parsed.SampleRate, parsed.Channels, parsed.PCM)
+	}
+	if _, err = (&Clip{SampleRate: 48000, Channels: 1}).ToWAV(); !errors.Is(err, ErrNoDecoder) {
+		t.Fatalf("ToWAV() 没有 PCM err = %v, want %v", err, ErrNoDecoder)
+	}
+}
+
+func TestOpusPacketTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		packet  []byte
+		want    int
+		wantErr bool
+	}{
+		{name: "空包", packet: nil, wantErr: true},
+		{name: "CELT 20ms 单帧", packet: []byte{31 << 3}, want: 200},
+		{name: "CELT 2.5ms 单帧", packet: []byte{16 << 3}, want: 25},
+		{name: "SILK 60ms 两帧", packet: []byte{3<<3 | 1}, want: 1200},
+		{name: "SILK 10ms 两帧不等长", packet: []byte{0<<3 | 2}, want: 200},
+		{name: "任意帧数", packet: []byte{31<<3 | 3, 3}, want: 600},
+		{name: "帧数忽略 VBR 和填充标记", packet: []byte{31<<3 | 3, 0xC0 | 2}, want: 400},
+		{name: "缺少帧数", packet: []byte{31<<3 | 3}, wantErr: true},
+		{name: "帧数为 0", packet: []byte{31<<3 | 3, 0}, wantErr: true},
+		{name: "超过 120ms", packet: []byte{3<<3 | 3, 3}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := opusPacketTime(tt.packet)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("opusPacketTime(%v) err = %v, wantErr %v", tt.packet, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Fatalf("opusPacketTime(%v) = %d, want %d", tt.packet, got, tt.want)
+			}
+		})
+	}
+}
+
+// testOggPage 构造 Ogg 页并计算 CRC
+type testOggPage struct {
+	headerType byte
+	granule    int64
+	serial     uint32
+	sequence   uint32
+	packets    [][]byte
+	// continued 最后一个包在下一页继续，不写结尾的短段
+	continued bool
+}
+
+func (p testOggPage) bytes() (page []byte) {
+	var segments, body []byte
+	for i, packet := range p.packets {
+		size := len(packet)
+		for ; size >= 255; size -= 255 {
+			segments = append(segments, 255)
+		}
+		if !p.continued || i != len(p.packets)-1 {
+			segments = append(segments, byte(size))
+		}
+		body = append(body, packet...)
+	}
+	page = make([]byte, oggHeaderSize, oggHeaderSize+len(segments)+len(body))
+	copy(page, "OggS")
+	page[5] = p.headerType
+	binary.LittleEndian.PutUint64(page[6:], uint64(p.granule))
+	binary.LittleEndian.PutUint32(page[14:], p.serial)
+	binary.LittleEndian.PutUint32(page[18:], p.sequence)
+	page[26] = byte(len(segments))
+	page = append(page, segments...)
+	page = append(page, body...)
+	binary.LittleEndian.PutUint32(page[22:], oggCRC(page))
+	return
+}
+
+func oggStream(pages ...testOggPage) (data []byte) {
+	for _, page := range pages {
+		data = append(data, page.bytes()...)
+	}
+	return
+}
+
+func opusHead(channels, preSkip int) (head []byte) {
+	head = append([]byte("OpusHead"), 1, byte(channels), 0, 0, 0x80, 0xBB, 0, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint16(head[10:], uint16(preSkip))
+	return
+}
+
+func TestParseOggOpus(t *testing.T) {
+	const preSkip = 312
+	frame := []byte{31 << 3, 1, 2, 3} // CELT 20ms
+	frames := func(n int) (packets [][]byte) {
+		for i := 0; i < n; i++ {
+			packets = append(packets, frame)
+		}
+		return
+	}
+	headPage := testOggPage{headerType: oggFlagBOS, packets: [][]byte{opusHead(1, preSkip)}}
+	tagsPage := testOggPage{sequence: 1, packets: [][]byte{[]byte("OpusTags\x00\x00\x00\x00\x00\x00\x00\x00")}}
+	// 5 个 20ms 包共 4800 个采样
+	audioPage := testOggPage{sequence: 2, granule: preSkip + 4800, packets: frames(5)}
+	valid := oggStream(headPage, tagsPage, audioPage)
+
+	badCRC := append([]byte(nil), valid...)
+	badCRC[len(badCRC)-1] ^= 0xFF
+
+	// 大包跨页
+	large := append([]byte{31 << 3}, make([]byte, 300)...)
+	splitFirst := testOggPage{sequence: 2, packets: [][]byte{large[:255]}, continued: true}
+	splitSecond := testOggPage{headerType: oggFlagContinued, sequence: 3, granule: preSkip + 960,
+		packets: [][]byte{large[255:]}}
+
+	tests := []struct {
+		name         string
+		data         []byte
+		wantErr      error
+		wantChannels int
+		wantDuration int
+	}{
+		{name: "合法", data: valid, wantChannels: 1, wantDuration: 100},
+		{name: "双声道", data: oggStream(testOggPage{headerType: oggFlagBOS, packets: [][]byte{opusHead(2, preSkip)}},
+			tagsPage, audioPage), wantChannels: 2, wantDuration: 100},
+		{name: "granule 小于数据包时长", data: oggStream(headPage, tagsPage,
+			testOggPage{sequence: 2, granule: preSkip + 2400, packets: frames(5)}), wantChannels: 1, wantDuration: 50},
+		{name: "包跨页", data: oggStream(headPage, tagsPage, splitFirst, splitSecond), wantChannels: 1,
+			wantDuration: 20},
+		{name: "空数据", data: nil, wantErr: ErrInvalidData},
+		{name: "不是 Ogg", data: []byte("RIFF0000WAVEfmt 0000000000000000000"), wantErr: ErrInvalidData},
+		{name: "校验失败", data: badCRC, wantErr: ErrInvalidData},
+		{name: "页不完整", data: valid[:len(valid)-1], wantErr: ErrInvalidData},
+		{name: "granule 超过数据包时长", data: oggStream(headPage, tagsPage,
+			testOggPage{sequence: 2, granule: preSkip + 9600, packets: frames(5)}), wantErr: ErrInvalidData},
+		{name: "granule 不大于 pre-skip", data: oggStream(headPage, tagsPage,
+			testOggPage{sequence: 2, granule: preSkip, packets: frames(5)}), wantErr: ErrInvalidData},
+		{name: "缺少 OpusHead", data: oggStream(testOggPage{headerType: oggFlagBOS,
+			packets: [][]byte{[]byte("OpusHeaX")}}, tagsPage, audioPage), wantErr: ErrInvalidData},
+		{name: "缺少 OpusTags", data: oggStream(headPage,
+			testOggPage{sequence: 1, packets: [][]byte{[]byte("Comments")}}, audioPage), wantErr: ErrInvalidData},
+		{name: "缺少音频包", data: oggStream(headPage, tagsPage), wantErr: ErrInvalidData},
+		{name: "声道数为 0", data: oggStream(testOggPage{headerType: oggFlagBOS, packets: [][]byte{opusHead(0, preSkip)}},
+			tagsPage, audioPage), wantErr: ErrUnsupportedFormat},
+		{name: "OpusHead 与 OpusTags 同页", data: oggStream(testOggPage{headerType: oggFlagBOS,
+			packets: [][]byte{opusHead(1, preSkip), tagsPage.packets[0]}}, testOggPage{sequence: 1,
+			granule: preSkip + 4800, packets: frames(5)}), wantErr: ErrInvalidData},
+		{name: "页序号不连续", data: oggStream(headPage, tagsPage,
+			testOggPage{sequence: 3, granule: preSkip + 4800, packets: frames(5)}), wantErr: ErrInvalidData},
+		{name: "缺少流开始标记", data: oggStream(testOggPage{packets: [][]byte{opusHead(1, preSkip)}}, tagsPage,
+			audioPage), wantErr: ErrInvalidData},
+		{name: "多个逻辑流", data: oggStream(headPage, tagsPage,
+			testOggPage{sequence: 2, serial: 1, granule: preSkip + 4800, packets: frames(5)}),
+			wantErr: ErrUnsupportedFormat},
+		{name: "续包标记缺失", data: oggStream(headPage, tagsPage, splitFirst,
+			testOggPage{sequence: 3, granule: preSkip + 960, packets: [][]byte{large[255:]}}), wantErr: ErrInvalidData},
+		{name: "最后一个包不完整", data: oggStream(headPage, tagsPage, splitFirst), wantErr: ErrInvalidData},
+		{name: "音频包超过 120ms", data: oggStream(headPage, tagsPage,
+			testOggPage{sequence: 2, granule: preSkip + 960, packets: [][]byte{{3<<3 | 3, 3}}}),
+			wantErr: ErrInvalidData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clip, err := Parse(FormatOpus, tt.data)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Parse() err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() err = %v", err)
+			}
+			if clip.Channels != tt.wantChannels || clip.Duration != tt.wantDuration || clip.PCM != nil {
+				t.Fatalf("Parse() = %d 声道 %dms, want %d 声道 %dms", clip.Channels, clip.Duration, tt.wantChannels,
+					tt.wantDuration)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+// Package audio 音频处理(解码、时长、波形、WAV 封装)
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+const (
+	oggHeaderSize     = 27
+	oggFlagContinued  = 0x01
+	oggFlagBOS        = 0x02
+	opusSampleRate    = 48000 // Opus 解码输出采样率
+	opusMaxPacketTime = 1200  // 单个包最长 120ms，单位 0.1ms
+)
+
+var (
+	oggCRCTable = makeOggCRCTable()
+
+	// frame 时长，单位 0.1ms，按 TOC config 索引
+	opusFrameTimes = [32]int{
+		100, 200, 400, 600, 100, 200, 400, 600, 100, 200, 400, 600, // SILK
+		100, 200, 100, 200, // Hybrid
+		25, 50, 100, 200, 25, 50, 100, 200, 25, 50, 100, 200, 25, 50, 100, 200, // CELT
+	}
+
+	opusDecoder     OpusDecodeFunc
+	opusDecoderLock sync.RWMutex
+)
+
+// OpusDecodeFunc Opus 解码函数，返回 48k 采样率 16bit 小端交错 PCM
+type OpusDecodeFunc func(packets [][]byte, channels int, preSkip int) (pcm []byte, err error)
+
+// RegisterOpusDecoder 注册 Opus 解码器
+// 纯 Go 环境没有完整的 Opus 解码实现，可在启动时注册基于 libopus 的解码器；
+// 未注册时不接收客户端发送的 opus 语音(见 IsDecodable)，避免只支持 PCM 的接收方收不到可播放的音频
+func RegisterOpusDecoder(f OpusDecodeFunc) {
+	opusDecoderLock.Lock()
+	defer opusDecoderLock.Unlock()
+	opusDecoder = f
+}
+
+func getOpusDecoder() (f OpusDecodeFunc) {
+	opusDecoderLock.RLock()
+	defer opusDecoderLock.RUnlock()
+	return opusDecoder
+}
+
+// oggPage Ogg 页
+type oggPage struct {
+	HeaderType byte
+	Granule    int64
+	Serial     uint32
+	Sequence   uint32
+	Segments   []byte
+	Body       []byte
+}
+
+func makeOggCRCTable() (table [256]uint32) {
+	for i := 0; i < 256; i++ {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = (r << 1) ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return
+}
+
+func oggCRC(data []byte) (crc uint32) {
+	for _, b := range data {
+		crc = (crc << 8) ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return
+}
+
+// readOggPages 读取并校验全部 Ogg 页
+func readOggPages(data []byte) (pages []*oggPage, err error) {
+	pos := 0
+	for pos < len(data) {
+		if pos+oggHeaderSize > len(data) || string(data[pos:pos+4]) != "OggS" {
+			err = fmt.Errorf("%w: Ogg 页头错误", ErrInvalidData)
+			return
+		}
+		if data[pos+4] != 0 {
+			err = fmt.Errorf("%w: Ogg 版本错误", ErrInvalidData)
+			return
+		}
+		segmentCount := int(data[pos+26])
+		headerEnd := pos + oggHeaderSize + segmentCount
+		if headerEnd > len(data) {
+			err = fmt.Errorf("%w: Ogg 段表不完整", ErrInvalidData)
+			return
+		}
+		segments := data[pos+oggHeaderSize : headerEnd]
+		bodySize := 0
+		for _, size := range segments {
+			bodySize += int(size)
+		}
+		pageEnd := headerEnd + bodySize
+		if pageEnd > len(data) {
+			err = fmt.Errorf("%w: Ogg 页数据不完整", ErrInvalidData)
+			return
+		}
+
+		// 校验 CRC，计算时 CRC 字段置 0
+		page := make([]byte, pageEnd-pos)
+		copy(page, data[pos:pageEnd])
+		checksum := binary.LittleEndian.Uint32(page[22:26])
+		binary.LittleEndian.PutUint32(page[22:26], 0)
+		if oggCRC(page) != checksum {
+			err = fmt.Errorf("%w: Ogg 页校验失败", ErrInvalidData)
+			return
+		}
+
+		pages = append(pages, &oggPage{
+			HeaderType: data[pos+5],
+			Granule:    int64(binary.LittleEndian.Uint64(data[pos+6 : pos+14])),
+			Serial:     binary.LittleEndian.Uint32(data[pos+14 : pos+18]),
+			Sequence:   binary.LittleEndian.Uint32(data[pos+18 : pos+22]),
+			Segments:   segments,
+			Body:       data[headerEnd:pageEnd],
+		})
+		pos = pageEnd
+	}
+	if len(pages) == 0 {
+		err = fmt.Errorf("%w: 没有 Ogg 页", ErrInvalidData)
+	}
+	return
+}
+
+// oggPackets 按段表把页数据组装成包
+func oggPackets(pages []*oggPage) (packets [][]byte, err error) {
+	var current []byte
+	for i, page := range pages {
+		if page.Serial != pages[0].Serial {
+			err = fmt.Errorf("%w: 只支持单个逻辑流", ErrUnsupportedFormat)
+			return
+		}
+		if page.Sequence != uint32(i) {
+			err = fmt.Errorf("%w: Ogg 页序号不连续", ErrInvalidData)
+			return
+		}
+		if (page.HeaderType&oggFlagBOS != 0) != (i == 0) {
+			err = fmt.Errorf("%w: Ogg 流开始标记错误", ErrInvalidData)
+			return
+		}
+		continued := page.HeaderType&oggFlagContinued != 0
+		if continued != (current != nil) {
+			err = fmt.Errorf("%w: Ogg 续包标记错误", ErrInvalidData)
+			return
+		}
+		offset := 0
+		for _, size := range page.Segments {
+			current = append(current, page.Body[offset:offset+int(size)]...)
+			offset += int(size)
+			if size < 255 {
+				packets = append(packets, current)
+				current = nil
+			}
+		}
+	}
+	if current != nil {
+		err = fmt.Errorf("%w: Ogg 最后一个包不完整", ErrInvalidData)
+	}
+	return
+}
+
+// opusPacketTime 根据 TOC 计算包时长，单位 0.1ms
+func opusPacketTime(packet []byte) (packetTime int, err error) {
+	if len(packet) == 0 {
+		err = fmt.Errorf("%w: Opus 空包", ErrInvalidData)
+		return
+	}
+	toc := packet[0]
+	frameTime := opusFrameTimes[toc>>3]
+	frames := 0
+	switch toc & 0x03 {
+	case 0:
+		frames = 1
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(packet) < 2 {
+			err = fmt.Errorf("%w: Opus 包缺少帧数", ErrInvalidData)
+			return
+		}
+		frames = int(packet[1] & 0x3F)
+	}
+	if frames == 0 {
+		err = fmt.Errorf("%w: Opus 帧数为 0", ErrInvalidData)
+		return
+	}
+	packetTime = frames * frameTime
+	if packetTime > opusMaxPacketTime {
+		err = fmt.Errorf("%w: Opus 包时长超过 120ms", ErrInvalidData)
+	}
+	return
+}
+
+// parseOggOpus 校验 Ogg 封装、OpusHead/OpusTags 头和每个音频包
+func parseOggOpus(data []byte) (clip *Clip, err error) {
+	pages, err := readOggPages(data)
+	if err != nil {
+		return
+	}
+	packets, err := oggPackets(pages)
+	if err != nil {
+		return
+	}
+	if len(packets) < 3 {
+		err = fmt.Errorf("%w: Opus 流缺少数据包", ErrInvalidData)
+		return
+	}
+
+	head := packets[0]
+	if len(head) < 19 || !bytes.HasPrefix(head, []byte("OpusHead")) {
+		err = fmt.Errorf("%w: 缺少 OpusHead", ErrInvalidData)
+		return
+	}
+	if head[8]>>4 != 0 {
+		err = fmt.Errorf("%w: OpusHead 版本 %d", ErrUnsupportedFormat, head[8])
+		return
+	}
+	channels := int(head[9])
+	preSkip := int(binary.LittleEndian.Uint16(head[10:12]))
+	mappingFamily := head[18]
+	if channels < 1 || (mappingFamily == 0 && channels > 2) {
+		err = fmt.Errorf("%w: Opus 声道数 %d", ErrUnsupportedFormat, channels)
+		return
+	}
+	// OpusHead 必须单独占一页
+	if len(pages[0].Segments) == 0 || !bytes.Equal(pages[0].Body, head) {
+		err = fmt.Errorf("%w: OpusHead 页错误", ErrInvalidData)
+		return
+	}
+	if !bytes.HasPrefix(packets[1], []byte("OpusTags")) {
+		err = fmt.Errorf("%w: 缺少 OpusTags", ErrInvalidData)
+		return
+	}
+
+	audioPackets := packets[2:]
+	totalTime := 0
+	for _, packet := range audioPackets {
+		packetTime, err := opusPacketTime(packet)
+		if err != nil {
+			return nil, err
+		}
+		totalTime += packetTime
+	}
+
+	// 时长以最后一页的 granule 为准(去除 pre-skip 和尾部裁剪)
+	lastGranule := pages[len(pages)-1].Granule
+	samples := lastGranule - int64(preSkip)
+	if samples <= 0 {
+		err = fmt.Errorf("%w: Opus granule 错误", ErrInvalidData)
+		return
+	}
+	duration := int(samples * 1000 / opusSampleRate)
+	if duration > totalTime/10 {
+		err = fmt.Errorf("%w: Opus granule 超过数据包总时长", ErrInvalidData)
+		return
+	}
+
+	clip = &Clip{
+		Format:     FormatOpus,
+		SampleRate: opusSampleRate,
+		Channels:   channels,
+		Duration:   duration,
+	}
+	if decoder := getOpusDecoder(); decoder != nil {
+		clip.PCM, err = decoder(audioPackets, channels, preSkip)
+		if err != nil {
+			err = fmt.Errorf("%w: Opus 解码失败 %v", ErrInvalidData, err)
+			return nil, err
+		}
+	}
+	return
+}
@@ -0,0 +1,123 @@
+// Package audio 音频处理(解码、时长、波形、WAV 封装)
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// PCMFormat PCM 参数
+type PCMFormat struct {
+	SampleRate    int // 采样率
+	Channels      int // 声道数
+	BitsPerSample int // 采样位数
+}
+
+var pcmFormats = map[string]*PCMFormat{
+	FormatPCM8k:  {SampleRate: 8000, Channels: 1, BitsPerSample: 16},
+	FormatPCM16k: {SampleRate: 16000, Channels: 1, BitsPerSample: 16},
+	FormatPCM48k: {SampleRate: 48000, Channels: 1, BitsPerSample: 16},
+}
+
+// BlockAlign 每帧字节数
+func (f *PCMFormat) BlockAlign() int {
+	return f.Channels * f.BitsPerSample / 8
+}
+
+// Duration 计算 PCM 数据时长(毫秒)
+func (f *PCMFormat) Duration(dataLen int) (duration int) {
+	frames := dataLen / f.BlockAlign()
+	duration = int(int64(frames) * 1000 / int64(f.SampleRate))
+	return
+}
+
+// Parse 校验 PCM 数据帧对齐
+func (f *PCMFormat) Parse(format string, pcm []byte) (clip *Clip, err error) {
+	if len(pcm) == 0 {
+		err = fmt.Errorf("%w: 数据为空", ErrInvalidData)
+		return
+	}
+	if len(pcm)%f.BlockAlign() != 0 {
+		err = fmt.Errorf("%w: 长度与采样格式不匹配", ErrInvalidData)
+		return
+	}
+	clip = &Clip{
+		Format:     format,
+		SampleRate: f.SampleRate,
+		Channels:   f.Channels,
+		Duration:   f.Duration(len(pcm)),
+		PCM:        pcm,
+	}
+	return
+}
+
+// ToWAV 将 PCM 数据封装为 WAV
+func (f *PCMFormat) ToWAV(pcm []byte) (wav []byte) {
+	buf := bytes.NewBuffer(make([]byte, 0, 44+len(pcm)))
+	byteRate := f.SampleRate * f.BlockAlign()
+	buf.WriteString("RIFF")
+	_ = binary.Write(buf, binary.LittleEndian, uint32(36+len(pcm)))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	_ = binary.Write(buf, binary.LittleEndian, uint32(16))
+	_ = binary.Write(buf, binary.LittleEndian, uint16(1)) // PCM
+	_ = binary.Write(buf, binary.LittleEndian, uint16(f.Channels))
+	_ = binary.Write(buf, binary.LittleEndian, uint32(f.SampleRate))
+	_ = binary.Write(buf, binary.LittleEndian, uint32(byteRate))
+	_ = binary.Write(buf, binary.LittleEndian, uint16(f.BlockAlign()))
+	_ = binary.Write(buf, binary.LittleEndian, uint16(f.BitsPerSample))
+	buf.WriteString("data")
+	_ = binary.Write(buf, binary.LittleEndian, uint32(len(pcm)))
+	buf.Write(pcm)
+	wav = buf.Bytes()
+	return
+}
+
+// downmix 混合为单声道样本
+func downmix(pcm []byte, channels int) (samples []int16) {
+	if channels <= 0 {
+		channels = 1
+	}
+	frames := len(pcm) / (channels * 2)
+	samples = make([]int16, frames)
+	for i := 0; i < frames; i++ {
+		sum := 0
+		for ch := 0; ch < channels; ch++ {
+			sum += int(int16(binary.LittleEndian.Uint16(pcm[(i*channels+ch)*2:])))
+		}
+		samples[i] = int16(sum / channels)
+	}
+	return
+}
+
+// resample 线性插值重采样
+func resample(samples []int16, fromRate, toRate int) (result []int16) {
+	if fromRate == toRate || len(samples) == 0 {
+		return samples
+	}
+	length := int(int64(len(samples)) * int64(toRate) / int64(fromRate))
+	result = make([]int16, length)
+	for i := 0; i < length; i++ {
+		// 目标样本在源中的位置，定点数避免浮点误差累积
+		pos := int64(i) * int64(fromRate)
+		index := int(pos / int64(toRate))
+		frac := pos % int64(toRate)
+		if index+1 >= len(samples) {
+			result[i] = samples[len(samples)-1]
+			continue
+		}
+		a := int64(samples[index])
+		b := int64(samples[index+1])
+		result[i] = int16(a + (b-a)*frac/int64(toRate))
+	}
+	return
+}
+
+func samplesToBytes(samples []int16) (pcm []byte) {
+	pcm = make([]byte, len(samples)*2)
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(sample))
+	}
+	return
+}
@@ -0,0 +1,93 @@
+// Package audio 音频处理(解码、时长、波形、WAV 封装)
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	wavMinSampleRate = 8000
+	wavMaxSampleRate = 48000
+)
+
+// parseWAV 校验 RIFF/WAVE 头部，只接受 16bit PCM 单声道/双声道
+func parseWAV(data []byte) (clip *Clip, err error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		err = fmt.Errorf("%w: 不是 WAV 文件", ErrInvalidData)
+		return
+	}
+	var (
+		fmtFound   bool
+		channels   int
+		sampleRate int
+		blockAlign int
+		pcm        []byte
+	)
+	pos := 12
+	for pos+8 <= len(data) {
+		chunkID := string(data[pos : pos+4])
+		chunkSize := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		body := pos + 8
+		if chunkSize < 0 || body+chunkSize > len(data) {
+			err = fmt.Errorf("%w: WAV 块 %s 长度错误", ErrInvalidData, chunkID)
+			return
+		}
+		chunk := data[body : body+chunkSize]
+		switch chunkID {
+		case "fmt ":
+			if chunkSize < 16 {
+				err = fmt.Errorf("%w: WAV fmt 块长度错误", ErrInvalidData)
+				return
+			}
+			audioFormat := binary.LittleEndian.Uint16(chunk[0:2])
+			channels = int(binary.LittleEndian.Uint16(chunk[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(chunk[4:8]))
+			byteRate := int(binary.LittleEndian.Uint32(chunk[8:12]))
+			blockAlign = int(binary.LittleEndian.Uint16(chunk[12:14]))
+			bitsPerSample := int(binary.LittleEndian.Uint16(chunk[14:16]))
+			if audioFormat != 1 || bitsPerSample != 16 {
+				err = fmt.Errorf("%w: WAV 只支持 16bit PCM", ErrUnsupportedFormat)
+				return
+			}
+			if channels < 1 || channels > 2 {
+				err = fmt.Errorf("%w: WAV 声道数 %d", ErrUnsupportedFormat, channels)
+				return
+			}
+			if sampleRate < wavMinSampleRate || sampleRate > wavMaxSampleRate {
+				err = fmt.Errorf("%w: WAV 采样率 %d", ErrUnsupportedFormat, sampleRate)
+				return
+			}
+			if blockAlign != channels*2 || byteRate != sampleRate*blockAlign {
+				err = fmt.Errorf("%w: WAV fmt 参数不一致", ErrInvalidData)
+				return
+			}
+			fmtFound = true
+		case "data":
+			if !fmtFound {
+				err = fmt.Errorf("%w: WAV data 块在 fmt 块之前", ErrInvalidData)
+				return
+			}
+			pcm = chunk
+		}
+		// 块按偶数字节对齐
+		pos = body + chunkSize + chunkSize%2
+	}
+	if !fmtFound || pcm == nil {
+		err = fmt.Errorf("%w: WAV 缺少 fmt 或 data 块", ErrInvalidData)
+		return
+	}
+	if len(pcm) == 0 || len(pcm)%blockAlign != 0 {
+		err = fmt.Errorf("%w: WAV 数据长度与采样格式不匹配", ErrInvalidData)
+		return
+	}
+	frames := len(pcm) / blockAlign
+	clip = &Clip{
+		Format:     FormatWAV,
+		SampleRate: sampleRate,
+		Channels:   channels,
+		Duration:   int(int64(frames) * 1000 / int64(sampleRate)),
+		PCM:        pcm,
+	}
+	return
+}
@@ -0,0 +1,294 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	adminAccountsKey         = "admin:accounts"          // 全部管理员用户名 set
+	adminAccountPrefix       = "admin:account:"          // 管理员账号 json
+	adminSessionPrefix       = "admin:session:"          // 管理员登录会话 => 用户名，key 使用令牌的 sha256
+	adminAccountSessionsPref = "admin:account:sessions:" // 管理员的全部登录会话 set
+	adminAuditKey            = "admin:audit"             // 审计日志 stream，只追加不修改
+	adminAuthFailPrefix      = "admin:auth:fail:"        // 管理员登录失败次数，与普通用户分开统计
+	adminAuthLockPrefix      = "admin:auth:lock:"        // 管理员登录锁定
+	auditScanBatch           = 200                       // 查询审计日志时每次读取的条数
+	auditScanMax             = 5000                      // 单次查询最多扫描的条数
+)
+
+func getAdminAccountKey(username string) (key string) {
+	key = fmt.Sprintf("%s%s", adminAccountPrefix, username)
+	return
+}
+
+func getAdminSessionKey(token string) (key string) {
+	sum := sha256.Sum256([]byte(token))
+	key = fmt.Sprintf("%s%s", adminSessionPrefix, hex.EncodeToString(sum[:]))
+	return
+}
+
+func getAdminAccountSessionsKey(username string) (key string) {
+	key = fmt.Sprintf("%s%s", adminAccountSessionsPref, username)
+	return
+}
+
+func getAdminAuthFailKey(username string) (key string) {
+	key = fmt.Sprintf("%s%s", adminAuthFailPrefix, username)
+	return
+}
+
+func getAdminAuthLockKey(username string) (key string) {
+	key = fmt.Sprintf("%s%s", adminAuthLockPrefix, username)
+	return
+}
+
+// GetAdminLoginLockTTL 管理员登录锁定的剩余时间，未锁定返回 0
+func GetAdminLoginLockTTL(username string) (ttl time.Duration) {
+	return getLockTTL(getAdminAuthLockKey(username))
+}
+
+// AddAdminLoginFailure 记录一次管理员登录失败，window 内失败 maxFailures 次后锁定 lockDuration
+func AddAdminLoginFailure(username string, maxFailures int64, window, lockDuration time.Duration) (failures int64,
+	locked bool) {
+	return addFailure(getAdminAuthFailKey(username), getAdminAuthLockKey(username), maxFailures, window, lockDuration)
+}
+
+// ClearAdminLoginFailures 管理员登录成功后清除失败记录和锁定
+func ClearAdminLoginFailures(username string) {
+	redislib.GetClient().Del(context.Background(), getAdminAuthFailKey(username), getAdminAuthLockKey(username))
+}
+
+// GetAdminAccount 获取管理员账号，不存在返回 nil
+func GetAdminAccount(username string) (account *models.AdminAccount, err error) {
+	value, err := redislib.GetClient().Get(context.Background(), getAdminAccountKey(username)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		fmt.Println("获取管理员账号失败", username, err)
+		return
+	}
+	account = &models.AdminAccount{}
+	err = json.Unmarshal([]byte(value), account)
+	return
+}
+
+// GetAdminAccounts 获取全部管理员账号
+func GetAdminAccounts() (accounts []*models.AdminAccount, err error) {
+	usernames, err := redislib.GetClient().SMembers(context.Background(), adminAccountsKey).Result()
+	if err != nil {
+		return
+	}
+	accounts = make([]*models.AdminAccount, 0, len(usernames))
+	for _, username := range usernames {
+		account, err := GetAdminAccount(username)
+		if err != nil {
+			return nil, err
+		}
+		if account != nil {
+			accounts = append(accounts, account)
+		}
+	}
+	return
+}
+
+// CountAdminAccounts 管理员账号数量
+func CountAdminAccounts() (count int64, err error) {
+	count, err = redislib.GetClient().SCard(context.Background(), adminAccountsKey).Result()
+	return
+}
+
+// CreateAdminAccount 创建管理员账号，用户名已存在返回 created=false
+func CreateAdminAccount(account *models.AdminAccount) (created bool, err error) {
+	value, err := json.Marshal(account)
+	if err != nil {
+		return
+	}
+	ctx := context.Background()
+	redisClient := redislib.GetClient()
+	created, err = redisClient.SetNX(ctx, getAdminAccountKey(account.Username), value, 0).Result()
+	if err != nil || !created {
+		return
+	}
+	err = redisClient.SAdd(ctx, adminAccountsKey, account.Username).Err()
+	return
+}
+
+// SaveAdminAccount 保存管理员账号
+func SaveAdminAccount(account *models.AdminAccount) (err error) {
+	value, err := json.Marshal(account)
+	if err != nil {
+		return
+	}
+	err = redislib.GetClient().Set(context.Background(), getAdminAccountKey(account.Username), value, 0).Err()
+	if err != nil {
+		fmt.Println("保存管理员账号失败", account.Username, err)
+	}
+	return
+}
+
+// DeleteAdminAccount 删除管理员账号及其全部登录会话
+func DeleteAdminAccount(username string) (err error) {
+	if err = DeleteAdminSessions(username); err != nil {
+		return
+	}
+	ctx := context.Background()
+	pipe := redislib.GetClient().TxPipeline()
+	pipe.Del(ctx, getAdminAccountKey(username))
+	pipe.SRem(ctx, adminAccountsKey, username)
+	_, err = pipe.Exec(ctx)
+	return
+}
+
+// CreateAdminSession 保存管理员登录会话
+func CreateAdminSession(token string, username string, ttl time.Duration) (err error) {
+	ctx := context.Background()
+	sessionKey := getAdminSessionKey(token)
+	pipe := redislib.GetClient().TxPipeline()
+	pipe.Set(ctx, sessionKey, username, ttl)
+	pipe.SAdd(ctx, getAdminAccountSessionsKey(username), sessionKey)
+	pipe.Expire(ctx, getAdminAccountSessionsKey(username), ttl)
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		fmt.Println("保存管理员登录会话失败", username, err)
+	}
+	return
+}
+
+// GetAdminSession 获取登录会话的管理员用户名，无效返回空
+func GetAdminSession(token string) (username string, err error) {
+	username, err = redislib.GetClient().Get(context.Background(), getAdminSessionKey(token)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return
+}
+
+// DeleteAdminSession 退出登录
+func DeleteAdminSession(token string, username string) (err error) {
+	ctx := context.Background()
+	sessionKey := getAdminSessionKey(token)
+	pipe := redislib.GetClient().TxPipeline()
+	pipe.Del(ctx, sessionKey)
+	pipe.SRem(ctx, getAdminAccountSessionsKey(username), sessionKey)
+	_, err = pipe.Exec(ctx)
+	return
+}
+
+// DeleteAdminSessions 删除管理员的全部登录会话，停用账号或修改密码时调用
+func DeleteAdminSessions(username string) (err error) {
+	ctx := context.Background()
+	redisClient := redislib.GetClient()
+	setKey := getAdminAccountSessionsKey(username)
+	sessionKeys, err := redisClient.SMembers(ctx, setKey).Result()
+	if err != nil {
+		return
+	}
+	err = redisClient.Del(ctx, append(sessionKeys, setKey)...).Err()
+	return
+}
+
+// AddAuditLog 追加审计日志
+func AddAuditLog(log *models.AuditLog) (err error) {
+	err = redislib.GetClient().XAdd(context.Background(), &redis.XAddArgs{
+		Stream: adminAuditKey,
+		Values: map[string]interface{}{
+			"time":   log.Time,
+			"admin":  log.Admin,
+			"role":   log.Role,
+			"action": log.Action,
+			"method": log.Method,
+			"path":   log.Path,
+			"params": log.Params,
+			"ip":     log.IP,
+			"code":   log.Code,
+			"detail": log.Detail,
+		},
+	}).Err()
+	if err != nil {
+		fmt.Println("记录审计日志失败", log.Admin, log.Action, err)
+	}
+	return
+}
+
+func newAuditLog(message redis.XMessage) (log *models.AuditLog) {
+	getString := func(field string) string {
+		value, _ := message.Values[field].(string)
+		return value
+	}
+	timestamp, _ := strconv.ParseInt(getString("time"), 10, 64)
+	code, _ := strconv.ParseUint(getString("code"), 10, 32)
+	return &models.AuditLog{
+		ID:     message.ID,
+		Time:   timestamp,
+		Admin:  getString("admin"),
+		Role:   getString("role"),
+		Action: getString("action"),
+		Method: getString("method"),
+		Path:   getString("path"),
+		Params: getString("params"),
+		IP:     getString("ip"),
+		Code:   uint32(code),
+		Detail: getString("detail"),
+	}
+}
+
+// GetAuditLogs 按时间倒序查询审计日志
+// cursor 为上一页最后一条日志的ID，startTime、endTime 为秒级时间范围(0 表示不限制)，filter 为空时不过滤
+// 单次最多扫描 auditScanMax 条，未扫描完时 nextCursor 不为空
+func GetAuditLogs(cursor string, startTime, endTime int64, filter func(*models.AuditLog) bool,
+	limit int) (logs []*models.AuditLog, nextCursor string, err error) {
+	ctx := context.Background()
+	redisClient := redislib.GetClient()
+	maxID, minID := "+", "-"
+	if endTime > 0 {
+		maxID = strconv.FormatInt(endTime*1000+999, 10)
+	}
+	if cursor != "" {
+		maxID = cursor
+	}
+	if startTime > 0 {
+		minID = strconv.FormatInt(startTime*1000, 10)
+	}
+
+	logs = make([]*models.AuditLog, 0, limit)
+	for scanned := 0; scanned < auditScanMax; {
+		messages, err := redisClient.XRevRangeN(ctx, adminAuditKey, maxID, minID, auditScanBatch).Result()
+		if err != nil {
+			return nil, "", err
+		}
+		for _, message := range messages {
+			// 游标本身已在上一页返回
+			if message.ID == cursor {
+				continue
+			}
+			scanned++
+			nextCursor = message.ID
+			log := newAuditLog(message)
+			if filter == nil || filter(log) {
+				logs = append(logs, log)
+				if len(logs) >= limit {
+					return logs, nextCursor, nil
+				}
+			}
+		}
+		if len(messages) < auditScanBatch {
+			return logs, "", nil
+		}
+		maxID, cursor = nextCursor, nextCursor
+	}
+	return
+}
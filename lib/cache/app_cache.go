@@ -0,0 +1,186 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	appIDsKey              = "app:ids"          // 全部应用ID set
+	appInfoPrefix          = "app:info:"        // 应用配置 json
+	appCredentialPrefix    = "app:credential:"  // 服务端接口凭证 json，key 为 appKey
+	appCredentialSetPrefix = "app:credentials:" // 应用的全部 appKey set
+)
+
+func getAppInfoKey(appID string) (key string) {
+	key = fmt.Sprintf("%s%s", appInfoPrefix, appID)
+	return
+}
+
+func getAppCredentialKey(appKey string) (key string) {
+	key = fmt.Sprintf("%s%s", appCredentialPrefix, appKey)
+	return
+}
+
+func getAppCredentialSetKey(appID string) (key string) {
+	key = fmt.Sprintf("%s%s", appCredentialSetPrefix, appID)
+	return
+}
+
+// GetAppIDs 全部应用ID
+func GetAppIDs() (appIDs []string, err error) {
+	appIDs, err = redislib.GetClient().SMembers(context.Background(), appIDsKey).Result()
+	if err != nil {
+		fmt.Println("获取应用列表失败", err)
+	}
+	return
+}
+
+// GetApp 获取应用，不存在返回 nil
+func GetApp(appID string) (app *models.App, err error) {
+	value, err := redislib.GetClient().Get(context.Background(), getAppInfoKey(appID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		fmt.Println("获取应用失败", appID, err)
+		return
+	}
+	app = &models.App{}
+	err = json.Unmarshal([]byte(value), app)
+	return
+}
+
+// GetApps 获取全部应用
+func GetApps() (apps []*models.App, err error) {
+	appIDs, err := GetAppIDs()
+	if err != nil {
+		return
+	}
+	apps = make([]*models.App, 0, len(appIDs))
+	for _, appID := range appIDs {
+		app, err := GetApp(appID)
+		if err != nil {
+			return nil, err
+		}
+		if app != nil {
+			apps = append(apps, app)
+		}
+	}
+	return
+}
+
+// CreateApp 创建应用，appID 已存在返回 created=false
+func CreateApp(app *models.App) (created bool, err error) {
+	value, err := json.Marshal(app)
+	if err != nil {
+		return
+	}
+	ctx := context.Background()
+	redisClient := redislib.GetClient()
+	created, err = redisClient.SetNX(ctx, getAppInfoKey(app.AppID), value, 0).Result()
+	if err != nil || !created {
+		return
+	}
+	err = redisClient.SAdd(ctx, appIDsKey, app.AppID).Err()
+	return
+}
+
+// SaveApp 保存应用配置
+func SaveApp(app *models.App) (err error) {
+	value, err := json.Marshal(app)
+	if err != nil {
+		return
+	}
+	err = redislib.GetClient().Set(context.Background(), getAppInfoKey(app.AppID), value, 0).Err()
+	if err != nil {
+		fmt.Println("保存应用失败", app.AppID, err)
+	}
+	return
+}
+
+// DeleteApp 删除应用及其全部服务端接口凭证
+func DeleteApp(appID string) (err error) {
+	ctx := context.Background()
+	redisClient := redislib.GetClient()
+	appKeys, err := redisClient.SMembers(ctx, getAppCredentialSetKey(appID)).Result()
+	if err != nil {
+		return
+	}
+	pipe := redisClient.TxPipeline()
+	for _, appKey := range appKeys {
+		pipe.Del(ctx, getAppCredentialKey(appKey))
+	}
+	pipe.Del(ctx, getAppCredentialSetKey(appID), getAppInfoKey(appID))
+	pipe.SRem(ctx, appIDsKey, appID)
+	_, err = pipe.Exec(ctx)
+	return
+}
+
+// GetAPICredential 获取服务端接口凭证，不存在返回 nil
+func GetAPICredential(appKey string) (credential *models.APICredential, err error) {
+	value, err := redislib.GetClient().Get(context.Background(), getAppCredentialKey(appKey)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		fmt.Println("获取服务端接口凭证失败", appKey, err)
+		return
+	}
+	credential = &models.APICredential{}
+	err = json.Unmarshal([]byte(value), credential)
+	return
+}
+
+// GetAppCredentials 获取应用的全部服务端接口凭证
+func GetAppCredentials(appID string) (credentials []*models.APICredential, err error) {
+	appKeys, err := redislib.GetClient().SMembers(context.Background(), getAppCredentialSetKey(appID)).Result()
+	if err != nil {
+		return
+	}
+	credentials = make([]*models.APICredential, 0, len(appKeys))
+	for _, appKey := range appKeys {
+		credential, err := GetAPICredential(appKey)
+		if err != nil {
+			return nil, err
+		}
+		if credential != nil {
+			credentials = append(credentials, credential)
+		}
+	}
+	return
+}
+
+// SaveAPICredential 保存服务端接口凭证
+func SaveAPICredential(credential *models.APICredential) (err error) {
+	value, err := json.Marshal(credential)
+	if err != nil {
+		return
+	}
+	ctx := context.Background()
+	pipe := redislib.GetClient().TxPipeline()
+	pipe.Set(ctx, getAppCredentialKey(credential.AppKey), value, 0)
+	pipe.SAdd(ctx, getAppCredentialSetKey(credential.AppID), credential.AppKey)
+	if _, err = pipe.Exec(ctx); err != nil {
+		fmt.Println("保存服务端接口凭证失败", credential.AppKey, err)
+	}
+	return
+}
+
+// DeleteAPICredential 删除服务端接口凭证
+func DeleteAPICredential(credential *models.APICredential) (err error) {
+	ctx := context.Background()
+	pipe := redislib.GetClient().TxPipeline()
+	pipe.Del(ctx, getAppCredentialKey(credential.AppKey))
+	pipe.SRem(ctx, getAppCredentialSetKey(credential.AppID), credential.AppKey)
+	_, err = pipe.Exec(ctx)
+	return
+}
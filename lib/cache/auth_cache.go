@@ -0,0 +1,159 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+)
+
+const (
+	userCredentialPrefix = "user:credential:" // 用户密码哈希 hash {appID}:{用户ID}
+	authFailPrefix       = "auth:fail:"       // 登录失败次数 {appID}:{用户ID}
+	authLockPrefix       = "auth:lock:"       // 登录锁定 {appID}:{用户ID}
+	authResetPrefix      = "auth:reset:"      // 重置密码凭证 => {appID}:{用户ID}
+)
+
+func getUserCredentialKey(appID string, userID string) (key string) {
+	key = fmt.Sprintf("%s%s:%s", userCredentialPrefix, appID, userID)
+	return
+}
+
+func getAuthFailKey(appID string, userID string) (key string) {
+	key = fmt.Sprintf("%s%s:%s", authFailPrefix, appID, userID)
+	return
+}
+
+func getAuthLockKey(appID string, userID string) (key string) {
+	key = fmt.Sprintf("%s%s:%s", authLockPrefix, appID, userID)
+	return
+}
+
+func getAuthResetKey(token string) (key string) {
+	key = fmt.Sprintf("%s%s", authResetPrefix, token)
+	return
+}
+
+// CreateUser 注册用户，用户已存在返回 created=false
+// 以密码哈希字段的 HSETNX 作为注册锁，防止并发注册同一个用户ID
+func CreateUser(appID string, userID string, passwordHash string, profile map[string]interface{}) (created bool,
+	err error) {
+	ctx := context.Background()
+	redisClient := redislib.GetClient()
+	exists, err := redisClient.Exists(ctx, GetUserProfileKey(appID, userID)).Result()
+	if err != nil || exists > 0 {
+		return
+	}
+	created, err = redisClient.HSetNX(ctx, getUserCredentialKey(appID, userID), "hash", passwordHash).Result()
+	if err != nil || !created {
+		return
+	}
+	pipe := redisClient.TxPipeline()
+	pipe.HSet(ctx, getUserCredentialKey(appID, userID), "updatedAt", time.Now().Unix())
+	pipe.HSet(ctx, GetUserProfileKey(appID, userID), profile)
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		fmt.Println("注册用户失败", appID, userID, err)
+		redisClient.Del(ctx, getUserCredentialKey(appID, userID))
+		created = false
+	}
+	return
+}
+
+// GetPasswordHash 获取密码哈希，未设置密码(自动开通的账号)返回空
+func GetPasswordHash(appID string, userID string) (passwordHash string, err error) {
+	passwordHash, err = redislib.GetClient().HGet(context.Background(), getUserCredentialKey(appID, userID),
+		"hash").Result()
+	if errors.Is(err, redis.Nil) {
+		err = nil
+	}
+	return
+}
+
+// SetPasswordHash 修改密码哈希
+func SetPasswordHash(appID string, userID string, passwordHash string) (err error) {
+	err = redislib.GetClient().HSet(context.Background(), getUserCredentialKey(appID, userID), map[string]interface{}{
+		"hash":      passwordHash,
+		"updatedAt": time.Now().Unix(),
+	}).Err()
+	if err != nil {
+		fmt.Println("修改密码失败", userID, err)
+	}
+	return
+}
+
+// GetLoginLockTTL 登录锁定的剩余时间，未锁定返回 0
+func GetLoginLockTTL(appID string, userID string) (ttl time.Duration) {
+	return getLockTTL(getAuthLockKey(appID, userID))
+}
+
+// AddLoginFailure 记录一次登录失败，window 内失败 maxFailures 次后锁定 lockDuration
+func AddLoginFailure(appID string, userID string, maxFailures int64, window, lockDuration time.Duration) (
+	failures int64, locked bool) {
+	return addFailure(getAuthFailKey(appID, userID), getAuthLockKey(appID, userID), maxFailures, window, lockDuration)
+}
+
+// ClearLoginFailures 登录成功或重置密码后清除失败记录和锁定
+func ClearLoginFailures(appID string, userID string) {
+	redislib.GetClient().Del(context.Background(), getAuthFailKey(appID, userID), getAuthLockKey(appID, userID))
+}
+
+// getLockTTL 锁定 key 的剩余时间，未锁定返回 0
+func getLockTTL(lockKey string) (ttl time.Duration) {
+	ttl, err := redislib.GetClient().TTL(context.Background(), lockKey).Result()
+	if err != nil || ttl < 0 {
+		return 0
+	}
+	return
+}
+
+// addFailure 失败次数加一，达到 maxFailures 后写入锁定 key
+func addFailure(failKey string, lockKey string, maxFailures int64, window, lockDuration time.Duration) (
+	failures int64, locked bool) {
+	ctx := context.Background()
+	redisClient := redislib.GetClient()
+	failures, err := redisClient.Incr(ctx, failKey).Result()
+	if err != nil {
+		fmt.Println("记录登录失败次数失败", failKey, err)
+		return
+	}
+	if failures == 1 {
+		redisClient.Expire(ctx, failKey, window)
+	}
+	if failures >= maxFailures {
+		redisClient.Set(ctx, lockKey, failures, lockDuration)
+		redisClient.Del(ctx, failKey)
+		locked = true
+	}
+	return
+}
+
+// SetPasswordResetToken 保存重置密码凭证
+func SetPasswordResetToken(token string, appID string, userID string, ttl time.Duration) (err error) {
+	err = redislib.GetClient().Set(context.Background(), getAuthResetKey(token), appID+":"+userID, ttl).Err()
+	if err != nil {
+		fmt.Println("保存重置密码凭证失败", appID, userID, err)
+	}
+	return
+}
+
+// ConsumePasswordResetToken 使用重置密码凭证(只能使用一次)，无效返回空
+func ConsumePasswordResetToken(token string) (appID string, userID string, err error) {
+	ctx := context.Background()
+	key := getAuthResetKey(token)
+	pipe := redislib.GetClient().TxPipeline()
+	get := pipe.Get(ctx, key)
+	pipe.Del(ctx, key)
+	_, err = pipe.Exec(ctx)
+	if errors.Is(err, redis.Nil) {
+		return "", "", nil
+	}
+	appID, userID, _ = strings.Cut(get.Val(), ":")
+	return
+}
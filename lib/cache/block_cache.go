@@ -0,0 +1,66 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+)
+
+const (
+	userBlockPrefix = "user:block:" // 用户的黑名单 zset score=拉黑时间
+)
+
+func getUserBlockKey(appID string, userID string) (key string) {
+	key = fmt.Sprintf("%s%s:%s", userBlockPrefix, appID, userID)
+	return
+}
+
+// BlockUser userID 把 targetID 加入黑名单
+func BlockUser(appID string, userID string, targetID string, blockedAt int64) (err error) {
+	err = redislib.GetClient().ZAdd(context.Background(), getUserBlockKey(appID, userID), redis.Z{
+		Score:  float64(blockedAt),
+		Member: targetID,
+	}).Err()
+	if err != nil {
+		fmt.Println("拉黑用户失败", userID, targetID, err)
+	}
+	return
+}
+
+// UnblockUser userID 把 targetID 移出黑名单
+func UnblockUser(appID string, userID string, targetID string) (err error) {
+	err = redislib.GetClient().ZRem(context.Background(), getUserBlockKey(appID, userID), targetID).Err()
+	if err != nil {
+		fmt.Println("取消拉黑失败", userID, targetID, err)
+	}
+	return
+}
+
+// GetBlockList 获取黑名单 用户ID=>拉黑时间，按拉黑时间倒序
+func GetBlockList(appID string, userID string) (list []redis.Z, err error) {
+	list, err = redislib.GetClient().ZRevRangeWithScores(context.Background(), getUserBlockKey(appID, userID), 0,
+		-1).Result()
+	if err != nil {
+		fmt.Println("获取黑名单失败", userID, err)
+	}
+	return
+}
+
+// IsBlocked userID 是否拉黑了 targetID
+func IsBlocked(appID string, userID string, targetID string) (blocked bool, err error) {
+	_, err = redislib.GetClient().ZScore(context.Background(), getUserBlockKey(appID, userID), targetID).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return false, nil
+		}
+		fmt.Println("查询黑名单失败", userID, targetID, err)
+		return
+	}
+	blocked = true
+	return
+}
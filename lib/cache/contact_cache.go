@@ -0,0 +1,166 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	friendMetaPrefix   = "friend:meta:"   // 好友备注、标签、分组 hash 好友ID=>json
+	contactGroupPrefix = "contact:group:" // 好友分组 hash 分组ID=>json
+)
+
+func getFriendMetaKey(appID string, userID string) (key string) {
+	key = fmt.Sprintf("%s%s:%s", friendMetaPrefix, appID, userID)
+	return
+}
+
+func getContactGroupKey(appID string, userID string) (key string) {
+	key = fmt.Sprintf("%s%s:%s", contactGroupPrefix, appID, userID)
+	return
+}
+
+// GetFriendMeta 获取好友的私有信息，未设置返回空值
+func GetFriendMeta(appID string, userID string, friendID string) (meta *models.FriendMeta, err error) {
+	meta = &models.FriendMeta{Tags: []string{}, Groups: []string{}}
+	data, err := redislib.GetClient().HGet(context.Background(), getFriendMetaKey(appID, userID), friendID).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return meta, nil
+		}
+		fmt.Println("获取好友信息失败", userID, friendID, err)
+		return
+	}
+	err = json.Unmarshal(data, meta)
+	if err != nil {
+		fmt.Println("获取好友信息 json Unmarshal", userID, friendID, err)
+	}
+	return
+}
+
+// GetFriendMetas 获取全部好友的私有信息
+func GetFriendMetas(appID string, userID string) (metas map[string]*models.FriendMeta, err error) {
+	metas = make(map[string]*models.FriendMeta)
+	values, err := redislib.GetClient().HGetAll(context.Background(), getFriendMetaKey(appID, userID)).Result()
+	if err != nil {
+		fmt.Println("获取好友信息失败", userID, err)
+		return
+	}
+	for friendID, value := range values {
+		meta := &models.FriendMeta{}
+		if err := json.Unmarshal([]byte(value), meta); err != nil {
+			fmt.Println("获取好友信息 json Unmarshal", userID, friendID, err)
+			continue
+		}
+		metas[friendID] = meta
+	}
+	return
+}
+
+// SetFriendMeta 保存好友的私有信息
+func SetFriendMeta(appID string, userID string, friendID string, meta *models.FriendMeta) (err error) {
+	valueByte, err := json.Marshal(meta)
+	if err != nil {
+		fmt.Println("保存好友信息 json Marshal", userID, friendID, err)
+		return
+	}
+	err = redislib.GetClient().HSet(context.Background(), getFriendMetaKey(appID, userID), friendID,
+		string(valueByte)).Err()
+	if err != nil {
+		fmt.Println("保存好友信息失败", userID, friendID, err)
+	}
+	return
+}
+
+// DelFriendMeta 删除好友时清除私有信息
+func DelFriendMeta(appID string, userID string, friendID string) (err error) {
+	err = redislib.GetClient().HDel(context.Background(), getFriendMetaKey(appID, userID), friendID).Err()
+	return
+}
+
+// GetContactGroups 获取好友分组，按创建时间排序
+func GetContactGroups(appID string, userID string) (groups []*models.ContactGroup, err error) {
+	groups = make([]*models.ContactGroup, 0)
+	values, err := redislib.GetClient().HGetAll(context.Background(), getContactGroupKey(appID, userID)).Result()
+	if err != nil {
+		fmt.Println("获取好友分组失败", userID, err)
+		return
+	}
+	for groupID, value := range values {
+		group := &models.ContactGroup{}
+		if err := json.Unmarshal([]byte(value), group); err != nil {
+			fmt.Println("获取好友分组 json Unmarshal", userID, groupID, err)
+			continue
+		}
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].CreatedAt < groups[j].CreatedAt
+	})
+	return
+}
+
+// GetContactGroup 获取好友分组，不存在返回 redis.Nil
+func GetContactGroup(appID string, userID string, groupID string) (group *models.ContactGroup, err error) {
+	data, err := redislib.GetClient().HGet(context.Background(), getContactGroupKey(appID, userID), groupID).Bytes()
+	if err != nil {
+		return
+	}
+	group = &models.ContactGroup{}
+	err = json.Unmarshal(data, group)
+	return
+}
+
+// SetContactGroup 保存好友分组
+func SetContactGroup(appID string, userID string, group *models.ContactGroup) (err error) {
+	valueByte, err := json.Marshal(group)
+	if err != nil {
+		fmt.Println("保存好友分组 json Marshal", userID, group.GroupID, err)
+		return
+	}
+	err = redislib.GetClient().HSet(context.Background(), getContactGroupKey(appID, userID), group.GroupID,
+		string(valueByte)).Err()
+	if err != nil {
+		fmt.Println("保存好友分组失败", userID, group.GroupID, err)
+	}
+	return
+}
+
+// DelContactGroup 删除好友分组，并从好友信息中移除该分组
+func DelContactGroup(appID string, userID string, groupID string) (err error) {
+	metas, err := GetFriendMetas(appID, userID)
+	if err != nil {
+		return
+	}
+	ctx := context.Background()
+	pipe := redislib.GetClient().TxPipeline()
+	pipe.HDel(ctx, getContactGroupKey(appID, userID), groupID)
+	for friendID, meta := range metas {
+		if !meta.InGroup(groupID) {
+			continue
+		}
+		groups := make([]string, 0, len(meta.Groups))
+		for _, value := range meta.Groups {
+			if value != groupID {
+				groups = append(groups, value)
+			}
+		}
+		meta.Groups = groups
+		valueByte, _ := json.Marshal(meta)
+		pipe.HSet(ctx, getFriendMetaKey(appID, userID), friendID, string(valueByte))
+	}
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		fmt.Println("删除好友分组失败", userID, groupID, err)
+	}
+	return
+}
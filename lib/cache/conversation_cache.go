@@ -0,0 +1,311 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	conversationIndexPrefix   = "conversation:index:"   // 用户会话列表 zset score=最后活跃时间
+	conversationSettingPrefix = "conversation:setting:" // 用户会话设置 hash 会话ID=>json
+	conversationReadPrefix    = "conversation:read:"    // 用户会话已读游标 hash 会话ID=>已读的最大 seq
+	conversationLastKey       = "conversation:last"     // 会话最后一条消息 hash 会话ID=>消息ID
+	conversationMaxCount      = 1000                    // 会话列表最多返回的会话数
+)
+
+func getConversationIndexKey(appID string, userID string) (key string) {
+	key = fmt.Sprintf("%s%s:%s", conversationIndexPrefix, appID, userID)
+	return
+}
+
+func getConversationSettingKey(appID string, userID string) (key string) {
+	key = fmt.Sprintf("%s%s:%s", conversationSettingPrefix, appID, userID)
+	return
+}
+
+func getConversationReadKey(appID string, userID string) (key string) {
+	key = fmt.Sprintf("%s%s:%s", conversationReadPrefix, appID, userID)
+	return
+}
+
+// setReadSeqScript 已读游标只前进不后退，多端同时上报时以最大值为准
+// KEYS: 已读游标 key  ARGV: 会话ID, seq  返回更新后的游标
+var setReadSeqScript = redis.NewScript(`
+local current = tonumber(redis.call('HGET', KEYS[1], ARGV[1]) or '0')
+local seq = tonumber(ARGV[2])
+if seq > current then
+	redis.call('HSET', KEYS[1], ARGV[1], seq)
+	return seq
+end
+return current
+`)
+
+// touchConversation 新消息更新双方的会话列表，发送方的已读游标前进到这条消息
+func touchConversation(ctx context.Context, pipe redis.Pipeliner, record *models.MessageRecord) {
+	for _, userID := range getMessageParticipants(record) {
+		pipe.ZAdd(ctx, getConversationIndexKey(record.AppID, userID), redis.Z{
+			Score:  float64(record.Timestamp),
+			Member: record.ConversationID,
+		})
+	}
+	pipe.HSet(ctx, conversationLastKey, record.ConversationID, record.MessageID)
+	setReadSeqScript.Eval(ctx, pipe, []string{getConversationReadKey(record.AppID, record.FromUserID)},
+		record.ConversationID, record.Seq)
+}
+
+// SetReadSeq 上报已读游标，返回更新后的游标(不会小于之前的值)
+func SetReadSeq(appID string, userID string, conversationID string, seq int64) (readSeq int64, err error) {
+	readSeq, err = setReadSeqScript.Run(context.Background(), redislib.GetClient(),
+		[]string{getConversationReadKey(appID, userID)}, conversationID, seq).Int64()
+	if err != nil {
+		fmt.Println("上报已读游标失败", userID, conversationID, seq, err)
+	}
+	return
+}
+
+// GetReadSeq 获取已读游标
+func GetReadSeq(appID string, userID string, conversationID string) (readSeq int64, err error) {
+	readSeq, err = redislib.GetClient().HGet(context.Background(), getConversationReadKey(appID, userID),
+		conversationID).Int64()
+	if errors.Is(err, redis.Nil) {
+		err = nil
+	}
+	return
+}
+
+// loadUnread 按已读游标计算未读数：同步索引中 seq 大于游标的消息数
+// 已过期删除的消息会从同步索引移除，不会计入未读
+func loadUnread(ctx context.Context, appID string, userID string, conversations []*models.Conversation) (err error) {
+	if len(conversations) == 0 {
+		return
+	}
+	redisClient := redislib.GetClient()
+	conversationIDs := make([]string, 0, len(conversations))
+	for _, conversation := range conversations {
+		conversationIDs = append(conversationIDs, conversation.ConversationID)
+	}
+	readSeqs, err := redisClient.HMGet(ctx, getConversationReadKey(appID, userID), conversationIDs...).Result()
+	if err != nil {
+		fmt.Println("获取已读游标失败", userID, err)
+		return
+	}
+	pipe := redisClient.Pipeline()
+	commands := make([]*redis.IntCmd, 0, len(conversations))
+	for i, conversation := range conversations {
+		if value, ok := readSeqs[i].(string); ok {
+			conversation.ReadSeq, _ = strconv.ParseInt(value, 10, 64)
+		}
+		commands = append(commands, pipe.ZCount(ctx, getChatSyncKey(conversation.ConversationID),
+			"("+strconv.FormatInt(conversation.ReadSeq, 10), "+inf"))
+	}
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		fmt.Println("计算未读数失败", userID, err)
+		return
+	}
+	for i, conversation := range conversations {
+		conversation.Unread = commands[i].Val()
+	}
+	return
+}
+
+// GetUnreadCount 获取单个会话的未读数
+func GetUnreadCount(appID string, userID string, conversationID string) (unread int64, err error) {
+	conversation := &models.Conversation{ConversationID: conversationID}
+	err = loadUnread(context.Background(), appID, userID, []*models.Conversation{conversation})
+	unread = conversation.Unread
+	return
+}
+
+// GetUnreadCounts 获取用户所有会话的未读数
+// total 为全部未读数，badge 为不含免打扰会话的未读数
+func GetUnreadCounts(appID string, userID string) (unreadCounts map[string]int64, total int64, badge int64,
+	err error) {
+	ctx := context.Background()
+	redisClient := redislib.GetClient()
+	unreadCounts = make(map[string]int64)
+
+	conversationIDs, err := redisClient.ZRevRange(ctx, getConversationIndexKey(appID, userID), 0,
+		conversationMaxCount-1).Result()
+	if err != nil {
+		fmt.Println("获取会话列表失败", userID, err)
+		return
+	}
+	settings, err := redisClient.HGetAll(ctx, getConversationSettingKey(appID, userID)).Result()
+	if err != nil {
+		fmt.Println("获取会话设置失败", userID, err)
+		return
+	}
+	conversations := make([]*models.Conversation, 0, len(conversationIDs))
+	for _, conversationID := range conversationIDs {
+		conversations = append(conversations, &models.Conversation{ConversationID: conversationID})
+	}
+	if err = loadUnread(ctx, appID, userID, conversations); err != nil {
+		return
+	}
+	for _, conversation := range conversations {
+		if conversation.Unread == 0 {
+			continue
+		}
+		unreadCounts[conversation.ConversationID] = conversation.Unread
+		total += conversation.Unread
+		setting := &models.ConversationSetting{}
+		if value, ok := settings[conversation.ConversationID]; ok {
+			_ = json.Unmarshal([]byte(value), setting)
+		}
+		if !setting.Muted {
+			badge += conversation.Unread
+		}
+	}
+	return
+}
+
+// GetConversationSetting 获取用户的会话设置，未设置返回默认值
+func GetConversationSetting(appID string, userID string, conversationID string) (setting *models.ConversationSetting,
+	err error) {
+	setting = &models.ConversationSetting{}
+	data, err := redislib.GetClient().HGet(context.Background(), getConversationSettingKey(appID, userID),
+		conversationID).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return setting, nil
+		}
+		fmt.Println("获取会话设置失败", userID, conversationID, err)
+		return
+	}
+	err = json.Unmarshal(data, setting)
+	if err != nil {
+		fmt.Println("获取会话设置 json Unmarshal", userID, conversationID, err)
+	}
+	return
+}
+
+// SetConversationSetting 保存用户的会话设置
+func SetConversationSetting(appID string, userID string, conversationID string,
+	setting *models.ConversationSetting) (err error) {
+	valueByte, err := json.Marshal(setting)
+	if err != nil {
+		fmt.Println("保存会话设置 json Marshal", userID, conversationID, err)
+		return
+	}
+	err = redislib.GetClient().HSet(context.Background(), getConversationSettingKey(appID, userID), conversationID,
+		string(valueByte)).Err()
+	if err != nil {
+		fmt.Println("保存会话设置失败", userID, conversationID, err)
+	}
+	return
+}
+
+// GetConversation 获取用户的单个会话列表项
+func GetConversation(appID string, userID string, conversationID string) (conversation *models.Conversation,
+	err error) {
+	ctx := context.Background()
+	redisClient := redislib.GetClient()
+	activeAt, err := redisClient.ZScore(ctx, getConversationIndexKey(appID, userID), conversationID).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		fmt.Println("获取会话失败", userID, conversationID, err)
+		return
+	}
+	setting, err := GetConversationSetting(appID, userID, conversationID)
+	if err != nil {
+		return
+	}
+	conversation = newConversation(userID, conversationID, int64(activeAt), setting)
+	if err = loadUnread(ctx, appID, userID, []*models.Conversation{conversation}); err != nil {
+		return
+	}
+	if messageID, err := redisClient.HGet(ctx, conversationLastKey, conversationID).Result(); err == nil {
+		conversation.LastMessage, _ = GetMessage(appID, messageID)
+	}
+	return conversation, nil
+}
+
+// GetConversations 获取用户的会话列表，置顶在前，其余按最后活跃时间倒序
+// archived 为 true 时只返回已归档的会话，否则只返回未归档的会话
+func GetConversations(appID string, userID string, archived bool, offset, limit int) (
+	conversations []*models.Conversation, total int, err error) {
+	ctx := context.Background()
+	redisClient := redislib.GetClient()
+	conversations = make([]*models.Conversation, 0)
+
+	members, err := redisClient.ZRevRangeWithScores(ctx, getConversationIndexKey(appID, userID), 0,
+		conversationMaxCount-1).Result()
+	if err != nil {
+		fmt.Println("获取会话列表失败", userID, err)
+		return
+	}
+	settings, err := redisClient.HGetAll(ctx, getConversationSettingKey(appID, userID)).Result()
+	if err != nil {
+		fmt.Println("获取会话设置失败", userID, err)
+		return
+	}
+
+	list := make([]*models.Conversation, 0, len(members))
+	for _, member := range members {
+		conversationID, _ := member.Member.(string)
+		setting := &models.ConversationSetting{}
+		if value, ok := settings[conversationID]; ok {
+			_ = json.Unmarshal([]byte(value), setting)
+		}
+		if setting.Archived != archived {
+			continue
+		}
+		list = append(list, newConversation(userID, conversationID, int64(member.Score), setting))
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Pinned && !list[j].Pinned
+	})
+
+	total = len(list)
+	if offset >= total {
+		return
+	}
+	if offset+limit < total {
+		list = list[offset : offset+limit]
+	} else {
+		list = list[offset:]
+	}
+
+	// 只加载当前页的未读数和最后一条消息
+	conversationIDs := make([]string, 0, len(list))
+	for _, conversation := range list {
+		conversationIDs = append(conversationIDs, conversation.ConversationID)
+	}
+	if err = loadUnread(ctx, appID, userID, list); err != nil {
+		return
+	}
+	messageIDs, err := redisClient.HMGet(ctx, conversationLastKey, conversationIDs...).Result()
+	if err != nil {
+		fmt.Println("获取会话最后一条消息失败", userID, err)
+		return
+	}
+	for i, conversation := range list {
+		if messageID, ok := messageIDs[i].(string); ok {
+			conversation.LastMessage, _ = GetMessage(appID, messageID)
+		}
+	}
+	conversations = list
+	return
+}
+
+func newConversation(userID, conversationID string, activeAt int64, setting *models.ConversationSetting) (conversation *models.Conversation) {
+	conversationType, peerID := models.GetConversationPeer(conversationID, userID)
+	conversation = &models.Conversation{
+		ConversationID:      conversationID,
+		Type:                conversationType,
+		PeerID:              peerID,
+		ActiveAt:            activeAt,
+		ConversationSetting: *setting,
+	}
+	return
+}
@@ -0,0 +1,232 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	userFriendsPrefix          = "user:friends:"           // 好友 set
+	friendRequestPrefix        = "friend:request:"         // 好友申请详情
+	friendRequestInPrefix      = "friend:request:in:"      // 收到的申请 zset score=最近一次申请时间
+	friendRequestOutPrefix     = "friend:request:out:"     // 发出的申请 zset score=最近一次申请时间
+	friendRequestPendingPrefix = "friend:request:pending:" // 两人之间待处理的申请ID
+	friendRequestRetention     = 7 * 24 * 60 * 60          // 申请过期或处理后在列表中保留的时间(秒)
+)
+
+func getUserFriendsKey(appID string, userID string) (key string) {
+	key = fmt.Sprintf("%s%s:%s", userFriendsPrefix, appID, userID)
+	return
+}
+
+func getFriendRequestKey(requestID string) (key string) {
+	key = fmt.Sprintf("%s%s", friendRequestPrefix, requestID)
+	return
+}
+
+func getFriendRequestListKey(appID string, userID string, incoming bool) (key string) {
+	if incoming {
+		key = fmt.Sprintf("%s%s:%s", friendRequestInPrefix, appID, userID)
+	} else {
+		key = fmt.Sprintf("%s%s:%s", friendRequestOutPrefix, appID, userID)
+	}
+	return
+}
+
+func getFriendRequestPendingKey(appID, fromUserID, toUserID string) (key string) {
+	key = fmt.Sprintf("%s%s:%s:%s", friendRequestPendingPrefix, appID, fromUserID, toUserID)
+	return
+}
+
+// IsFriend 是否为好友
+func IsFriend(appID string, userID string, friendID string) (isFriend bool, err error) {
+	isFriend, err = redislib.GetClient().SIsMember(context.Background(), getUserFriendsKey(appID, userID),
+		friendID).Result()
+	return
+}
+
+// GetFriendIDs 获取好友ID列表
+func GetFriendIDs(appID string, userID string) (friendIDs []string, err error) {
+	friendIDs, err = redislib.GetClient().SMembers(context.Background(), getUserFriendsKey(appID, userID)).Result()
+	if err != nil {
+		fmt.Println("获取好友列表失败", userID, err)
+	}
+	return
+}
+
+// AddFriend 建立双向好友关系
+func AddFriend(appID string, userID string, friendID string) (err error) {
+	ctx := context.Background()
+	pipe := redislib.GetClient().TxPipeline()
+	pipe.SAdd(ctx, getUserFriendsKey(appID, userID), friendID)
+	pipe.SAdd(ctx, getUserFriendsKey(appID, friendID), userID)
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		fmt.Println("建立好友关系失败", userID, friendID, err)
+	}
+	return
+}
+
+// RemoveFriend 解除双向好友关系
+func RemoveFriend(appID string, userID string, friendID string) (err error) {
+	ctx := context.Background()
+	pipe := redislib.GetClient().TxPipeline()
+	pipe.SRem(ctx, getUserFriendsKey(appID, userID), friendID)
+	pipe.SRem(ctx, getUserFriendsKey(appID, friendID), userID)
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		fmt.Println("解除好友关系失败", userID, friendID, err)
+	}
+	return
+}
+
+// GetFriendRequest 获取好友申请，不存在返回 redis.Nil
+func GetFriendRequest(requestID string) (request *models.FriendRequest, err error) {
+	data, err := redislib.GetClient().Get(context.Background(), getFriendRequestKey(requestID)).Bytes()
+	if err != nil {
+		return
+	}
+	request = &models.FriendRequest{}
+	err = json.Unmarshal(data, request)
+	if err != nil {
+		fmt.Println("获取好友申请 json Unmarshal", requestID, err)
+	}
+	return
+}
+
+// GetPendingFriendRequest 获取 fromUserID 发给 toUserID 的待处理申请，没有返回 nil
+func GetPendingFriendRequest(appID, fromUserID, toUserID string) (request *models.FriendRequest, err error) {
+	requestID, err := redislib.GetClient().Get(context.Background(),
+		getFriendRequestPendingKey(appID, fromUserID, toUserID)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return
+	}
+	request, err = GetFriendRequest(requestID)
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return
+}
+
+// SaveFriendRequest 保存新的好友申请(或刷新待处理的申请)
+func SaveFriendRequest(request *models.FriendRequest) (err error) {
+	valueByte, err := json.Marshal(request)
+	if err != nil {
+		fmt.Println("保存好友申请 json Marshal", request.RequestID, err)
+		return
+	}
+	ttl := time.Duration(request.ExpireAt-request.UpdatedAt) * time.Second
+	ctx := context.Background()
+	pipe := redislib.GetClient().TxPipeline()
+	pipe.Set(ctx, getFriendRequestKey(request.RequestID), string(valueByte),
+		ttl+friendRequestRetention*time.Second)
+	pipe.Set(ctx, getFriendRequestPendingKey(request.AppID, request.FromUserID, request.ToUserID), request.RequestID,
+		ttl)
+	// 申请详情失效后，从双方列表中清理
+	expired := strconv.FormatInt(request.UpdatedAt-(request.ExpireAt-request.UpdatedAt)-friendRequestRetention, 10)
+	for _, listKey := range []string{
+		getFriendRequestListKey(request.AppID, request.ToUserID, true),
+		getFriendRequestListKey(request.AppID, request.FromUserID, false),
+	} {
+		pipe.ZAdd(ctx, listKey, redis.Z{Score: float64(request.UpdatedAt), Member: request.RequestID})
+		pipe.ZRemRangeByScore(ctx, listKey, "-inf", "("+expired)
+	}
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		fmt.Println("保存好友申请失败", request.RequestID, err)
+	}
+	return
+}
+
+// UpdateFriendRequestStatus 处理好友申请，只有待处理的申请可以修改
+// 修改成功返回 true，申请已被处理或已过期返回 false
+func UpdateFriendRequestStatus(request *models.FriendRequest, status string, now int64) (updated bool,
+	err error) {
+	ctx := context.Background()
+	key := getFriendRequestKey(request.RequestID)
+	err = redislib.GetClient().Watch(ctx, func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		current := &models.FriendRequest{}
+		if err = json.Unmarshal(data, current); err != nil {
+			return err
+		}
+		if !current.IsPending(now) {
+			return nil
+		}
+		current.Status = status
+		current.UpdatedAt = now
+		valueByte, err := json.Marshal(current)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, string(valueByte), redis.KeepTTL)
+			pipe.Del(ctx, getFriendRequestPendingKey(current.AppID, current.FromUserID, current.ToUserID))
+			if status == models.FriendRequestAccepted {
+				pipe.SAdd(ctx, getUserFriendsKey(current.AppID, current.FromUserID), current.ToUserID)
+				pipe.SAdd(ctx, getUserFriendsKey(current.AppID, current.ToUserID), current.FromUserID)
+			}
+			return nil
+		})
+		if err == nil {
+			updated = true
+			*request = *current
+		}
+		return err
+	}, key)
+	if err != nil {
+		fmt.Println("处理好友申请失败", request.RequestID, status, err)
+	}
+	return
+}
+
+// GetFriendRequests 获取收到或发出的好友申请，按最近一次申请时间倒序
+func GetFriendRequests(appID string, userID string, incoming bool, offset, limit int64) (
+	requests []*models.FriendRequest, total int64, err error) {
+	ctx := context.Background()
+	redisClient := redislib.GetClient()
+	key := getFriendRequestListKey(appID, userID, incoming)
+	requests = make([]*models.FriendRequest, 0)
+
+	now := time.Now().Unix()
+	total, err = redisClient.ZCard(ctx, key).Result()
+	if err != nil {
+		fmt.Println("获取好友申请列表失败", key, err)
+		return
+	}
+	requestIDs, err := redisClient.ZRevRange(ctx, key, offset, offset+limit-1).Result()
+	if err != nil {
+		fmt.Println("获取好友申请列表失败", key, err)
+		return
+	}
+	for _, requestID := range requestIDs {
+		request, err := GetFriendRequest(requestID)
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				redisClient.ZRem(ctx, key, requestID)
+			}
+			continue
+		}
+		requests = append(requests, request.Normalize(now))
+	}
+	return
+}
@@ -0,0 +1,96 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	oidcStatePrefix    = "auth:oidc:state:" // 授权码登录状态 json
+	identityPrefix     = "auth:identity:"   // 外部身份 {appID}:{provider}:{subject} => 用户ID
+	userIdentityPrefix = "user:identity:"   // 用户绑定的外部身份 {appID}:{用户ID} hash provider => subject
+)
+
+func getOIDCStateKey(state string) (key string) {
+	key = fmt.Sprintf("%s%s", oidcStatePrefix, state)
+	return
+}
+
+func getIdentityKey(appID string, provider string, subject string) (key string) {
+	key = fmt.Sprintf("%s%s:%s:%s", identityPrefix, appID, provider, subject)
+	return
+}
+
+func getUserIdentityKey(appID string, userID string) (key string) {
+	key = fmt.Sprintf("%s%s:%s", userIdentityPrefix, appID, userID)
+	return
+}
+
+// SaveOIDCState 保存授权码登录状态
+func SaveOIDCState(state string, oidcState *models.OIDCState, ttl time.Duration) (err error) {
+	value, err := json.Marshal(oidcState)
+	if err != nil {
+		return
+	}
+	err = redislib.GetClient().Set(context.Background(), getOIDCStateKey(state), value, ttl).Err()
+	if err != nil {
+		fmt.Println("保存OIDC状态失败", oidcState.Provider, err)
+	}
+	return
+}
+
+// ConsumeOIDCState 取出授权码登录状态(只能使用一次)，不存在返回 nil
+func ConsumeOIDCState(state string) (oidcState *models.OIDCState, err error) {
+	ctx := context.Background()
+	key := getOIDCStateKey(state)
+	pipe := redislib.GetClient().TxPipeline()
+	get := pipe.Get(ctx, key)
+	pipe.Del(ctx, key)
+	if _, err = pipe.Exec(ctx); err != nil {
+		if errors.Is(err, redis.Nil) {
+			err = nil
+		}
+		return
+	}
+	oidcState = &models.OIDCState{}
+	err = json.Unmarshal([]byte(get.Val()), oidcState)
+	return
+}
+
+// GetIdentityUser 外部身份绑定的用户ID，未绑定返回空
+func GetIdentityUser(appID string, provider string, subject string) (userID string, err error) {
+	userID, err = redislib.GetClient().Get(context.Background(), getIdentityKey(appID, provider, subject)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		fmt.Println("获取外部身份失败", provider, subject, err)
+	}
+	return
+}
+
+// BindIdentity 绑定外部身份，已被绑定时返回已绑定的用户ID
+func BindIdentity(appID string, provider string, subject string, userID string) (boundUserID string, err error) {
+	ctx := context.Background()
+	redisClient := redislib.GetClient()
+	key := getIdentityKey(appID, provider, subject)
+	ok, err := redisClient.SetNX(ctx, key, userID, 0).Result()
+	if err != nil {
+		fmt.Println("绑定外部身份失败", provider, subject, err)
+		return
+	}
+	if !ok {
+		return redisClient.Get(ctx, key).Result()
+	}
+	redisClient.HSet(ctx, getUserIdentityKey(appID, userID), provider, subject)
+	return userID, nil
+}
@@ -0,0 +1,81 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	mediaInfoPrefix          = "media:info:"          // 媒体文件元数据
+	mediaConversationsPrefix = "media:conversations:" // 引用了媒体文件的会话 set
+)
+
+func getMediaInfoKey(mediaID string) (key string) {
+	key = fmt.Sprintf("%s%s", mediaInfoPrefix, mediaID)
+	return
+}
+
+func getMediaConversationsKey(mediaID string) (key string) {
+	key = fmt.Sprintf("%s%s", mediaConversationsPrefix, mediaID)
+	return
+}
+
+// referenceMedia 图片、语音消息记录引用媒体文件的会话，会话参与者可以下载该文件
+func referenceMedia(ctx context.Context, pipe redis.Pipeliner, record *models.MessageRecord) {
+	if record.MessageType != models.MessageTypeImage && record.MessageType != models.MessageTypeAudio {
+		return
+	}
+	pipe.SAdd(ctx, getMediaConversationsKey(record.Content), record.ConversationID)
+}
+
+// GetMediaConversations 引用了媒体文件的会话ID
+func GetMediaConversations(mediaID string) (conversationIDs []string, err error) {
+	conversationIDs, err = redislib.GetClient().SMembers(context.Background(),
+		getMediaConversationsKey(mediaID)).Result()
+	if err != nil {
+		fmt.Println("获取媒体文件引用的会话失败", mediaID, err)
+	}
+	return
+}
+
+// GetMediaInfo 获取媒体元数据
+func GetMediaInfo(mediaID string) (mediaInfo *models.MediaInfo, err error) {
+	redisClient := redislib.GetClient()
+	key := getMediaInfoKey(mediaID)
+	data, err := redisClient.Get(context.Background(), key).Bytes()
+	if err != nil {
+		fmt.Println("GetMediaInfo", mediaID, err)
+		return
+	}
+	mediaInfo = &models.MediaInfo{}
+	err = json.Unmarshal(data, mediaInfo)
+	if err != nil {
+		fmt.Println("获取媒体元数据 json Unmarshal", mediaID, err)
+		return
+	}
+	return
+}
+
+// SetMediaInfo 设置媒体元数据
+func SetMediaInfo(mediaInfo *models.MediaInfo) (err error) {
+	redisClient := redislib.GetClient()
+	key := getMediaInfoKey(mediaInfo.MediaID)
+	valueByte, err := json.Marshal(mediaInfo)
+	if err != nil {
+		fmt.Println("设置媒体元数据 json Marshal", key, err)
+		return
+	}
+	_, err = redisClient.Set(context.Background(), key, string(valueByte), 0).Result()
+	if err != nil {
+		fmt.Println("设置媒体元数据", key, err)
+		return
+	}
+	return
+}
@@ -0,0 +1,283 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	messageDetailPrefix      = "message:detail:"      // 消息详情 hash {appID}:{消息ID}
+	chatHistoryPrefix        = "chat:history:"        // 会话消息 zset score=发送时间
+	conversationPolicyPrefix = "conversation:policy:" // 会话过期策略
+	messageExpireKey         = "message:expire"       // 待过期消息 zset 成员={appID}:{消息ID} score=过期时间
+	messageBurnPrefix        = "message:burn:"        // 阅后即焚待读消息 set
+)
+
+// GetMessageDetailKey 消息详情 key
+func GetMessageDetailKey(appID string, messageID string) (key string) {
+	key = fmt.Sprintf("%s%s:%s", messageDetailPrefix, appID, messageID)
+	return
+}
+
+// GetChatHistoryKey 会话消息 key
+func GetChatHistoryKey(conversationID string) (key string) {
+	key = fmt.Sprintf("%s%s", chatHistoryPrefix, conversationID)
+	return
+}
+
+func getConversationPolicyKey(conversationID string) (key string) {
+	key = fmt.Sprintf("%s%s", conversationPolicyPrefix, conversationID)
+	return
+}
+
+func getMessageBurnKey(conversationID string, userID string) (key string) {
+	key = fmt.Sprintf("%s%s:%s", messageBurnPrefix, conversationID, userID)
+	return
+}
+
+// getMessageExpireMember 待过期队列的成员，appID 不包含冒号
+func getMessageExpireMember(appID string, messageID string) (member string) {
+	member = fmt.Sprintf("%s:%s", appID, messageID)
+	return
+}
+
+// ParseMessageExpireMember 解析待过期队列的成员
+func ParseMessageExpireMember(member string) (appID string, messageID string) {
+	appID, messageID, _ = strings.Cut(member, ":")
+	return
+}
+
+// GetExpirePolicy 获取会话过期策略，未设置返回 nil
+func GetExpirePolicy(conversationID string) (policy *models.ExpirePolicy, err error) {
+	redisClient := redislib.GetClient()
+	key := getConversationPolicyKey(conversationID)
+	data, err := redisClient.Get(context.Background(), key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		fmt.Println("GetExpirePolicy", key, err)
+		return
+	}
+	policy = &models.ExpirePolicy{}
+	err = json.Unmarshal(data, policy)
+	if err != nil {
+		fmt.Println("获取会话过期策略 json Unmarshal", key, err)
+		return
+	}
+	return
+}
+
+// SetExpirePolicy 设置会话过期策略
+func SetExpirePolicy(conversationID string, policy *models.ExpirePolicy) (err error) {
+	redisClient := redislib.GetClient()
+	key := getConversationPolicyKey(conversationID)
+	if policy.Policy == models.ExpirePolicyOff {
+		_, err = redisClient.Del(context.Background(), key).Result()
+		return
+	}
+	valueByte, err := json.Marshal(policy)
+	if err != nil {
+		fmt.Println("设置会话过期策略 json Marshal", key, err)
+		return
+	}
+	_, err = redisClient.Set(context.Background(), key, string(valueByte), 0).Result()
+	if err != nil {
+		fmt.Println("设置会话过期策略", key, err)
+	}
+	return
+}
+
+// SaveMessage 保存消息并按会话策略登记过期
+func SaveMessage(record *models.MessageRecord) (err error) {
+	policy, err := GetExpirePolicy(record.ConversationID)
+	if err != nil {
+		return
+	}
+	record.ExpireAt = policy.GetExpireAt(record.Timestamp)
+
+	// 分配 seq 与写入详情在同一个脚本中完成，保证 seq 顺序与落库顺序一致
+	record.Seq, err = saveMessageDetail(record)
+	if err != nil {
+		fmt.Println("保存消息详情失败", record.MessageID, err)
+		return
+	}
+
+	ctx := context.Background()
+	pipe := redislib.GetClient().TxPipeline()
+	pipe.ZAdd(ctx, GetChatHistoryKey(record.ConversationID), redis.Z{
+		Score:  float64(record.Timestamp),
+		Member: record.MessageID,
+	})
+	if record.ExpireAt > 0 {
+		pipe.ZAdd(ctx, messageExpireKey, redis.Z{
+			Score:  float64(record.ExpireAt),
+			Member: getMessageExpireMember(record.AppID, record.MessageID),
+		})
+	}
+	if policy.IsBurnAfterRead() {
+		pipe.SAdd(ctx, getMessageBurnKey(record.ConversationID, record.ToUserID), record.MessageID)
+	}
+	indexMessage(ctx, pipe, record)
+	touchConversation(ctx, pipe, record)
+	referenceMedia(ctx, pipe, record)
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		fmt.Println("保存消息失败", record.MessageID, err)
+	}
+	return
+}
+
+// GetMessage 获取消息详情
+func GetMessage(appID string, messageID string) (record *models.MessageRecord, err error) {
+	fields, err := redislib.GetClient().HGetAll(context.Background(), GetMessageDetailKey(appID, messageID)).Result()
+	if err != nil {
+		return
+	}
+	if len(fields) == 0 {
+		err = redis.Nil
+		return
+	}
+	record = models.NewMessageRecordFromMap(fields)
+	return
+}
+
+// BurnReadMessages 阅后即焚会话中 userID 已读的消息立即过期
+func BurnReadMessages(conversationID string, userID string, now int64) (count int, err error) {
+	ctx := context.Background()
+	redisClient := redislib.GetClient()
+	key := getMessageBurnKey(conversationID, userID)
+	messageIDs, err := redisClient.SMembers(ctx, key).Result()
+	if err != nil || len(messageIDs) == 0 {
+		return
+	}
+	appID := models.GetConversationAppID(conversationID)
+	members := make([]redis.Z, 0, len(messageIDs))
+	for _, messageID := range messageIDs {
+		members = append(members, redis.Z{Score: float64(now), Member: getMessageExpireMember(appID, messageID)})
+	}
+	pipe := redisClient.TxPipeline()
+	pipe.ZAdd(ctx, messageExpireKey, members...)
+	pipe.SRem(ctx, key, toInterfaces(messageIDs)...)
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		fmt.Println("阅后即焚 登记过期失败", key, err)
+		return
+	}
+	count = len(messageIDs)
+	return
+}
+
+// ClearBurnMessages 取消阅后即焚时清除待焚毁记录
+func ClearBurnMessages(conversationID string, userIDs ...string) {
+	keys := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		keys = append(keys, getMessageBurnKey(conversationID, userID))
+	}
+	redislib.GetClient().Del(context.Background(), keys...)
+}
+
+// GetExpiredMessages 获取已到期的消息，members 用 ParseMessageExpireMember 解析
+func GetExpiredMessages(now int64, limit int64) (members []string, err error) {
+	members, err = redislib.GetClient().ZRangeByScore(context.Background(), messageExpireKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now, 10),
+		Count: limit,
+	}).Result()
+	return
+}
+
+// ClaimExpiredMessage 从待过期队列中领取消息，多个节点同时清理时只有一个能领取成功
+func ClaimExpiredMessage(member string) (claimed bool) {
+	number, err := redislib.GetClient().ZRem(context.Background(), messageExpireKey, member).Result()
+	if err != nil {
+		fmt.Println("ClaimExpiredMessage", member, err)
+		return
+	}
+	claimed = number == 1
+	return
+}
+
+// ReleaseExpiredMessage 领取后删除失败时放回待过期队列，下次定时任务重新清理
+func ReleaseExpiredMessage(member string, now int64) (err error) {
+	err = redislib.GetClient().ZAdd(context.Background(), messageExpireKey, redis.Z{
+		Score:  float64(now),
+		Member: member,
+	}).Err()
+	if err != nil {
+		fmt.Println("ReleaseExpiredMessage", member, err)
+	}
+	return
+}
+
+// DeleteMessage 删除消息详情和会话记录，返回被删除的消息(已不存在时 record 为 nil)
+func DeleteMessage(appID string, messageID string) (record *models.MessageRecord, err error) {
+	ctx := context.Background()
+	record, err = GetMessage(appID, messageID)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return
+	}
+	err = nil
+	pipe := redislib.GetClient().TxPipeline()
+	pipe.Del(ctx, GetMessageDetailKey(appID, messageID))
+	pipe.ZRem(ctx, messageExpireKey, getMessageExpireMember(appID, messageID))
+	if record != nil {
+		pipe.ZRem(ctx, GetChatHistoryKey(record.ConversationID), messageID)
+		pipe.ZRem(ctx, getChatSyncKey(record.ConversationID), messageID)
+		pipe.SRem(ctx, getMessageBurnKey(record.ConversationID, record.ToUserID), messageID)
+		unindexMessage(ctx, pipe, record)
+	}
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		fmt.Println("删除消息失败", messageID, err)
+	}
+	return
+}
+
+// PurgeConversation 删除会话的全部消息，返回被删除的消息
+// 会话 seq 不重置，之后的新消息继续递增，客户端增量同步不受影响
+func PurgeConversation(conversationID string) (records []*models.MessageRecord, err error) {
+	ctx := context.Background()
+	redisClient := redislib.GetClient()
+	messageIDs, err := redisClient.ZRange(ctx, GetChatHistoryKey(conversationID), 0, -1).Result()
+	if err != nil {
+		return
+	}
+	appID := models.GetConversationAppID(conversationID)
+	records = make([]*models.MessageRecord, 0, len(messageIDs))
+	for _, messageID := range messageIDs {
+		record, err := DeleteMessage(appID, messageID)
+		if err != nil {
+			return records, err
+		}
+		if record != nil {
+			records = append(records, record)
+		}
+	}
+	pipe := redisClient.TxPipeline()
+	pipe.Del(ctx, GetChatHistoryKey(conversationID), getChatSyncKey(conversationID))
+	pipe.HDel(ctx, conversationLastKey, conversationID)
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		fmt.Println("清空会话失败", conversationID, err)
+	}
+	return
+}
+
+func toInterfaces(values []string) (result []interface{}) {
+	result = make([]interface{}, 0, len(values))
+	for _, value := range values {
+		result = append(result, value)
+	}
+	return
+}
@@ -0,0 +1,128 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	userProfilePrefix   = "user:profile:"         // 用户资料 hash {appID}:{用户ID}
+	userNicknamePrefix  = "user:search:nickname:" // 应用内的昵称前缀索引 zset 成员=小写昵称\x00用户ID
+	nicknameIndexSep    = "\x00"                  // 索引成员中昵称和用户ID的分隔符
+	nicknameIndexMaxEnd = "\xff"                  // 前缀查询的上界
+)
+
+// GetUserProfileKey 用户资料 key
+func GetUserProfileKey(appID string, userID string) (key string) {
+	key = fmt.Sprintf("%s%s:%s", userProfilePrefix, appID, userID)
+	return
+}
+
+func getNicknameIndexKey(appID string) (key string) {
+	key = fmt.Sprintf("%s%s", userNicknamePrefix, appID)
+	return
+}
+
+func getNicknameIndexMember(userID string, nickname string) string {
+	return strings.ToLower(nickname) + nicknameIndexSep + userID
+}
+
+// GetUserProfile 获取用户资料，用户不存在返回 redis.Nil
+func GetUserProfile(appID string, userID string) (profile *models.UserProfile, err error) {
+	fields, err := redislib.GetClient().HGetAll(context.Background(), GetUserProfileKey(appID, userID)).Result()
+	if err != nil {
+		fmt.Println("获取用户资料失败", userID, err)
+		return
+	}
+	if len(fields) == 0 {
+		err = redis.Nil
+		return
+	}
+	profile = models.NewUserProfileFromMap(fields)
+	return
+}
+
+// UpdateUserProfile 修改用户资料，同时维护昵称搜索索引
+// old 为修改前的资料，fields 为需要修改的字段
+func UpdateUserProfile(appID string, old *models.UserProfile, fields map[string]interface{}) (
+	profile *models.UserProfile, err error) {
+	ctx := context.Background()
+	pipe := redislib.GetClient().TxPipeline()
+	pipe.HSet(ctx, GetUserProfileKey(appID, old.UserID), fields)
+	pipe.ZRem(ctx, getNicknameIndexKey(appID), getNicknameIndexMember(old.UserID, old.Nickname))
+	getAll := pipe.HGetAll(ctx, GetUserProfileKey(appID, old.UserID))
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		fmt.Println("修改用户资料失败", old.UserID, err)
+		return
+	}
+	profile = models.NewUserProfileFromMap(getAll.Val())
+	err = IndexUserNickname(appID, profile)
+	return
+}
+
+// IndexUserNickname 更新昵称索引，关闭昵称搜索的用户不进入索引
+func IndexUserNickname(appID string, profile *models.UserProfile) (err error) {
+	if !profile.SearchByNickname || profile.Nickname == "" {
+		return
+	}
+	err = redislib.GetClient().ZAdd(context.Background(), getNicknameIndexKey(appID), redis.Z{
+		Member: getNicknameIndexMember(profile.UserID, profile.Nickname),
+	}).Err()
+	if err != nil {
+		fmt.Println("更新昵称索引失败", profile.UserID, err)
+	}
+	return
+}
+
+// SearchUserIDsByNickname 按昵称前缀(不区分大小写)搜索应用内的用户ID
+func SearchUserIDsByNickname(appID string, prefix string, limit int64) (userIDs []string, err error) {
+	prefix = strings.ToLower(prefix)
+	members, err := redislib.GetClient().ZRangeByLex(context.Background(), getNicknameIndexKey(appID), &redis.ZRangeBy{
+		Min:   "[" + prefix,
+		Max:   "[" + prefix + nicknameIndexMaxEnd,
+		Count: limit,
+	}).Result()
+	if err != nil {
+		fmt.Println("按昵称搜索用户失败", prefix, err)
+		return
+	}
+	userIDs = make([]string, 0, len(members))
+	for _, member := range members {
+		if index := strings.LastIndex(member, nicknameIndexSep); index >= 0 {
+			userIDs = append(userIDs, member[index+len(nicknameIndexSep):])
+		}
+	}
+	return
+}
+
+// ScanUserIDs 分页遍历应用内的用户ID，prefix 为用户ID前缀
+// cursor 为 0 表示从头开始，nextCursor 为 0 表示遍历结束，每页返回的数量不固定
+func ScanUserIDs(appID string, cursor uint64, prefix string, count int64) (userIDs []string, nextCursor uint64,
+	err error) {
+	keyPrefix := GetUserProfileKey(appID, "")
+	match := escapeMatchPattern(keyPrefix+prefix) + "*"
+	keys, nextCursor, err := redislib.GetClient().Scan(context.Background(), cursor, match, count).Result()
+	if err != nil {
+		fmt.Println("遍历用户失败", prefix, err)
+		return
+	}
+	userIDs = make([]string, 0, len(keys))
+	for _, key := range keys {
+		userIDs = append(userIDs, strings.TrimPrefix(key, keyPrefix))
+	}
+	return
+}
+
+// escapeMatchPattern 转义 SCAN MATCH 中的通配符
+func escapeMatchPattern(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+	return replacer.Replace(value)
+}
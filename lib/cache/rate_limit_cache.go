@@ -0,0 +1,89 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+)
+
+const (
+	rateLimitPrefix = "ratelimit:" // 限流令牌桶 hash tokens/ts
+)
+
+// takeTokensScript 同时检查多个令牌桶，全部有令牌时才各扣除一个
+// KEYS 为令牌桶，ARGV[1] 为当前毫秒时间，之后依次为每个桶的每秒补充数和容量
+// 返回需要等待的毫秒数，0 表示通过
+var takeTokensScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local wait = 0
+local tokens = {}
+for i, key in ipairs(KEYS) do
+	local rate = tonumber(ARGV[i * 2])
+	local burst = tonumber(ARGV[i * 2 + 1])
+	local value = redis.call('HMGET', key, 'tokens', 'ts')
+	local current = tonumber(value[1])
+	local ts = tonumber(value[2])
+	if current == nil or ts == nil then
+		current = burst
+		ts = now
+	end
+	current = math.min(burst, current + math.max(0, now - ts) * rate / 1000)
+	if current < 1 then
+		local need = math.ceil((1 - current) * 1000 / rate)
+		if need > wait then
+			wait = need
+		end
+	end
+	tokens[i] = current
+end
+if wait > 0 then
+	return wait
+end
+for i, key in ipairs(KEYS) do
+	local rate = tonumber(ARGV[i * 2])
+	local burst = tonumber(ARGV[i * 2 + 1])
+	redis.call('HMSET', key, 'tokens', tostring(tokens[i] - 1), 'ts', now)
+	redis.call('PEXPIRE', key, math.ceil(burst * 1000 / rate) + 1000)
+end
+return 0
+`)
+
+// RateLimitBucket 令牌桶
+type RateLimitBucket struct {
+	Key   string  // 限流对象，例如 ws.sendMessage:user:{appID}:{userID}
+	Rate  float64 // 每秒补充的令牌数
+	Burst int64   // 最多累积的令牌数
+}
+
+func getRateLimitKey(bucketKey string) (key string) {
+	key = fmt.Sprintf("%s%s", rateLimitPrefix, bucketKey)
+	return
+}
+
+// TakeRateLimitTokens 从全部令牌桶各取一个令牌，任一桶不足时都不扣除，返回需要等待的时间
+// 使用本机时间计算补充的令牌，各节点需要同步时钟
+func TakeRateLimitTokens(buckets []RateLimitBucket) (retryAfter time.Duration, err error) {
+	if len(buckets) == 0 {
+		return
+	}
+	keys := make([]string, len(buckets))
+	args := make([]interface{}, 0, len(buckets)*2+1)
+	args = append(args, time.Now().UnixMilli())
+	for i, bucket := range buckets {
+		keys[i] = getRateLimitKey(bucket.Key)
+		args = append(args, strconv.FormatFloat(bucket.Rate, 'f', -1, 64), bucket.Burst)
+	}
+	wait, err := takeTokensScript.Run(context.Background(), redislib.GetClient(), keys, args...).Int64()
+	if err != nil {
+		fmt.Println("限流 获取令牌失败", keys, err)
+		return
+	}
+	retryAfter = time.Duration(wait) * time.Millisecond
+	return
+}
@@ -0,0 +1,123 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	refreshTokenPrefix  = "auth:refresh:"        // 刷新令牌 hash，key 使用令牌的 sha256，不保存明文
+	refreshFamilyPrefix = "auth:refresh:family:" // 令牌族 => 创建时间，删除即吊销整个令牌族
+)
+
+func getRefreshTokenKey(token string) (key string) {
+	sum := sha256.Sum256([]byte(token))
+	key = fmt.Sprintf("%s%s", refreshTokenPrefix, hex.EncodeToString(sum[:]))
+	return
+}
+
+func getRefreshFamilyKey(familyID string) (key string) {
+	key = fmt.Sprintf("%s%s", refreshFamilyPrefix, familyID)
+	return
+}
+
+// rotateRefreshTokenScript 使用刷新令牌，每个令牌只能使用一次
+// 已使用的令牌再次出现说明令牌可能泄露，吊销整个令牌族
+// 用户吊销全部令牌之前创建的令牌族同样无效
+// KEYS: 刷新令牌 key  ARGV: 令牌族 key 前缀, 用户吊销时间 key 前缀(后接 {appID}:{用户ID})
+// 返回 {状态, 用户ID, 应用ID, 令牌族ID}
+var rotateRefreshTokenScript = redis.NewScript(`
+local info = redis.call('HMGET', KEYS[1], 'userID', 'appID', 'familyID', 'used')
+if not info[1] then
+	return {'invalid'}
+end
+local familyKey = ARGV[1] .. info[3]
+local createdAt = redis.call('GET', familyKey)
+if not createdAt then
+	return {'invalid'}
+end
+local revokedAt = redis.call('GET', ARGV[2] .. info[2] .. ':' .. info[1])
+if revokedAt and tonumber(createdAt) < tonumber(revokedAt) then
+	redis.call('DEL', familyKey)
+	return {'invalid'}
+end
+if info[4] == '1' then
+	redis.call('DEL', familyKey)
+	return {'reused', info[1], info[2], info[3]}
+end
+redis.call('HSET', KEYS[1], 'used', '1')
+return {'rotated', info[1], info[2], info[3]}
+`)
+
+// CreateRefreshFamily 登录时创建新的令牌族
+func CreateRefreshFamily(familyID string, ttl time.Duration) (err error) {
+	err = redislib.GetClient().Set(context.Background(), getRefreshFamilyKey(familyID), time.Now().UnixMilli(),
+		ttl).Err()
+	if err != nil {
+		fmt.Println("创建令牌族失败", familyID, err)
+	}
+	return
+}
+
+// SaveRefreshToken 保存刷新令牌，令牌族的有效期随每次轮换顺延
+// 令牌族已被吊销时不会重新创建，保存的令牌也无法使用
+func SaveRefreshToken(token string, refreshToken *models.RefreshToken, ttl time.Duration) (err error) {
+	ctx := context.Background()
+	key := getRefreshTokenKey(token)
+	pipe := redislib.GetClient().TxPipeline()
+	pipe.Expire(ctx, getRefreshFamilyKey(refreshToken.FamilyID), ttl)
+	pipe.HSet(ctx, key, "userID", refreshToken.UserID, "appID", refreshToken.AppID,
+		"familyID", refreshToken.FamilyID, "used", "0")
+	pipe.Expire(ctx, key, ttl)
+	if _, err = pipe.Exec(ctx); err != nil {
+		fmt.Println("保存刷新令牌失败", refreshToken.UserID, err)
+	}
+	return
+}
+
+// RotateRefreshToken 使用刷新令牌，返回轮换状态和令牌信息
+func RotateRefreshToken(token string) (status string, refreshToken *models.RefreshToken, err error) {
+	result, err := rotateRefreshTokenScript.Run(context.Background(), redislib.GetClient(),
+		[]string{getRefreshTokenKey(token)}, refreshFamilyPrefix, revokedUserPrefix).StringSlice()
+	if err != nil {
+		fmt.Println("使用刷新令牌失败", err)
+		return
+	}
+	status = result[0]
+	if len(result) == 4 {
+		refreshToken = &models.RefreshToken{UserID: result[1], AppID: result[2], FamilyID: result[3]}
+	}
+	return
+}
+
+// RevokeRefreshToken 吊销刷新令牌所属的整个令牌族，只能吊销属于该用户的令牌
+func RevokeRefreshToken(token string, appID string, userID string) (revoked bool, err error) {
+	ctx := context.Background()
+	redisClient := redislib.GetClient()
+	info, err := redisClient.HMGet(ctx, getRefreshTokenKey(token), "userID", "appID", "familyID").Result()
+	if err != nil {
+		fmt.Println("获取刷新令牌失败", err)
+		return
+	}
+	tokenUserID, _ := info[0].(string)
+	tokenAppID, _ := info[1].(string)
+	familyID, _ := info[2].(string)
+	if familyID == "" || tokenUserID != userID || tokenAppID != appID {
+		return
+	}
+	if err = redisClient.Del(ctx, getRefreshFamilyKey(familyID)).Err(); err != nil {
+		fmt.Println("吊销令牌族失败", familyID, err)
+		return
+	}
+	revoked = true
+	return
+}
@@ -0,0 +1,119 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	reviewItemPrefix  = "review:item:"    // 审核内容 json
+	reviewPendingKey  = "review:pending"  // 待审核 zset，score 为创建时间
+	reviewResolvedKey = "review:resolved" // 已审核 zset，score 为审核时间
+	reviewResolvedTTL = 30 * 24 * time.Hour
+)
+
+func getReviewItemKey(reviewID string) (key string) {
+	key = fmt.Sprintf("%s%s", reviewItemPrefix, reviewID)
+	return
+}
+
+// AddReviewItem 加入审核队列
+func AddReviewItem(item *models.ReviewItem) (err error) {
+	value, err := json.Marshal(item)
+	if err != nil {
+		return
+	}
+	ctx := context.Background()
+	_, err = redislib.GetClient().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, getReviewItemKey(item.ReviewID), value, 0)
+		pipe.ZAdd(ctx, reviewPendingKey, redis.Z{Score: float64(item.CreatedAt), Member: item.ReviewID})
+		return nil
+	})
+	if err != nil {
+		fmt.Println("加入审核队列失败", item.Scope, item.UserID, err)
+	}
+	return
+}
+
+// GetReviewItem 获取审核内容，不存在返回 nil
+func GetReviewItem(reviewID string) (item *models.ReviewItem, err error) {
+	value, err := redislib.GetClient().Get(context.Background(), getReviewItemKey(reviewID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return
+	}
+	item = &models.ReviewItem{}
+	err = json.Unmarshal([]byte(value), item)
+	return
+}
+
+// GetReviewItems 分页获取审核内容，待审核的按创建时间正序，已审核的按审核时间倒序
+func GetReviewItems(pending bool, offset int64, limit int64) (items []*models.ReviewItem, total int64, err error) {
+	ctx := context.Background()
+	key := reviewPendingKey
+	if !pending {
+		key = reviewResolvedKey
+		// 清理已过期的审核记录
+		expired := time.Now().Add(-reviewResolvedTTL).Unix()
+		redislib.GetClient().ZRemRangeByScore(ctx, key, "-inf", fmt.Sprint(expired))
+	}
+	total, err = redislib.GetClient().ZCard(ctx, key).Result()
+	if err != nil {
+		return
+	}
+	var reviewIDs []string
+	if pending {
+		reviewIDs, err = redislib.GetClient().ZRange(ctx, key, offset, offset+limit-1).Result()
+	} else {
+		reviewIDs, err = redislib.GetClient().ZRevRange(ctx, key, offset, offset+limit-1).Result()
+	}
+	if err != nil {
+		return
+	}
+	items = make([]*models.ReviewItem, 0, len(reviewIDs))
+	for _, reviewID := range reviewIDs {
+		item, err := GetReviewItem(reviewID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if item == nil {
+			redislib.GetClient().ZRem(ctx, key, reviewID)
+			continue
+		}
+		items = append(items, item)
+	}
+	return
+}
+
+// ResolveReviewItem 完成审核，返回是否由本次请求完成(并发审核时只有一个成功)
+func ResolveReviewItem(item *models.ReviewItem) (ok bool, err error) {
+	ctx := context.Background()
+	removed, err := redislib.GetClient().ZRem(ctx, reviewPendingKey, item.ReviewID).Result()
+	if err != nil || removed == 0 {
+		return false, err
+	}
+	value, err := json.Marshal(item)
+	if err != nil {
+		return
+	}
+	_, err = redislib.GetClient().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, getReviewItemKey(item.ReviewID), value, reviewResolvedTTL)
+		pipe.ZAdd(ctx, reviewResolvedKey, redis.Z{Score: float64(item.ReviewedAt), Member: item.ReviewID})
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	return true, nil
+}
@@ -0,0 +1,113 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/lib/jwtlib"
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	revokedTokenPrefix = "auth:revoked:token:" // 已吊销的令牌 jti，过期时间与令牌一致
+	revokedUserPrefix  = "auth:revoked:user:"  // {appID}:{用户ID} 在该时间(毫秒)之前签发的令牌全部失效
+)
+
+func getRevokedTokenKey(tokenID string) (key string) {
+	key = fmt.Sprintf("%s%s", revokedTokenPrefix, tokenID)
+	return
+}
+
+func getRevokedUserKey(appID string, userID string) (key string) {
+	key = fmt.Sprintf("%s%s:%s", revokedUserPrefix, appID, userID)
+	return
+}
+
+// RevokeToken 吊销单个令牌，ttl 为令牌剩余的有效期
+func RevokeToken(tokenID string, ttl time.Duration) (err error) {
+	if tokenID == "" || ttl <= 0 {
+		return
+	}
+	err = redislib.GetClient().Set(context.Background(), getRevokedTokenKey(tokenID), 1, ttl).Err()
+	if err != nil {
+		fmt.Println("吊销令牌失败", tokenID, err)
+	}
+	return
+}
+
+// RevokeUserTokens 吊销用户当前时间之前签发的全部令牌(含刷新令牌)
+// ttl 不小于令牌的最长有效期
+func RevokeUserTokens(appID string, userID string, ttl time.Duration) (err error) {
+	err = redislib.GetClient().Set(context.Background(), getRevokedUserKey(appID, userID),
+		time.Now().UnixMilli(), ttl).Err()
+	if err != nil {
+		fmt.Println("吊销用户令牌失败", appID, userID, err)
+	}
+	return
+}
+
+// IsClaimsRevoked 访问令牌是否已被吊销，HTTP 接口和长连接共用
+// 单个令牌按 jti 吊销，修改密码等场景按用户吊销之前签发的全部令牌
+func IsClaimsRevoked(claims *jwtlib.Claims) (revoked bool, err error) {
+	ref := models.TokenRef{
+		AppID:   claims.AppID,
+		UserID:  claims.UserID,
+		TokenID: claims.ID,
+	}
+	if claims.IssuedAt != nil {
+		ref.IssuedAt = claims.IssuedAt.UnixMilli()
+	}
+	return IsTokenRevoked(ref)
+}
+
+// IsTokenRevoked 令牌是否已被吊销
+func IsTokenRevoked(ref models.TokenRef) (revoked bool, err error) {
+	result, err := GetRevokedTokens([]models.TokenRef{ref})
+	if err != nil {
+		return
+	}
+	revoked = result[0]
+	return
+}
+
+// GetRevokedTokens 批量检查令牌是否已被吊销，返回值与参数一一对应
+func GetRevokedTokens(refs []models.TokenRef) (revoked []bool, err error) {
+	revoked = make([]bool, len(refs))
+	if len(refs) == 0 {
+		return
+	}
+	ctx := context.Background()
+	pipe := redislib.GetClient().Pipeline()
+	tokenCmds := make([]*redis.IntCmd, len(refs))
+	userCmds := make([]*redis.StringCmd, len(refs))
+	for i, ref := range refs {
+		if ref.TokenID != "" {
+			tokenCmds[i] = pipe.Exists(ctx, getRevokedTokenKey(ref.TokenID))
+		}
+		userCmds[i] = pipe.Get(ctx, getRevokedUserKey(ref.AppID, ref.UserID))
+	}
+	if _, err = pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		fmt.Println("检查令牌吊销状态失败", err)
+		return
+	}
+	err = nil
+	for i, ref := range refs {
+		if tokenCmds[i] != nil && tokenCmds[i].Val() > 0 {
+			revoked[i] = true
+			continue
+		}
+		// 按毫秒比较，吊销的同一毫秒内签发的令牌视为有效，避免修改密码后立即签发的新令牌被误判
+		revokedAt, _ := strconv.ParseInt(userCmds[i].Val(), 10, 64)
+		if revokedAt > 0 && ref.IssuedAt < revokedAt {
+			revoked[i] = true
+		}
+	}
+	return
+}
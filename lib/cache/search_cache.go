@@ -0,0 +1,150 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/helper"
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/lib/search"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	searchTermPrefix = "search:term:" // 倒排索引 zset 按应用和用户隔离 score=发送时间
+	searchDocPrefix  = "search:doc:"  // 消息的索引词 set {appID}:{消息ID}，删除消息时使用
+	searchTmpPrefix  = "search:tmp:"  // 多词查询的临时交集
+	searchScanBatch  = 200            // 每批扫描的候选消息数
+	searchScanLimit  = 5000           // 单次查询最多扫描的候选消息数
+)
+
+func getSearchTermKey(appID string, userID string, term string) (key string) {
+	key = fmt.Sprintf("%s%s:%s:%s", searchTermPrefix, appID, userID, term)
+	return
+}
+
+func getSearchDocKey(appID string, messageID string) (key string) {
+	key = fmt.Sprintf("%s%s:%s", searchDocPrefix, appID, messageID)
+	return
+}
+
+// getMessageParticipants 可以搜索到该消息的用户
+func getMessageParticipants(record *models.MessageRecord) (userIDs []string) {
+	userIDs = []string{record.FromUserID}
+	if record.ToUserID != "" && record.ToUserID != record.FromUserID {
+		userIDs = append(userIDs, record.ToUserID)
+	}
+	return
+}
+
+// indexMessage 把文本消息写入倒排索引
+func indexMessage(ctx context.Context, pipe redis.Pipeliner, record *models.MessageRecord) {
+	if record.MessageType != models.MessageTypeText {
+		return
+	}
+	terms := search.Tokenize(record.Content)
+	if len(terms) == 0 {
+		return
+	}
+	for _, userID := range getMessageParticipants(record) {
+		for _, term := range terms {
+			pipe.ZAdd(ctx, getSearchTermKey(record.AppID, userID, term), redis.Z{
+				Score:  float64(record.Timestamp),
+				Member: record.MessageID,
+			})
+		}
+	}
+	pipe.SAdd(ctx, getSearchDocKey(record.AppID, record.MessageID), toInterfaces(terms)...)
+}
+
+// unindexMessage 从倒排索引中删除消息
+func unindexMessage(ctx context.Context, pipe redis.Pipeliner, record *models.MessageRecord) {
+	terms, err := redislib.GetClient().SMembers(ctx, getSearchDocKey(record.AppID, record.MessageID)).Result()
+	if err != nil {
+		fmt.Println("删除消息索引 获取索引词失败", record.MessageID, err)
+		return
+	}
+	for _, userID := range getMessageParticipants(record) {
+		for _, term := range terms {
+			pipe.ZRem(ctx, getSearchTermKey(record.AppID, userID, term), record.MessageID)
+		}
+	}
+	pipe.Del(ctx, getSearchDocKey(record.AppID, record.MessageID))
+}
+
+// SearchMessages 在用户可见的消息中检索同时包含全部 terms 的消息，按时间倒序
+// startTime/endTime 为 0 表示不限制，filter 返回 false 的消息会被跳过
+func SearchMessages(appID string, userID string, terms []string, startTime, endTime int64,
+	filter func(*models.MessageRecord) bool, offset, limit int) (records []*models.MessageRecord, hasMore bool, err error) {
+	ctx := context.Background()
+	redisClient := redislib.GetClient()
+	records = make([]*models.MessageRecord, 0)
+	if len(terms) == 0 {
+		return
+	}
+
+	key := getSearchTermKey(appID, userID, terms[0])
+	if len(terms) > 1 {
+		keys := make([]string, 0, len(terms))
+		for _, term := range terms {
+			keys = append(keys, getSearchTermKey(appID, userID, term))
+		}
+		key = searchTmpPrefix + helper.GetRandomID(8)
+		_, err = redisClient.ZInterStore(ctx, key, &redis.ZStore{Keys: keys, Aggregate: "MAX"}).Result()
+		if err != nil {
+			fmt.Println("检索消息 求交集失败", userID, err)
+			return
+		}
+		defer redisClient.Del(ctx, key)
+	}
+
+	rangeBy := &redis.ZRangeBy{Min: "-inf", Max: "+inf"}
+	if startTime > 0 {
+		rangeBy.Min = strconv.FormatInt(startTime, 10)
+	}
+	if endTime > 0 {
+		rangeBy.Max = strconv.FormatInt(endTime, 10)
+	}
+
+	now := time.Now().Unix()
+	skipped := 0
+	for scanned := int64(0); scanned < searchScanLimit; scanned += searchScanBatch {
+		rangeBy.Offset = scanned
+		rangeBy.Count = searchScanBatch
+		messageIDs, err := redisClient.ZRevRangeByScore(ctx, key, rangeBy).Result()
+		if err != nil {
+			fmt.Println("检索消息 获取候选失败", userID, err)
+			return records, false, err
+		}
+		for _, messageID := range messageIDs {
+			record, err := GetMessage(appID, messageID)
+			if err != nil {
+				continue
+			}
+			if record.ExpireAt > 0 && record.ExpireAt <= now {
+				continue
+			}
+			if filter != nil && !filter(record) {
+				continue
+			}
+			if skipped < offset {
+				skipped++
+				continue
+			}
+			if len(records) == limit {
+				hasMore = true
+				return records, hasMore, nil
+			}
+			records = append(records, record)
+		}
+		if len(messageIDs) < searchScanBatch {
+			break
+		}
+	}
+	return
+}
@@ -43,8 +43,14 @@ func submitAgain(from string, second int, value string) (isSubmitAgain bool) {
 
 }
 
-// SeqDuplicates Seq 重复提交
-func SeqDuplicates(seq string) (result bool) {
-	result = submitAgain("seq", 12*60*60, seq)
+// SeqDuplicates Seq 重复提交，不同应用的 seq 互不影响
+func SeqDuplicates(appID string, seq string) (result bool) {
+	result = submitAgain("seq", 12*60*60, appID+":"+seq)
+	return
+}
+
+// NonceDuplicates 签名请求的 nonce 重复使用，second 不小于允许的时间误差
+func NonceDuplicates(appKey string, nonce string, second int) (result bool) {
+	result = submitAgain("nonce", second, appKey+":"+nonce)
 	return
 }
@@ -0,0 +1,128 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	chatSeqPrefix  = "chat:seq:"  // 会话当前最大 seq
+	chatSyncPrefix = "chat:sync:" // 会话消息 zset score=seq
+)
+
+// saveMessageScript 原子地分配 seq 并写入消息详情和同步索引
+// KEYS: seq key, 详情 key, 同步 key  ARGV: 消息ID, 详情字段...
+var saveMessageScript = redis.NewScript(`
+local seq = redis.call('INCR', KEYS[1])
+redis.call('HSET', KEYS[2], unpack(ARGV, 2))
+redis.call('HSET', KEYS[2], 'seq', seq)
+redis.call('ZADD', KEYS[3], seq, ARGV[1])
+return seq
+`)
+
+func getChatSeqKey(conversationID string) (key string) {
+	key = fmt.Sprintf("%s%s", chatSeqPrefix, conversationID)
+	return
+}
+
+func getChatSyncKey(conversationID string) (key string) {
+	key = fmt.Sprintf("%s%s", chatSyncPrefix, conversationID)
+	return
+}
+
+// saveMessageDetail 写入消息详情，返回分配的 seq
+func saveMessageDetail(record *models.MessageRecord) (seq int64, err error) {
+	keys := []string{
+		getChatSeqKey(record.ConversationID),
+		GetMessageDetailKey(record.AppID, record.MessageID),
+		getChatSyncKey(record.ConversationID),
+	}
+	args := []interface{}{record.MessageID}
+	for field, value := range record.ToMap() {
+		args = append(args, field, value)
+	}
+	seq, err = saveMessageScript.Run(context.Background(), redislib.GetClient(), keys, args...).Int64()
+	return
+}
+
+// GetConversationSeq 获取会话当前最大 seq
+func GetConversationSeq(conversationID string) (seq int64, err error) {
+	seq, err = redislib.GetClient().Get(context.Background(), getChatSeqKey(conversationID)).Int64()
+	if err == redis.Nil {
+		err = nil
+	}
+	return
+}
+
+// GetMessagesAfterSeq 获取 seq 大于 afterSeq 的消息，按 seq 升序
+// cursor 为本次扫描到的最大 seq(包含已过期被跳过的消息)，客户端下次从 cursor 继续同步
+func GetMessagesAfterSeq(conversationID string, afterSeq int64, limit int64) (records []*models.MessageRecord,
+	cursor int64, hasMore bool, err error) {
+	members, err := redislib.GetClient().ZRangeByScoreWithScores(context.Background(), getChatSyncKey(conversationID),
+		&redis.ZRangeBy{
+			Min:   "(" + strconv.FormatInt(afterSeq, 10),
+			Max:   "+inf",
+			Count: limit + 1,
+		}).Result()
+	if err != nil {
+		fmt.Println("同步消息 获取消息失败", conversationID, err)
+		return
+	}
+	records, cursor, hasMore = loadSyncMessages(models.GetConversationAppID(conversationID), members, limit, afterSeq)
+	return
+}
+
+// GetMessagesBeforeSeq 获取 seq 小于 beforeSeq 的消息(beforeSeq<=0 表示从最新开始)，按 seq 升序返回
+// cursor 为本次扫描到的最小 seq，客户端继续向前翻页时作为 beforeSeq
+func GetMessagesBeforeSeq(conversationID string, beforeSeq int64, limit int64) (records []*models.MessageRecord,
+	cursor int64, hasMore bool, err error) {
+	rangeBy := &redis.ZRangeBy{Min: "-inf", Max: "+inf", Count: limit + 1}
+	if beforeSeq > 0 {
+		rangeBy.Max = "(" + strconv.FormatInt(beforeSeq, 10)
+	}
+	members, err := redislib.GetClient().ZRevRangeByScoreWithScores(context.Background(),
+		getChatSyncKey(conversationID), rangeBy).Result()
+	if err != nil {
+		fmt.Println("翻页消息 获取消息失败", conversationID, err)
+		return
+	}
+	records, cursor, hasMore = loadSyncMessages(models.GetConversationAppID(conversationID), members, limit, beforeSeq)
+	// 倒序取出，翻转为升序
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return
+}
+
+// loadSyncMessages 加载消息详情，跳过已过期未清理的消息
+func loadSyncMessages(appID string, members []redis.Z, limit int64, cursor int64) (records []*models.MessageRecord,
+	nextCursor int64, hasMore bool) {
+	records = make([]*models.MessageRecord, 0, len(members))
+	nextCursor = cursor
+	if int64(len(members)) > limit {
+		hasMore = true
+		members = members[:limit]
+	}
+	now := time.Now().Unix()
+	for _, member := range members {
+		nextCursor = int64(member.Score)
+		messageID, _ := member.Member.(string)
+		record, err := GetMessage(appID, messageID)
+		if err != nil {
+			continue
+		}
+		if record.ExpireAt > 0 && record.ExpireAt <= now {
+			continue
+		}
+		records = append(records, record)
+	}
+	return
+}
@@ -0,0 +1,197 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	webhookDeliveryPrefix = "webhook:delivery:" // 回调投递 json
+	webhookQueueKey       = "webhook:queue"     // 待投递的回调 zset，score 为下次投递时间
+	webhookDeadPrefix     = "webhook:dead:"     // 投递失败的回调 zset，按应用区分，score 为失败时间
+)
+
+func getWebhookDeliveryKey(deliveryID string) (key string) {
+	key = fmt.Sprintf("%s%s", webhookDeliveryPrefix, deliveryID)
+	return
+}
+
+func getWebhookDeadKey(appID string) (key string) {
+	key = fmt.Sprintf("%s%s", webhookDeadPrefix, appID)
+	return
+}
+
+// claimWebhookScript 领取到期的回调，把下次投递时间推迟到租约结束
+// 多个节点同时领取时同一个回调只会被一个节点拿到，节点宕机后租约到期会被重新领取
+// KEYS[1] 队列 ARGV[1] 当前时间 ARGV[2] 租约结束时间 ARGV[3] 最多领取的数量
+var claimWebhookScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[3]))
+for _, id in ipairs(ids) do
+	redis.call('ZADD', KEYS[1], 'XX', ARGV[2], id)
+end
+return ids
+`)
+
+// EnqueueWebhookDeliveries 回调加入投递队列
+func EnqueueWebhookDeliveries(deliveries []*models.WebhookDelivery) (err error) {
+	if len(deliveries) == 0 {
+		return
+	}
+	ctx := context.Background()
+	_, err = redislib.GetClient().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, delivery := range deliveries {
+			value, err := json.Marshal(delivery)
+			if err != nil {
+				return err
+			}
+			pipe.Set(ctx, getWebhookDeliveryKey(delivery.DeliveryID), value, 0)
+			pipe.ZAdd(ctx, webhookQueueKey, redis.Z{Score: float64(delivery.NextAt), Member: delivery.DeliveryID})
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Println("回调加入投递队列失败", err)
+	}
+	return
+}
+
+// ClaimWebhookDeliveries 领取到期的回调ID
+func ClaimWebhookDeliveries(now int64, lease time.Duration, limit int) (deliveryIDs []string, err error) {
+	deliveryIDs, err = claimWebhookScript.Run(context.Background(), redislib.GetClient(), []string{webhookQueueKey},
+		now, now+int64(lease.Seconds()), limit).StringSlice()
+	if err != nil {
+		fmt.Println("领取回调失败", err)
+	}
+	return
+}
+
+// GetWebhookDelivery 获取回调，不存在返回 nil
+func GetWebhookDelivery(deliveryID string) (delivery *models.WebhookDelivery, err error) {
+	value, err := redislib.GetClient().Get(context.Background(), getWebhookDeliveryKey(deliveryID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		fmt.Println("获取回调失败", deliveryID, err)
+		return
+	}
+	delivery = &models.WebhookDelivery{}
+	err = json.Unmarshal([]byte(value), delivery)
+	return
+}
+
+// CompleteWebhookDelivery 回调投递成功或无需再投递，从队列中删除
+func CompleteWebhookDelivery(deliveryID string) (err error) {
+	ctx := context.Background()
+	_, err = redislib.GetClient().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, webhookQueueKey, deliveryID)
+		pipe.Del(ctx, getWebhookDeliveryKey(deliveryID))
+		return nil
+	})
+	return
+}
+
+// RetryWebhookDelivery 保存投递结果，在 NextAt 重新投递
+func RetryWebhookDelivery(delivery *models.WebhookDelivery) (err error) {
+	value, err := json.Marshal(delivery)
+	if err != nil {
+		return
+	}
+	ctx := context.Background()
+	_, err = redislib.GetClient().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, getWebhookDeliveryKey(delivery.DeliveryID), value, 0)
+		pipe.ZAdd(ctx, webhookQueueKey, redis.Z{Score: float64(delivery.NextAt), Member: delivery.DeliveryID})
+		return nil
+	})
+	return
+}
+
+// DeadWebhookDelivery 超过重试次数，移入死信队列，保存 ttl 时间
+func DeadWebhookDelivery(delivery *models.WebhookDelivery, ttl time.Duration) (err error) {
+	value, err := json.Marshal(delivery)
+	if err != nil {
+		return
+	}
+	ctx := context.Background()
+	deadKey := getWebhookDeadKey(delivery.AppID)
+	_, err = redislib.GetClient().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, webhookQueueKey, delivery.DeliveryID)
+		pipe.Set(ctx, getWebhookDeliveryKey(delivery.DeliveryID), value, ttl)
+		pipe.ZAdd(ctx, deadKey, redis.Z{Score: float64(delivery.DeadAt), Member: delivery.DeliveryID})
+		// 清理已过期的死信
+		pipe.ZRemRangeByScore(ctx, deadKey, "-inf", fmt.Sprint(delivery.DeadAt-int64(ttl.Seconds())))
+		return nil
+	})
+	return
+}
+
+// GetDeadWebhookDeliveries 分页获取应用的死信，按失败时间倒序
+func GetDeadWebhookDeliveries(appID string, offset int64, limit int64) (deliveries []*models.WebhookDelivery,
+	total int64, err error) {
+	ctx := context.Background()
+	deadKey := getWebhookDeadKey(appID)
+	total, err = redislib.GetClient().ZCard(ctx, deadKey).Result()
+	if err != nil {
+		return
+	}
+	deliveryIDs, err := redislib.GetClient().ZRevRange(ctx, deadKey, offset, offset+limit-1).Result()
+	if err != nil {
+		return
+	}
+	deliveries = make([]*models.WebhookDelivery, 0, len(deliveryIDs))
+	for _, deliveryID := range deliveryIDs {
+		delivery, err := GetWebhookDelivery(deliveryID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if delivery == nil {
+			// 已过期
+			redislib.GetClient().ZRem(ctx, deadKey, deliveryID)
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return
+}
+
+// ReplayWebhookDelivery 死信重新加入投递队列，返回是否存在
+func ReplayWebhookDelivery(appID string, deliveryID string, now int64) (ok bool, err error) {
+	delivery, err := GetWebhookDelivery(deliveryID)
+	if err != nil {
+		return
+	}
+	ctx := context.Background()
+	deadKey := getWebhookDeadKey(appID)
+	if delivery == nil || delivery.AppID != appID || delivery.DeadAt == 0 {
+		redislib.GetClient().ZRem(ctx, deadKey, deliveryID)
+		return false, nil
+	}
+	removed, err := redislib.GetClient().ZRem(ctx, deadKey, deliveryID).Result()
+	if err != nil || removed == 0 {
+		// 已被其他请求重放
+		return false, err
+	}
+
+	delivery.Attempts = 0
+	delivery.NextAt = now
+	delivery.DeadAt = 0
+	if err = RetryWebhookDelivery(delivery); err != nil {
+		return
+	}
+	return true, nil
+}
+
+// GetDeadWebhookDeliveryIDs 获取应用最近的死信ID
+func GetDeadWebhookDeliveryIDs(appID string, limit int64) (deliveryIDs []string, err error) {
+	deliveryIDs, err = redislib.GetClient().ZRevRange(context.Background(), getWebhookDeadKey(appID), 0, limit-1).Result()
+	return
+}
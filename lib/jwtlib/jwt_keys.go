@@ -0,0 +1,258 @@
+package jwtlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/viper"
+)
+
+// 支持的签名算法
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+)
+
+// SigningKey 签名密钥，通过 kid 区分，轮换时旧密钥保留用于验证
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	signKey   interface{} // HS256: []byte  RS256: *rsa.PrivateKey  ES256: *ecdsa.PrivateKey，只用于验证的密钥为空
+	verifyKey interface{} // HS256: []byte  RS256: *rsa.PublicKey  ES256: *ecdsa.PublicKey
+	expireAt  time.Time   // 过期后不再用于验证，为零值时不过期
+}
+
+// keyConfig 配置文件中的密钥
+type keyConfig struct {
+	Kid            string `mapstructure:"kid"`
+	Alg            string `mapstructure:"alg"`
+	Secret         string `mapstructure:"secret"`
+	SecretFile     string `mapstructure:"secretFile"`
+	PrivateKeyFile string `mapstructure:"privateKeyFile"`
+	PublicKeyFile  string `mapstructure:"publicKeyFile"`
+}
+
+// legacyKeyConfig 老版本签发的 token(没有 kid)使用的密钥，迁移期间只用于验证
+type legacyKeyConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	Secret     string `mapstructure:"secret"`
+	SecretFile string `mapstructure:"secretFile"`
+	ExpireAt   string `mapstructure:"expireAt"` // RFC3339 格式，超过后拒绝老版本的 token
+}
+
+// JWK 公钥，用于 JWKS 接口
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+var (
+	keysRWMutex sync.RWMutex
+	// 未初始化时使用内置的 HS256 密钥，兼容老版本签发的 token(没有 kid)
+	signingKeys = map[string]*SigningKey{"": {Algorithm: AlgHS256, signKey: jwtSecret, verifyKey: jwtSecret}}
+	activeKey   = signingKeys[""]
+)
+
+// loadLegacyKey 加载 jwt.legacyKey，需要显式开启并配置密钥和过期时间，不会使用内置密钥
+func loadLegacyKey() (key *SigningKey, err error) {
+	var config legacyKeyConfig
+	if err = viper.UnmarshalKey("jwt.legacyKey", &config); err != nil || !config.Enabled {
+		return
+	}
+	secret := []byte(config.Secret)
+	if config.SecretFile != "" {
+		if secret, err = os.ReadFile(config.SecretFile); err != nil {
+			return
+		}
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("缺少 secret 或 secretFile")
+	}
+	if config.ExpireAt == "" {
+		return nil, errors.New("缺少 expireAt")
+	}
+	expireAt, err := time.Parse(time.RFC3339, config.ExpireAt)
+	if err != nil {
+		return nil, fmt.Errorf("expireAt 格式错误: %s", err)
+	}
+	key = &SigningKey{Algorithm: AlgHS256, verifyKey: secret, expireAt: expireAt}
+	return
+}
+
+// Init 从配置加载签名密钥
+// jwt.keys 为全部可用于验证的密钥，jwt.activeKey 为签名使用的 kid
+// 没有配置密钥时使用内置密钥，仅适合开发环境；配置密钥后不再接受内置密钥签发的 token，
+// 需要兼容老版本 token 时开启 jwt.legacyKey
+func Init() {
+	var configs []keyConfig
+	if err := viper.UnmarshalKey("jwt.keys", &configs); err != nil {
+		panic(fmt.Errorf("读取JWT密钥配置失败: %s \n", err))
+	}
+	if len(configs) == 0 {
+		fmt.Println("JWT 未配置签名密钥，使用内置密钥，请勿在生产环境使用")
+		return
+	}
+
+	keys := make(map[string]*SigningKey, len(configs))
+	for _, config := range configs {
+		key, err := loadKey(config)
+		if err != nil {
+			panic(fmt.Errorf("加载JWT密钥失败 kid:%s %s \n", config.Kid, err))
+		}
+		if _, ok := keys[key.ID]; ok {
+			panic(fmt.Errorf("JWT密钥 kid 重复: %s \n", key.ID))
+		}
+		keys[key.ID] = key
+	}
+
+	legacy, err := loadLegacyKey()
+	if err != nil {
+		panic(fmt.Errorf("加载JWT老版本密钥失败: %s \n", err))
+	}
+	if legacy != nil {
+		if _, ok := keys[""]; ok {
+			panic(errors.New("JWT密钥 kid 为空时不能同时开启 legacyKey"))
+		}
+		keys[""] = legacy
+		fmt.Println("JWT 老版本 token 验证开启，过期时间:", legacy.expireAt.Format(time.RFC3339))
+	}
+
+	active, ok := keys[viper.GetString("jwt.activeKey")]
+	if !ok || active.signKey == nil {
+		panic(fmt.Errorf("JWT签名密钥不存在或没有私钥: %s \n", viper.GetString("jwt.activeKey")))
+	}
+
+	keysRWMutex.Lock()
+	defer keysRWMutex.Unlock()
+	signingKeys = keys
+	activeKey = active
+	fmt.Println("JWT 密钥初始化成功 当前kid:", active.ID, active.Algorithm, "密钥数:", len(keys))
+}
+
+// loadKey 解析一个密钥配置
+func loadKey(config keyConfig) (key *SigningKey, err error) {
+	key = &SigningKey{ID: config.Kid, Algorithm: config.Alg}
+	switch config.Alg {
+	case AlgHS256:
+		secret := []byte(config.Secret)
+		if config.SecretFile != "" {
+			if secret, err = os.ReadFile(config.SecretFile); err != nil {
+				return
+			}
+		}
+		if len(secret) < 32 {
+			return nil, errors.New("HS256 密钥长度至少32字节")
+		}
+		key.signKey, key.verifyKey = secret, secret
+	case AlgRS256:
+		if config.PrivateKeyFile != "" {
+			var privateKey *rsa.PrivateKey
+			if privateKey, err = loadPEM(config.PrivateKeyFile, jwt.ParseRSAPrivateKeyFromPEM); err != nil {
+				return
+			}
+			key.signKey, key.verifyKey = privateKey, &privateKey.PublicKey
+		} else if key.verifyKey, err = loadPEM(config.PublicKeyFile, jwt.ParseRSAPublicKeyFromPEM); err != nil {
+			return
+		}
+	case AlgES256:
+		var publicKey *ecdsa.PublicKey
+		if config.PrivateKeyFile != "" {
+			var privateKey *ecdsa.PrivateKey
+			if privateKey, err = loadPEM(config.PrivateKeyFile, jwt.ParseECPrivateKeyFromPEM); err != nil {
+				return
+			}
+			key.signKey, publicKey = privateKey, &privateKey.PublicKey
+		} else if publicKey, err = loadPEM(config.PublicKeyFile, jwt.ParseECPublicKeyFromPEM); err != nil {
+			return
+		}
+		if publicKey.Curve != elliptic.P256() {
+			return nil, errors.New("ES256 只支持 P-256 曲线")
+		}
+		key.verifyKey = publicKey
+	default:
+		return nil, fmt.Errorf("不支持的签名算法: %s", config.Alg)
+	}
+	return
+}
+
+// loadPEM 读取并解析 PEM 格式的密钥文件
+func loadPEM[T any](file string, parse func([]byte) (T, error)) (key T, err error) {
+	if file == "" {
+		err = errors.New("缺少密钥文件")
+		return
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return
+	}
+	return parse(data)
+}
+
+// getActiveKey 当前签名使用的密钥
+func getActiveKey() (key *SigningKey) {
+	keysRWMutex.RLock()
+	defer keysRWMutex.RUnlock()
+	return activeKey
+}
+
+// getVerifyKey 根据 token 头部的 kid 和 alg 查找验证密钥，算法必须和密钥一致
+func getVerifyKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	keysRWMutex.RLock()
+	key, ok := signingKeys[kid]
+	keysRWMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未知的密钥: %s", kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("无效的签名方法")
+	}
+	if !key.expireAt.IsZero() && time.Now().After(key.expireAt) {
+		return nil, fmt.Errorf("密钥已过期: %s", kid)
+	}
+	return key.verifyKey, nil
+}
+
+// GetJWKS 全部非对称密钥的公钥，HS256 密钥不公开
+func GetJWKS() (keys []JWK) {
+	keysRWMutex.RLock()
+	defer keysRWMutex.RUnlock()
+	keys = make([]JWK, 0, len(signingKeys))
+	for _, key := range signingKeys {
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Algorithm}
+		switch publicKey := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case *ecdsa.PublicKey:
+			jwk.Kty = "EC"
+			jwk.Crv = "P-256"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, 32)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, 32)))
+		default:
+			continue
+		}
+		keys = append(keys, jwk)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Kid < keys[j].Kid })
+	return
+}
@@ -0,0 +1,109 @@
+package jwtlib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/viper"
+)
+
+// 配置 jwt.keys 后，内置密钥签发的没有 kid 的 token 只有在显式开启 legacyKey 且未过期时才能通过
+func TestInitLegacyKey(t *testing.T) {
+	const legacySecret = "legacy_secret_configured_by_operator"
+	sign := func(secret []byte) string {
+		claims := &Claims{UserID: "1", AppID: "101", RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "gowebsocket",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}}
+		tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+		if err != nil {
+			t.Fatalf("签名失败 %v", err)
+		}
+		return tokenString
+	}
+
+	tests := []struct {
+		name      string
+		legacyKey map[string]interface{}
+		token     string
+		wantValid bool
+	}{
+		{name: "未开启时拒绝内置密钥", legacyKey: nil, token: sign(jwtSecret), wantValid: false},
+		{name: "开启后不使用内置密钥", token: sign(jwtSecret), wantValid: false, legacyKey: map[string]interface{}{
+			"enabled": true, "secret": legacySecret, "expireAt": time.Now().Add(time.Hour).Format(time.RFC3339)}},
+		{name: "开启后接受配置的老密钥", token: sign([]byte(legacySecret)), wantValid: true,
+			legacyKey: map[string]interface{}{"enabled": true, "secret": legacySecret,
+				"expireAt": time.Now().Add(time.Hour).Format(time.RFC3339)}},
+		{name: "超过过期时间拒绝", token: sign([]byte(legacySecret)), wantValid: false,
+			legacyKey: map[string]interface{}{"enabled": true, "secret": legacySecret,
+				"expireAt": time.Now().Add(-time.Hour).Format(time.RFC3339)}},
+		{name: "enabled 为 false", token: sign([]byte(legacySecret)), wantValid: false,
+			legacyKey: map[string]interface{}{"enabled": false, "secret": legacySecret,
+				"expireAt": time.Now().Add(time.Hour).Format(time.RFC3339)}},
+	}
+
+	oldKeys, oldActive := signingKeys, activeKey
+	defer func() {
+		viper.Reset()
+		signingKeys, activeKey = oldKeys, oldActive
+	}()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			viper.Set("jwt.activeKey", "hs-1")
+			viper.Set("jwt.keys", []map[string]interface{}{
+				{"kid": "hs-1", "alg": AlgHS256, "secret": "0123456789abcdef0123456789abcdef"},
+			})
+			if tt.legacyKey != nil {
+				viper.Set("jwt.legacyKey", tt.legacyKey)
+			}
+			Init()
+
+			_, err := ValidateToken(tt.token)
+			if (err == nil) != tt.wantValid {
+				t.Fatalf("ValidateToken() err = %v, wantValid %v", err, tt.wantValid)
+			}
+			tokenString, _, err := NewToken("1", "101", time.Hour)
+			if err != nil {
+				t.Fatalf("NewToken() err = %v", err)
+			}
+			if _, err = ValidateToken(tokenString); err != nil {
+				t.Fatalf("当前密钥签发的 token 验证失败 %v", err)
+			}
+		})
+	}
+}
+
+func TestInitLegacyKeyInvalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		legacyKey map[string]interface{}
+	}{
+		{name: "缺少密钥", legacyKey: map[string]interface{}{"enabled": true, "expireAt": "2030-01-01T00:00:00Z"}},
+		{name: "缺少过期时间", legacyKey: map[string]interface{}{"enabled": true, "secret": "legacy"}},
+		{name: "过期时间格式错误", legacyKey: map[string]interface{}{"enabled": true, "secret": "legacy",
+			"expireAt": "2030-01-01"}},
+	}
+	oldKeys, oldActive := signingKeys, activeKey
+	defer func() {
+		viper.Reset()
+		signingKeys, activeKey = oldKeys, oldActive
+	}()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			viper.Set("jwt.activeKey", "hs-1")
+			viper.Set("jwt.keys", []map[string]interface{}{
+				{"kid": "hs-1", "alg": AlgHS256, "secret": "0123456789abcdef0123456789abcdef"},
+			})
+			viper.Set("jwt.legacyKey", tt.legacyKey)
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("Init() 没有 panic")
+				}
+			}()
+			Init()
+		})
+	}
+}
@@ -5,11 +5,19 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/viper"
+
+	"github.com/link1st/gowebsocket/v2/helper"
 )
 
-// JWT密钥，实际项目中应该从配置文件读取
+// 内置的JWT密钥，没有配置 jwt.keys 时使用
 var jwtSecret = []byte("gowebsocket_jwt_secret_key_2024")
 
+func init() {
+	// iat 精确到毫秒，吊销用户令牌后同一秒内新签发的令牌不会被误判，吊销前签发的令牌也不会漏判
+	jwt.TimePrecision = time.Millisecond
+}
+
 // Claims JWT声明结构体
 type Claims struct {
 	UserID string `json:"userID"`
@@ -25,36 +33,50 @@ type Claims struct {
  * @return token字符串和错误信息
  */
 func GenerateToken(userID, appID string, expireHours int) (string, error) {
+	tokenString, _, err := NewToken(userID, appID, time.Duration(expireHours)*time.Hour)
+	return tokenString, err
+}
+
+// getIssuer token 签发者
+func getIssuer() (issuer string) {
+	issuer = viper.GetString("jwt.issuer")
+	if issuer == "" {
+		issuer = "gowebsocket"
+	}
+	return
+}
+
+// NewToken 生成指定有效期的JWT token，返回的声明中带有唯一的 jti
+func NewToken(userID, appID string, ttl time.Duration) (tokenString string, claims *Claims, err error) {
 	if userID == "" {
-		return "", errors.New("userID不能为空")
+		return "", nil, errors.New("userID不能为空")
 	}
-	
-	// 设置过期时间
-	expirationTime := time.Now().Add(time.Duration(expireHours) * time.Hour)
-	
-	// 创建声明
-	claims := &Claims{
+
+	now := time.Now()
+	claims = &Claims{
 		UserID: userID,
 		AppID:  appID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "gowebsocket",
+			ID:        helper.GetRandomID(16),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    getIssuer(),
 			Subject:   userID,
 		},
 	}
-	
-	// 创建token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	
-	// 签名token
-	tokenString, err := token.SignedString(jwtSecret)
+
+	// 使用当前密钥签名，头部带上 kid 便于验证方选择公钥
+	key := getActiveKey()
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	tokenString, err = token.SignedString(key.signKey)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
-	
-	return tokenString, nil
+	return
 }
 
 /**
@@ -68,13 +90,8 @@ func ValidateToken(tokenString string) (*Claims, error) {
 	}
 	
 	// 解析token
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// 验证签名方法
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("无效的签名方法")
-		}
-		return jwtSecret, nil
-	})
+	// 根据 kid 选择验证密钥，同时验证签名方法和签发者
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, getVerifyKey, jwt.WithIssuer(getIssuer()))
 	
 	if err != nil {
 		return nil, err
@@ -0,0 +1,84 @@
+// Package media 媒体文件处理(存储、缩略图、元数据)
+package media
+
+import (
+	"time"
+
+	"github.com/link1st/gowebsocket/v2/helper"
+	"github.com/link1st/gowebsocket/v2/lib/audio"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+var (
+	// 音频格式 => 扩展名
+	audioExts = map[string]string{
+		audio.FormatWAV:  ".wav",
+		audio.FormatOpus: ".ogg",
+	}
+	// 音频格式 => MIME 类型
+	audioContentTypes = map[string]string{
+		audio.FormatWAV:  "audio/wav",
+		audio.FormatOpus: "audio/ogg",
+	}
+)
+
+// ParseAudio 解码语音数据，校验格式和时长，计算波形并生成媒体元数据，此时还没有保存
+// claimedDuration 为客户端声明的时长(毫秒)，0 表示未声明
+func ParseAudio(ownerID, appID, audioFormat, audioData string, claimedDuration int) (mediaInfo *models.MediaInfo,
+	clip *audio.Clip, data []byte, err error) {
+	data, err = audio.DecodeBase64(audioData)
+	if err != nil {
+		return
+	}
+	clip, err = audio.Parse(audioFormat, data)
+	if err != nil {
+		return
+	}
+	if err = audio.CheckDuration(claimedDuration, clip.Duration); err != nil {
+		return
+	}
+
+	mediaID := helper.GetRandomID(16)
+	mediaInfo = models.NewAudioMedia(mediaID, ownerID, appID, audioFormat, int64(len(data)), time.Now().Unix())
+	if contentType, ok := audioContentTypes[audioFormat]; ok {
+		mediaInfo.ContentType = contentType
+	}
+	mediaInfo.SampleRate = clip.SampleRate
+	mediaInfo.Channels = clip.Channels
+	mediaInfo.Duration = clip.Duration
+	mediaInfo.Waveform = clip.Waveform()
+	ext, ok := audioExts[audioFormat]
+	if !ok {
+		ext = ".pcm"
+	}
+	mediaInfo.File = mediaID + ext
+	return
+}
+
+// SaveAudio 保存 ParseAudio 解析的语音，消息确定可以发送后再调用，避免被拒绝的语音留在存储中
+func SaveAudio(mediaInfo *models.MediaInfo, data []byte) (err error) {
+	if err = SaveFile(mediaInfo.File, data); err != nil {
+		return
+	}
+	err = cache.SetMediaInfo(mediaInfo)
+	return
+}
+
+// ReadAudioWAV 读取语音并封装为 WAV
+func ReadAudioWAV(mediaInfo *models.MediaInfo) (wav []byte, err error) {
+	data, err := ReadFile(mediaInfo.File)
+	if err != nil {
+		return
+	}
+	if mediaInfo.AudioFormat == audio.FormatWAV {
+		wav = data
+		return
+	}
+	clip, err := audio.Parse(mediaInfo.AudioFormat, data)
+	if err != nil {
+		return
+	}
+	wav, err = clip.ToWAV()
+	return
+}
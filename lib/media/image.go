@@ -0,0 +1,192 @@
+// Package media 媒体文件处理(存储、缩略图、元数据)
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	// 注册 gif 解码器
+	_ "image/gif"
+)
+
+const (
+	thumbnailQuality = 85 // 缩略图 jpeg 质量
+)
+
+var (
+	// 支持的图片格式 MIME => 扩展名
+	imageTypes = map[string]string{
+		"image/jpeg": ".jpg",
+		"image/png":  ".png",
+		"image/gif":  ".gif",
+	}
+
+	jpegExifHeader = []byte("Exif\x00\x00")
+	jpegXmpHeader  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	pngSignature   = []byte("\x89PNG\r\n\x1a\n")
+)
+
+// DetectImage 识别图片类型和尺寸
+func DetectImage(data []byte) (contentType string, ext string, width int, height int, err error) {
+	contentType = http.DetectContentType(data)
+	ext, ok := imageTypes[contentType]
+	if !ok {
+		err = ErrUnsupportedType
+		return
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	width = config.Width
+	height = config.Height
+	return
+}
+
+// CheckPixels 检查图片像素数，防止解码时占用过多内存(解压炸弹)
+func CheckPixels(width int, height int) (err error) {
+	if width <= 0 || height <= 0 || int64(width)*int64(height) > GetMaxPixels() {
+		err = ErrImageTooLarge
+	}
+	return
+}
+
+// StripMetadata 去除图片中的 EXIF/XMP 元数据(包含 GPS 位置信息)，不重新编码图片
+func StripMetadata(contentType string, data []byte) (result []byte, err error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJpegMetadata(data)
+	case "image/png":
+		return stripPngMetadata(data)
+	default:
+		// gif 不包含 EXIF
+		return data, nil
+	}
+}
+
+// stripJpegMetadata 删除 APP1 中的 Exif 和 XMP 段
+func stripJpegMetadata(data []byte) (result []byte, err error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		err = errors.New("jpeg 格式错误")
+		return
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, len(data)))
+	buf.Write(data[:2])
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			err = errors.New("jpeg 段标记错误")
+			return
+		}
+		marker := data[pos+1]
+		// 图像数据开始，剩余部分原样保留
+		if marker == 0xDA {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			err = errors.New("jpeg 段长度错误")
+			return
+		}
+		segment := data[pos:end]
+		payload := segment[4:]
+		if marker == 0xE1 && (bytes.HasPrefix(payload, jpegExifHeader) || bytes.HasPrefix(payload, jpegXmpHeader)) {
+			pos = end
+			continue
+		}
+		buf.Write(segment)
+		pos = end
+	}
+	buf.Write(data[pos:])
+	result = buf.Bytes()
+	return
+}
+
+// stripPngMetadata 删除 eXIf 块
+func stripPngMetadata(data []byte) (result []byte, err error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		err = errors.New("png 格式错误")
+		return
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, len(data)))
+	buf.Write(pngSignature)
+	pos := len(pngSignature)
+	for pos+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			err = errors.New("png 块长度错误")
+			return
+		}
+		if string(data[pos+4:pos+8]) != "eXIf" {
+			buf.Write(data[pos:end])
+		}
+		pos = end
+	}
+	result = buf.Bytes()
+	return
+}
+
+// Thumbnail 按最长边 size 等比缩小图片，图片小于 size 时保持原尺寸
+func Thumbnail(src image.Image, size int) (dst *image.RGBA) {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dstW, dstH := srcW, srcH
+	if srcW > size || srcH > size {
+		if srcW >= srcH {
+			dstW = size
+			dstH = max(1, srcH*size/srcW)
+		} else {
+			dstH = size
+			dstW = max(1, srcW*size/srcH)
+		}
+	}
+
+	// 区域平均采样，直接从原图读取，不再复制一份原图大小的像素
+	dst = image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := y * srcH / dstH
+		y1 := max(y0+1, (y+1)*srcH/dstH)
+		for x := 0; x < dstW; x++ {
+			x0 := x * srcW / dstW
+			x1 := max(x0+1, (x+1)*srcW/dstW)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(bounds.Min.X+sx, bounds.Min.Y+sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset] = uint8(r / n >> 8)
+			dst.Pix[offset+1] = uint8(g / n >> 8)
+			dst.Pix[offset+2] = uint8(b / n >> 8)
+			dst.Pix[offset+3] = uint8(a / n >> 8)
+		}
+	}
+	return
+}
+
+// EncodeThumbnail 编码缩略图，png/gif 保留透明通道使用 png，其它使用 jpeg
+func EncodeThumbnail(contentType string, img image.Image) (data []byte, ext string, err error) {
+	buf := &bytes.Buffer{}
+	if contentType == "image/png" || contentType == "image/gif" {
+		ext = ".png"
+		err = png.Encode(buf, img)
+	} else {
+		ext = ".jpg"
+		err = jpeg.Encode(buf, img, &jpeg.Options{Quality: thumbnailQuality})
+	}
+	data = buf.Bytes()
+	return
+}
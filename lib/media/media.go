@@ -0,0 +1,130 @@
+// Package media 媒体文件处理(存储、缩略图、元数据)
+package media
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultDir       = "media"
+	defaultMaxSize   = 10 * 1024 * 1024 // 默认最大 10M
+	defaultMaxPixels = 40 * 1000 * 1000 // 默认图片最多 4000 万像素
+	defaultWorkers   = 2
+	defaultQueueSize = 1000
+)
+
+var (
+	defaultThumbnailSizes = []int{160, 480} // 默认缩略图尺寸(最长边)
+
+	// ErrFileTooLarge 文件过大
+	ErrFileTooLarge = errors.New("文件过大")
+	// ErrUnsupportedType 不支持的文件类型
+	ErrUnsupportedType = errors.New("不支持的文件类型")
+	// ErrImageTooLarge 图片像素数过多
+	ErrImageTooLarge = errors.New("图片尺寸过大")
+)
+
+// GetDir 媒体文件存储目录
+func GetDir() (dir string) {
+	dir = viper.GetString("media.dir")
+	if dir == "" {
+		dir = defaultDir
+	}
+	return
+}
+
+// GetMaxSize 上传文件大小上限
+func GetMaxSize() (maxSize int64) {
+	maxSize = viper.GetInt64("media.maxSize")
+	if maxSize <= 0 {
+		maxSize = defaultMaxSize
+	}
+	return
+}
+
+// GetMaxPixels 图片像素数(宽*高)上限
+func GetMaxPixels() (maxPixels int64) {
+	maxPixels = viper.GetInt64("media.maxPixels")
+	if maxPixels <= 0 {
+		maxPixels = defaultMaxPixels
+	}
+	return
+}
+
+// GetThumbnailSizes 缩略图尺寸配置
+func GetThumbnailSizes() (sizes []int) {
+	sizes = make([]int, 0)
+	for _, size := range viper.GetIntSlice("media.thumbnailSizes") {
+		if size > 0 {
+			sizes = append(sizes, size)
+		}
+	}
+	if len(sizes) == 0 {
+		sizes = defaultThumbnailSizes
+	}
+	return
+}
+
+// Init 初始化存储目录并启动处理队列
+func Init() {
+	dir := GetDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		panic(fmt.Errorf("创建媒体目录失败: %s \n", err))
+	}
+	startWorkers()
+	fmt.Println("媒体处理 初始化成功", dir)
+}
+
+// GetFilePath 获取文件存储路径
+func GetFilePath(file string) (path string, err error) {
+	// 只允许文件名，防止路径穿越
+	if file == "" || file != filepath.Base(file) || strings.HasPrefix(file, ".") {
+		err = errors.New("非法的文件名")
+		return
+	}
+	path = filepath.Join(GetDir(), file)
+	return
+}
+
+// SaveFile 保存文件
+func SaveFile(file string, data []byte) (err error) {
+	path, err := GetFilePath(file)
+	if err != nil {
+		return
+	}
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		fmt.Println("保存媒体文件失败", path, err)
+	}
+	return
+}
+
+// ReadFile 读取文件
+func ReadFile(file string) (data []byte, err error) {
+	path, err := GetFilePath(file)
+	if err != nil {
+		return
+	}
+	data, err = os.ReadFile(path)
+	return
+}
+
+// ReadLimit 读取上传内容，超过大小上限返回 ErrFileTooLarge
+func ReadLimit(r io.Reader) (data []byte, err error) {
+	maxSize := GetMaxSize()
+	data, err = io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return
+	}
+	if int64(len(data)) > maxSize {
+		err = ErrFileTooLarge
+	}
+	return
+}
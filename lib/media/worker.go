@@ -0,0 +1,127 @@
+// Package media 媒体文件处理(存储、缩略图、元数据)
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"runtime/debug"
+
+	"github.com/spf13/viper"
+
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+var (
+	queue chan string // 待处理的媒体ID
+
+	// ErrQueueFull 处理队列已满
+	ErrQueueFull = errors.New("媒体处理队列已满")
+)
+
+// startWorkers 启动处理协程
+func startWorkers() {
+	workers := viper.GetInt("media.workers")
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	queueSize := viper.GetInt("media.queueSize")
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	queue = make(chan string, queueSize)
+	for i := 0; i < workers; i++ {
+		go worker()
+	}
+}
+
+// Enqueue 加入处理队列
+func Enqueue(mediaID string) (err error) {
+	if queue == nil {
+		return errors.New("媒体处理队列未初始化")
+	}
+	select {
+	case queue <- mediaID:
+	default:
+		err = ErrQueueFull
+	}
+	return
+}
+
+func worker() {
+	for mediaID := range queue {
+		process(mediaID)
+	}
+}
+
+// process 处理单个媒体文件
+func process(mediaID string) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("媒体处理 stop", mediaID, r, string(debug.Stack()))
+		}
+	}()
+	mediaInfo, err := cache.GetMediaInfo(mediaID)
+	if err != nil {
+		fmt.Println("媒体处理 获取元数据失败", mediaID, err)
+		return
+	}
+	if mediaInfo.Kind != models.MediaKindImage {
+		return
+	}
+	err = processImage(mediaInfo)
+	if err != nil {
+		fmt.Println("媒体处理 失败", mediaID, err)
+		mediaInfo.Status = models.MediaStatusFailed
+	} else {
+		mediaInfo.Status = models.MediaStatusReady
+	}
+	_ = cache.SetMediaInfo(mediaInfo)
+	fmt.Println("媒体处理 完成", mediaID, mediaInfo.Status, len(mediaInfo.Thumbnails))
+}
+
+// processImage 生成缩略图并记录尺寸
+func processImage(mediaInfo *models.MediaInfo) (err error) {
+	data, err := ReadFile(mediaInfo.File)
+	if err != nil {
+		return
+	}
+	// 解码前再检查一次像素数，上传之后修改了配置或文件时也不会解码超大图片
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	if err = CheckPixels(config.Width, config.Height); err != nil {
+		return
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	bounds := img.Bounds()
+	mediaInfo.Width = bounds.Dx()
+	mediaInfo.Height = bounds.Dy()
+
+	thumbnails := make([]*models.Thumbnail, 0)
+	for _, size := range GetThumbnailSizes() {
+		thumb := Thumbnail(img, size)
+		thumbData, ext, err := EncodeThumbnail(mediaInfo.ContentType, thumb)
+		if err != nil {
+			return err
+		}
+		file := fmt.Sprintf("%s_%d%s", mediaInfo.MediaID, size, ext)
+		if err = SaveFile(file, thumbData); err != nil {
+			return err
+		}
+		thumbnails = append(thumbnails, &models.Thumbnail{
+			Size:   size,
+			Width:  thumb.Bounds().Dx(),
+			Height: thumb.Bounds().Dy(),
+			File:   file,
+		})
+	}
+	mediaInfo.Thumbnails = thumbnails
+	return
+}
@@ -0,0 +1,152 @@
+// Package oidc 外部身份提供方(OIDC)登录和上游 token 验证
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	jwksCacheTime      = 10 * time.Minute // 公钥缓存时间
+	jwksRefreshMinTime = 30 * time.Second // 遇到未知 kid 时最短刷新间隔，防止被刷接口
+)
+
+// jwk 单个公钥
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// keySet 远程 JWKS 的本地缓存
+type keySet struct {
+	url       string
+	client    *http.Client
+	lock      sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+func newKeySet(url string, client *http.Client) *keySet {
+	return &keySet{url: url, client: client}
+}
+
+// getKey 根据 kid 获取公钥，缓存过期或 kid 未知时重新拉取
+func (s *keySet) getKey(kid string) (key interface{}, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key, ok := s.keys[kid]
+	expired := time.Since(s.fetchedAt) > jwksCacheTime
+	if ok && !expired {
+		return
+	}
+	if expired || time.Since(s.fetchedAt) > jwksRefreshMinTime {
+		if err = s.fetch(); err != nil {
+			return
+		}
+		if key, ok = s.keys[kid]; ok {
+			return
+		}
+	}
+	return nil, fmt.Errorf("未知的公钥: %s", kid)
+}
+
+// fetch 拉取公钥
+func (s *keySet) fetch() (err error) {
+	rsp, err := s.client.Get(s.url)
+	if err != nil {
+		fmt.Println("拉取JWKS失败", s.url, err)
+		return
+	}
+	defer func() { _ = rsp.Body.Close() }()
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("拉取JWKS失败 status:%d", rsp.StatusCode)
+	}
+	var body struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err = json.NewDecoder(rsp.Body).Decode(&body); err != nil {
+		return
+	}
+	keys := make(map[string]interface{}, len(body.Keys))
+	for _, item := range body.Keys {
+		key, err := item.publicKey()
+		if err != nil {
+			fmt.Println("解析JWKS公钥失败", s.url, item.Kid, err)
+			continue
+		}
+		keys[item.Kid] = key
+	}
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	return
+}
+
+// publicKey 解析 RSA 或 P-256 公钥
+func (k *jwk) publicKey() (key interface{}, err error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("不支持的曲线: %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		publicKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !publicKey.Curve.IsOnCurve(x, y) {
+			return nil, errors.New("无效的EC公钥")
+		}
+		return publicKey, nil
+	}
+	return nil, fmt.Errorf("不支持的密钥类型: %s", k.Kty)
+}
+
+func decodeBigInt(value string) (n *big.Int, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// keyFunc 供 jwt 解析使用，只接受非对称签名算法
+func (s *keySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+	default:
+		return nil, errors.New("无效的签名方法")
+	}
+	kid, _ := token.Header["kid"].(string)
+	return s.getKey(kid)
+}
@@ -0,0 +1,290 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/viper"
+)
+
+const (
+	httpTimeout = 10 * time.Second
+)
+
+var (
+	// ErrProviderNotFound 未配置的身份提供方
+	ErrProviderNotFound = errors.New("身份提供方不存在")
+)
+
+// Config 身份提供方配置 oidc.providers
+// 配置了 issuer 时通过 {issuer}/.well-known/openid-configuration 发现各个端点，也可以直接配置端点
+type Config struct {
+	Name             string   `mapstructure:"name"`
+	Issuer           string   `mapstructure:"issuer"`
+	ClientID         string   `mapstructure:"clientID"`
+	ClientSecret     string   `mapstructure:"clientSecret"`
+	RedirectURL      string   `mapstructure:"redirectURL"`
+	Scopes           []string `mapstructure:"scopes"`
+	AuthURL          string   `mapstructure:"authURL"`
+	TokenURL         string   `mapstructure:"tokenURL"`
+	JWKSURL          string   `mapstructure:"jwksURL"`
+	Audiences        []string `mapstructure:"audiences"`        // token exchange 接受的 aud，默认 clientID
+	AllowLogin       bool     `mapstructure:"allowLogin"`       // 允许授权码登录
+	AllowExchange    bool     `mapstructure:"allowExchange"`    // 允许上游 token 换取 gim token
+	AutoCreate       bool     `mapstructure:"autoCreate"`       // 外部用户第一次登录时自动创建 gim 用户
+	UserIDClaim      string   `mapstructure:"userIDClaim"`      // 自动创建时作为 gim 用户ID的声明，为空时生成
+	NicknameClaim    string   `mapstructure:"nicknameClaim"`    // 昵称声明，默认 name
+	ClockSkewSeconds int64    `mapstructure:"clockSkewSeconds"` // 允许的时钟误差
+}
+
+// Claims 验证通过的外部身份
+type Claims struct {
+	Subject  string
+	Nonce    string
+	Nickname string
+	UserID   string // UserIDClaim 对应的值
+}
+
+// Provider 身份提供方
+type Provider struct {
+	Config
+	client *http.Client
+	keySet *keySet
+	lock   sync.Mutex
+	ready  bool
+}
+
+var (
+	providersRWMutex sync.RWMutex
+	providers        = make(map[string]*Provider)
+)
+
+// Init 从配置加载身份提供方，端点在第一次使用时发现
+func Init() {
+	var configs []Config
+	if err := viper.UnmarshalKey("oidc.providers", &configs); err != nil {
+		panic(fmt.Errorf("读取OIDC配置失败: %s \n", err))
+	}
+	loaded := make(map[string]*Provider, len(configs))
+	for _, config := range configs {
+		if config.Name == "" || config.ClientID == "" {
+			panic(fmt.Errorf("OIDC配置缺少 name 或 clientID \n"))
+		}
+		// 上游 token 不经过授权码流程，必须校验签发者，否则同一 JWKS 下其他签发者的 token 也能换取
+		if config.AllowExchange && config.Issuer == "" {
+			panic(fmt.Errorf("OIDC配置 %s 开启 allowExchange 时必须配置 issuer \n", config.Name))
+		}
+		loaded[config.Name] = NewProvider(config)
+	}
+	providersRWMutex.Lock()
+	defer providersRWMutex.Unlock()
+	providers = loaded
+}
+
+// NewProvider 创建身份提供方
+func NewProvider(config Config) *Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "profile"}
+	}
+	if len(config.Audiences) == 0 {
+		config.Audiences = []string{config.ClientID}
+	}
+	if config.NicknameClaim == "" {
+		config.NicknameClaim = "name"
+	}
+	return &Provider{Config: config, client: &http.Client{Timeout: httpTimeout}}
+}
+
+// GetProvider 获取身份提供方
+func GetProvider(name string) (provider *Provider, err error) {
+	providersRWMutex.RLock()
+	provider, ok := providers[name]
+	providersRWMutex.RUnlock()
+	if !ok {
+		return nil, ErrProviderNotFound
+	}
+	if err = provider.init(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// init 第一次使用时初始化，失败后下次使用时重试
+func (p *Provider) init() (err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.ready {
+		return
+	}
+	if err = p.discover(); err != nil {
+		fmt.Println("OIDC 初始化失败", p.Name, err)
+		return
+	}
+	p.keySet = newKeySet(p.JWKSURL, p.client)
+	p.ready = true
+	fmt.Println("OIDC 初始化成功", p.Name, p.Issuer)
+	return
+}
+
+// discover 补全未配置的端点
+func (p *Provider) discover() (err error) {
+	if p.AuthURL == "" || p.TokenURL == "" || p.JWKSURL == "" {
+		if p.Issuer == "" {
+			return errors.New("OIDC 缺少 issuer")
+		}
+		rsp, err := p.client.Get(strings.TrimSuffix(p.Issuer, "/") + "/.well-known/openid-configuration")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = rsp.Body.Close() }()
+		var metadata struct {
+			Issuer   string `json:"issuer"`
+			AuthURL  string `json:"authorization_endpoint"`
+			TokenURL string `json:"token_endpoint"`
+			JWKSURL  string `json:"jwks_uri"`
+		}
+		if err = json.NewDecoder(rsp.Body).Decode(&metadata); err != nil {
+			return err
+		}
+		if metadata.Issuer != p.Issuer {
+			return fmt.Errorf("OIDC issuer 不匹配: %s", metadata.Issuer)
+		}
+		if p.AuthURL == "" {
+			p.AuthURL = metadata.AuthURL
+		}
+		if p.TokenURL == "" {
+			p.TokenURL = metadata.TokenURL
+		}
+		if p.JWKSURL == "" {
+			p.JWKSURL = metadata.JWKSURL
+		}
+	}
+	if p.JWKSURL == "" {
+		return errors.New("OIDC 缺少 jwksURL")
+	}
+	return
+}
+
+// AuthCodeURL 授权地址，使用 PKCE(S256)
+func (p *Provider) AuthCodeURL(state, nonce, codeVerifier string) (authURL string, err error) {
+	if !p.AllowLogin || p.AuthURL == "" {
+		return "", errors.New("该身份提供方不支持授权码登录")
+	}
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.ClientID},
+		"redirect_uri":          {p.RedirectURL},
+		"scope":                 {strings.Join(p.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(p.AuthURL, "?") {
+		separator = "&"
+	}
+	return p.AuthURL + separator + query.Encode(), nil
+}
+
+// Exchange 使用授权码换取并验证 id_token
+func (p *Provider) Exchange(code, codeVerifier, nonce string) (claims *Claims, err error) {
+	if !p.AllowLogin || p.TokenURL == "" {
+		return nil, errors.New("该身份提供方不支持授权码登录")
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+		"code_verifier": {codeVerifier},
+	}
+	rsp, err := p.client.PostForm(p.TokenURL, form)
+	if err != nil {
+		fmt.Println("OIDC 换取token失败", p.Name, err)
+		return
+	}
+	defer func() { _ = rsp.Body.Close() }()
+	var body struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}
+	if err = json.NewDecoder(rsp.Body).Decode(&body); err != nil {
+		return
+	}
+	if rsp.StatusCode != http.StatusOK || body.IDToken == "" {
+		return nil, fmt.Errorf("OIDC 换取token失败 status:%d error:%s", rsp.StatusCode, body.Error)
+	}
+	claims, err = p.verify(body.IDToken, []string{p.ClientID})
+	if err != nil {
+		return
+	}
+	if claims.Nonce != nonce {
+		return nil, errors.New("nonce 不匹配")
+	}
+	return
+}
+
+// VerifyToken 验证上游服务签发的 JWT (token exchange)
+func (p *Provider) VerifyToken(rawToken string) (claims *Claims, err error) {
+	if !p.AllowExchange || p.Issuer == "" {
+		return nil, errors.New("该身份提供方不支持 token exchange")
+	}
+	return p.verify(rawToken, p.Audiences)
+}
+
+// verify 验证签名、iss、aud、exp
+func (p *Provider) verify(rawToken string, audiences []string) (claims *Claims, err error) {
+	options := []jwt.ParserOption{
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Duration(p.ClockSkewSeconds) * time.Second),
+	}
+	if p.Issuer != "" {
+		options = append(options, jwt.WithIssuer(p.Issuer))
+	}
+	mapClaims := jwt.MapClaims{}
+	if _, err = jwt.ParseWithClaims(rawToken, mapClaims, p.keySet.keyFunc, options...); err != nil {
+		return
+	}
+	if !matchAudience(mapClaims, audiences) {
+		return nil, errors.New("aud 不匹配")
+	}
+	claims = &Claims{}
+	claims.Subject, _ = mapClaims["sub"].(string)
+	claims.Nonce, _ = mapClaims["nonce"].(string)
+	claims.Nickname, _ = mapClaims[p.NicknameClaim].(string)
+	if p.UserIDClaim != "" {
+		claims.UserID, _ = mapClaims[p.UserIDClaim].(string)
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("缺少 sub")
+	}
+	return
+}
+
+// matchAudience aud 中包含任意一个允许的值
+func matchAudience(mapClaims jwt.MapClaims, audiences []string) bool {
+	tokenAudiences, err := mapClaims.GetAudience()
+	if err != nil {
+		return false
+	}
+	for _, audience := range tokenAudiences {
+		for _, allowed := range audiences {
+			if audience == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,313 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/viper"
+)
+
+const (
+	mockClientID = "gim"
+	mockCode     = "code-1"
+	mockNonce    = "nonce-1"
+)
+
+// mockIdP 本地模拟的身份提供方，提供发现、JWKS 和 token 端点
+type mockIdP struct {
+	server         *httptest.Server
+	rsaKey         *rsa.PrivateKey
+	ecKey          *ecdsa.PrivateKey
+	metadataIssuer string // 发现接口返回的 issuer，为空时使用服务地址
+	idToken        string // token 端点返回的 id_token
+}
+
+func newMockIdP(t *testing.T) (m *mockIdP) {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = &mockIdP{rsaKey: rsaKey, ecKey: ecKey}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer := m.metadataIssuer
+		if issuer == "" {
+			issuer = m.server.URL
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		encode := func(n *big.Int) string { return base64.RawURLEncoding.EncodeToString(n.Bytes()) }
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{
+				{"kty": "RSA", "kid": "rs", "alg": "RS256", "n": encode(rsaKey.N), "e": encode(big.NewInt(int64(rsaKey.E)))},
+				{"kty": "EC", "kid": "es", "alg": "ES256", "crv": "P-256", "x": encode(ecKey.X), "y": encode(ecKey.Y)},
+			},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("code") != mockCode ||
+			r.PostFormValue("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": m.idToken, "token_type": "Bearer"})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return
+}
+
+// claims 默认有效的声明，overrides 中值为 nil 的声明会被删除
+func (m *mockIdP) claims(overrides jwt.MapClaims) (claims jwt.MapClaims) {
+	now := time.Now()
+	claims = jwt.MapClaims{
+		"iss":   m.server.URL,
+		"sub":   "subject-1",
+		"aud":   mockClientID,
+		"exp":   now.Add(time.Hour).Unix(),
+		"iat":   now.Unix(),
+		"nonce": mockNonce,
+		"name":  "测试用户",
+	}
+	for key, value := range overrides {
+		if value == nil {
+			delete(claims, key)
+			continue
+		}
+		claims[key] = value
+	}
+	return
+}
+
+// sign 签名，kid 和 alg 决定使用的密钥
+func (m *mockIdP) sign(t *testing.T, alg string, kid string, claims jwt.MapClaims) (token string) {
+	t.Helper()
+	var key interface{}
+	switch alg {
+	case "RS256":
+		key = m.rsaKey
+	case "ES256":
+		key = m.ecKey
+	case "HS256":
+		key = []byte("shared-secret-shared-secret-1234")
+	case "none":
+		key = jwt.UnsafeAllowNoneSignatureType
+	}
+	jwtToken := jwt.NewWithClaims(jwt.GetSigningMethod(alg), claims)
+	jwtToken.Header["kid"] = kid
+	token, err := jwtToken.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func (m *mockIdP) provider(t *testing.T, config Config) (provider *Provider) {
+	t.Helper()
+	config.Name = "mock"
+	config.Issuer = m.server.URL
+	config.ClientID = mockClientID
+	config.RedirectURL = "http://127.0.0.1/callback"
+	provider = NewProvider(config)
+	if err := provider.init(); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestDiscover(t *testing.T) {
+	tests := []struct {
+		name           string
+		metadataIssuer string
+		wantErr        bool
+	}{
+		{name: "发现端点", wantErr: false},
+		{name: "issuer 不匹配", metadataIssuer: "https://evil.example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockIdP(t)
+			m.metadataIssuer = tt.metadataIssuer
+			provider := NewProvider(Config{Name: "mock", Issuer: m.server.URL, ClientID: mockClientID})
+			err := provider.init()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("init() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if provider.AuthURL != m.server.URL+"/authorize" || provider.TokenURL != m.server.URL+"/token" ||
+				provider.JWKSURL != m.server.URL+"/jwks" {
+				t.Fatalf("端点错误 %s %s %s", provider.AuthURL, provider.TokenURL, provider.JWKSURL)
+			}
+		})
+	}
+}
+
+func TestExchange(t *testing.T) {
+	m := newMockIdP(t)
+	provider := m.provider(t, Config{AllowLogin: true})
+
+	tests := []struct {
+		name    string
+		alg     string
+		kid     string
+		claims  jwt.MapClaims
+		code    string
+		nonce   string
+		wantErr bool
+	}{
+		{name: "成功", alg: "RS256", kid: "rs"},
+		{name: "ES256", alg: "ES256", kid: "es"},
+		{name: "nonce 不匹配", alg: "RS256", kid: "rs", nonce: "nonce-2", wantErr: true},
+		{name: "缺少 nonce", alg: "RS256", kid: "rs", claims: jwt.MapClaims{"nonce": nil}, wantErr: true},
+		{name: "aud 错误", alg: "RS256", kid: "rs", claims: jwt.MapClaims{"aud": "other"}, wantErr: true},
+		{name: "iss 错误", alg: "RS256", kid: "rs", claims: jwt.MapClaims{"iss": "https://evil.example.com"},
+			wantErr: true},
+		{name: "已过期", alg: "RS256", kid: "rs", claims: jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()},
+			wantErr: true},
+		{name: "缺少 exp", alg: "RS256", kid: "rs", claims: jwt.MapClaims{"exp": nil}, wantErr: true},
+		{name: "HS256 签名", alg: "HS256", kid: "rs", wantErr: true},
+		{name: "none 签名", alg: "none", kid: "rs", wantErr: true},
+		{name: "kid 与算法不一致", alg: "RS256", kid: "es", wantErr: true},
+		{name: "未知 kid", alg: "RS256", kid: "unknown", wantErr: true},
+		{name: "授权码错误", alg: "RS256", kid: "rs", code: "code-2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.idToken = m.sign(t, tt.alg, tt.kid, m.claims(tt.claims))
+			code, nonce := mockCode, mockNonce
+			if tt.code != "" {
+				code = tt.code
+			}
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+			claims, err := provider.Exchange(code, "verifier-1", nonce)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Exchange() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (claims.Subject != "subject-1" || claims.Nickname != "测试用户") {
+				t.Fatalf("Exchange() claims = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestExchangeNotAllowed(t *testing.T) {
+	m := newMockIdP(t)
+	provider := m.provider(t, Config{AllowExchange: true})
+	m.idToken = m.sign(t, "RS256", "rs", m.claims(nil))
+	if _, err := provider.Exchange(mockCode, "verifier-1", mockNonce); err == nil {
+		t.Fatal("未开启 allowLogin 时 Exchange() 应该失败")
+	}
+}
+
+func TestVerifyToken(t *testing.T) {
+	m := newMockIdP(t)
+	provider := m.provider(t, Config{AllowExchange: true, Audiences: []string{"gim", "api"}, UserIDClaim: "uid"})
+
+	tests := []struct {
+		name       string
+		alg        string
+		kid        string
+		claims     jwt.MapClaims
+		wantUserID string
+		wantErr    bool
+	}{
+		{name: "成功", alg: "RS256", kid: "rs", claims: jwt.MapClaims{"uid": "user-1"}, wantUserID: "user-1"},
+		{name: "多个 aud 其中一个匹配", alg: "ES256", kid: "es", claims: jwt.MapClaims{"aud": []string{"x", "api"}}},
+		{name: "aud 错误", alg: "RS256", kid: "rs", claims: jwt.MapClaims{"aud": "other"}, wantErr: true},
+		{name: "缺少 aud", alg: "RS256", kid: "rs", claims: jwt.MapClaims{"aud": nil}, wantErr: true},
+		{name: "iss 错误", alg: "RS256", kid: "rs", claims: jwt.MapClaims{"iss": "https://evil.example.com"},
+			wantErr: true},
+		{name: "缺少 iss", alg: "RS256", kid: "rs", claims: jwt.MapClaims{"iss": nil}, wantErr: true},
+		{name: "缺少 sub", alg: "RS256", kid: "rs", claims: jwt.MapClaims{"sub": nil}, wantErr: true},
+		{name: "尚未生效", alg: "RS256", kid: "rs", claims: jwt.MapClaims{"nbf": time.Now().Add(time.Hour).Unix()},
+			wantErr: true},
+		{name: "HS256 签名", alg: "HS256", kid: "rs", wantErr: true},
+		{name: "none 签名", alg: "none", kid: "rs", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := provider.VerifyToken(m.sign(t, tt.alg, tt.kid, m.claims(tt.claims)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyToken() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (claims.Subject != "subject-1" || claims.UserID != tt.wantUserID) {
+				t.Fatalf("VerifyToken() claims = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestVerifyTokenNotAllowed(t *testing.T) {
+	m := newMockIdP(t)
+	token := m.sign(t, "RS256", "rs", m.claims(nil))
+
+	provider := m.provider(t, Config{AllowLogin: true})
+	if _, err := provider.VerifyToken(token); err == nil {
+		t.Fatal("未开启 allowExchange 时 VerifyToken() 应该失败")
+	}
+
+	// 直接配置端点、没有 issuer 的身份提供方不能用于 token exchange
+	provider = NewProvider(Config{Name: "mock", ClientID: mockClientID, AllowExchange: true,
+		AuthURL: m.server.URL + "/authorize", TokenURL: m.server.URL + "/token", JWKSURL: m.server.URL + "/jwks"})
+	if err := provider.init(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.VerifyToken(token); err == nil {
+		t.Fatal("没有 issuer 时 VerifyToken() 应该失败")
+	}
+}
+
+func TestInitRequireIssuer(t *testing.T) {
+	tests := []struct {
+		name      string
+		provider  map[string]interface{}
+		wantPanic bool
+	}{
+		{name: "exchange 配置 issuer", provider: map[string]interface{}{
+			"name": "corp", "clientID": "gim", "issuer": "https://sso.example.com", "allowExchange": true}},
+		{name: "exchange 缺少 issuer", provider: map[string]interface{}{
+			"name": "corp", "clientID": "gim", "jwksURL": "https://sso.example.com/jwks", "allowExchange": true},
+			wantPanic: true},
+		{name: "授权码登录可以不配置 issuer", provider: map[string]interface{}{
+			"name": "corp", "clientID": "gim", "jwksURL": "https://sso.example.com/jwks", "allowLogin": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("oidc.providers", []map[string]interface{}{tt.provider})
+			defer viper.Set("oidc.providers", nil)
+			defer func() {
+				if r := recover(); (r != nil) != tt.wantPanic {
+					t.Fatalf("Init() panic = %v, wantPanic %v", r, tt.wantPanic)
+				}
+			}()
+			Init()
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Package password 密码哈希
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	argonTime    = 1         // 迭代次数
+	argonMemory  = 64 * 1024 // 内存(KiB)
+	argonThreads = 2         // 并行度
+	argonKeyLen  = 32        // 哈希长度
+	argonSaltLen = 16        // 盐长度
+)
+
+var (
+	// ErrInvalidHash 无法识别的哈希格式
+	ErrInvalidHash = errors.New("无法识别的密码哈希")
+)
+
+// Hash 使用 argon2id 计算密码哈希
+// 格式: $argon2id$v=19$m=65536,t=1,p=2$盐$哈希
+func Hash(password string) (encoded string, err error) {
+	salt := make([]byte, argonSaltLen)
+	if _, err = rand.Read(salt); err != nil {
+		return
+	}
+	key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	encoded = fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argonMemory, argonTime,
+		argonThreads, base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+	return
+}
+
+// Verify 校验密码，支持 argon2id 和 bcrypt(兼容从其他系统导入的账号)
+func Verify(password string, encoded string) (ok bool, err error) {
+	if strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$") {
+		err = bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false, ErrInvalidHash
+	}
+	var (
+		version       int
+		memory, time  uint32
+		threads       uint8
+		salt, hashKey []byte
+	)
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, ErrInvalidHash
+	}
+	// 参数为 0 时 argon2 会 panic
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil || time == 0 ||
+		threads == 0 {
+		return false, ErrInvalidHash
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return false, ErrInvalidHash
+	}
+	// 空哈希与任意密码比较都相等
+	if hashKey, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(hashKey) == 0 {
+		return false, ErrInvalidHash
+	}
+	key := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(hashKey)))
+	ok = subtle.ConstantTimeCompare(key, hashKey) == 1
+	return ok, nil
+}
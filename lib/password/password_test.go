@@ -0,0 +1,97 @@
+package password
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// argonHash 按指定参数生成 argon2id 哈希，测试导入的低强度哈希
+func argonHash(password string, salt string, memory uint32, time uint32, threads uint8) string {
+	key := argon2.IDKey([]byte(password), []byte(salt), time, memory, threads, argonKeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, memory, time, threads,
+		base64.RawStdEncoding.EncodeToString([]byte(salt)), base64.RawStdEncoding.EncodeToString(key))
+}
+
+func TestHash(t *testing.T) {
+	encoded, err := Hash("123456")
+	if err != nil {
+		t.Fatalf("Hash() err = %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=65536,t=1,p=2$") {
+		t.Fatalf("Hash() = %s", encoded)
+	}
+	other, _ := Hash("123456")
+	if other == encoded {
+		t.Fatalf("Hash() 两次结果相同，盐没有随机")
+	}
+	for _, tt := range []struct {
+		password string
+		want     bool
+	}{{"123456", true}, {"1234567", false}, {"", false}} {
+		if ok, err := Verify(tt.password, encoded); ok != tt.want || err != nil {
+			t.Fatalf("Verify(%q) = %v %v, want %v", tt.password, ok, err, tt.want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	lowCost := argonHash("123456", "saltsalt", 1024, 2, 1)
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("123456"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt err = %v", err)
+	}
+	parts := strings.Split(lowCost, "$")
+	withParams := func(params string) string {
+		return strings.Join([]string{"", parts[1], parts[2], params, parts[4], parts[5]}, "$")
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		encoded  string
+		want     bool
+		wantErr  error
+	}{
+		{name: "argon2id 按哈希中的参数校验", password: "123456", encoded: lowCost, want: true},
+		{name: "argon2id 密码错误", password: "654321", encoded: lowCost, want: false},
+		{name: "argon2id 参数不一致", password: "123456", encoded: withParams("m=1024,t=1,p=1"), want: false},
+		{name: "bcrypt", password: "123456", encoded: string(bcryptHash), want: true},
+		{name: "bcrypt 密码错误", password: "654321", encoded: string(bcryptHash), want: false},
+		{name: "bcrypt $2y$", password: "123456", encoded: "$2y$" + string(bcryptHash[4:]), want: true},
+		{name: "明文", password: "123456", encoded: "123456", wantErr: ErrInvalidHash},
+		{name: "空", password: "", encoded: "", wantErr: ErrInvalidHash},
+		{name: "argon2i", password: "123456", encoded: strings.Replace(lowCost, "argon2id", "argon2i", 1),
+			wantErr: ErrInvalidHash},
+		{name: "版本不支持", password: "123456", encoded: strings.Replace(lowCost, "v=19", "v=16", 1),
+			wantErr: ErrInvalidHash},
+		{name: "参数格式错误", password: "123456", encoded: withParams("m=1024"), wantErr: ErrInvalidHash},
+		{name: "并行度为 0", password: "123456", encoded: withParams("m=1024,t=1,p=0"), wantErr: ErrInvalidHash},
+		{name: "迭代次数为 0", password: "123456", encoded: withParams("m=1024,t=0,p=1"), wantErr: ErrInvalidHash},
+		{name: "并行度溢出", password: "123456", encoded: withParams("m=1024,t=1,p=256"), wantErr: ErrInvalidHash},
+		{name: "盐不是 base64", password: "123456", encoded: strings.Replace(lowCost, parts[4], "!!", 1),
+			wantErr: ErrInvalidHash},
+		{name: "空哈希", password: "任意密码", encoded: strings.TrimSuffix(lowCost, parts[5]),
+			wantErr: ErrInvalidHash},
+		{name: "段数错误", password: "123456", encoded: lowCost + "$", wantErr: ErrInvalidHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := Verify(tt.password, tt.encoded)
+			if tt.wantErr != nil {
+				if ok || !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Verify() = %v %v, want false %v", ok, err, tt.wantErr)
+				}
+				return
+			}
+			if ok != tt.want || err != nil {
+				t.Fatalf("Verify() = %v %v, want %v", ok, err, tt.want)
+			}
+		})
+	}
+}
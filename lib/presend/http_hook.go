@@ -0,0 +1,72 @@
+// Package presend 消息发送前的同步回调，用于内容审核、改写
+package presend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/link1st/gowebsocket/v2/lib/webhook"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	preSendEvent    = "message.beforeSend"
+	maxResponseSize = 1 << 20 // 读取响应体的最大长度
+)
+
+var httpClient = &http.Client{
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// httpHook HTTP 发送前回调，签名方式和事件回调相同
+// 返回 2xx 且响应体为空时放行，否则响应体为 models.PreSendResult
+type httpHook struct {
+	url    string
+	secret string
+}
+
+// BeforeSend 实现 Hook
+func (h *httpHook) BeforeSend(ctx context.Context, message *models.PreSendMessage) (result *models.PreSendResult,
+	err error) {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", preSendEvent)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	if h.secret != "" {
+		req.Header.Set("X-Webhook-Signature", webhook.Sign(h.secret, timestamp, body))
+	}
+
+	rsp, err := httpClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = rsp.Body.Close()
+	}()
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return nil, fmt.Errorf("http status %d", rsp.StatusCode)
+	}
+	content, err := io.ReadAll(io.LimitReader(rsp.Body, maxResponseSize))
+	if err != nil || len(bytes.TrimSpace(content)) == 0 {
+		return
+	}
+	result = &models.PreSendResult{}
+	err = json.Unmarshal(content, result)
+	return
+}
@@ -0,0 +1,147 @@
+// Package presend 消息发送前的同步回调，用于内容审核、改写
+// 支持程序内注册的 Hook 和 HTTP 回调，全部回调共用一个超时时间，按注册顺序执行，HTTP 回调最后执行
+package presend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/lib/apps"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	defaultTimeout = 300 // 默认超时时间(毫秒)
+)
+
+// Hook 发送前回调，返回 nil 表示放行
+// 需要在 ctx 结束前返回，超时后结果被丢弃
+type Hook interface {
+	BeforeSend(ctx context.Context, message *models.PreSendMessage) (result *models.PreSendResult, err error)
+}
+
+// HookFunc 函数形式的 Hook
+type HookFunc func(ctx context.Context, message *models.PreSendMessage) (result *models.PreSendResult, err error)
+
+// BeforeSend 实现 Hook
+func (f HookFunc) BeforeSend(ctx context.Context, message *models.PreSendMessage) (*models.PreSendResult, error) {
+	return f(ctx, message)
+}
+
+var (
+	hooksRWMutex sync.RWMutex
+	hooks        []Hook
+)
+
+// Register 注册程序内的回调，在启动时调用
+func Register(hook Hook) {
+	hooksRWMutex.Lock()
+	defer hooksRWMutex.Unlock()
+	hooks = append(hooks, hook)
+}
+
+// getHooks 全部需要执行的回调，应用配置了回调地址时使用应用的配置
+func getHooks(appID string) (list []Hook, timeout time.Duration, failClosed bool) {
+	hooksRWMutex.RLock()
+	list = append(list, hooks...)
+	hooksRWMutex.RUnlock()
+
+	milliseconds := viper.GetInt("preSend.timeout")
+	failClosed = viper.GetBool("preSend.failClosed")
+	url, secret := viper.GetString("preSend.url"), viper.GetString("preSend.secret")
+	if app, err := apps.GetApp(appID); err == nil && app != nil && app.PreSend.URL != "" {
+		url, secret = app.PreSend.URL, app.WebhookSecret
+		failClosed = app.PreSend.FailClosed
+		if app.PreSend.Timeout > 0 {
+			milliseconds = app.PreSend.Timeout
+		}
+	}
+	if milliseconds <= 0 {
+		milliseconds = defaultTimeout
+	}
+	if url != "" {
+		list = append(list, &httpHook{url: url, secret: secret})
+	}
+	return list, time.Duration(milliseconds) * time.Millisecond, failClosed
+}
+
+// Run 依次执行发送前回调，返回最终的消息内容
+// 被拒绝时返回回调指定的错误码，回调失败或超时时按 failClosed 配置放行或拒绝
+func Run(message *models.PreSendMessage) (content string, code uint32, msg string) {
+	list, timeout, failClosed := getHooks(message.AppID)
+	if len(list) == 0 {
+		return message.Content, common.OK, ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for _, hook := range list {
+		result, err := call(ctx, hook, *message)
+		if err != nil {
+			fmt.Println("发送前回调 失败", message.AppID, message.FromUserID, message.ToUserID, err)
+			if failClosed {
+				return "", common.MessageRejected, "消息审核服务不可用"
+			}
+			continue
+		}
+		if result == nil {
+			continue
+		}
+		switch result.Action {
+		case models.PreSendActionReject:
+			code = result.Code
+			if code == 0 || code == common.OK {
+				code = common.MessageRejected
+			}
+			fmt.Println("发送前回调 拒绝发送", message.AppID, message.FromUserID, message.ToUserID, code, result.Msg)
+			return "", code, result.Msg
+		case models.PreSendActionReplace:
+			if message.MessageType != models.MessageTypeText {
+				fmt.Println("发送前回调 只能替换文本消息", message.AppID, message.MessageType)
+				continue
+			}
+			message.Content = result.Content
+		}
+	}
+	return message.Content, common.OK, ""
+}
+
+// call 执行一个回调，超时后立即返回，不等待回调结束
+// 传入消息的副本，回调修改消息不会影响发送的内容
+func call(ctx context.Context, hook Hook, message models.PreSendMessage) (result *models.PreSendResult, err error) {
+	type response struct {
+		result *models.PreSendResult
+		err    error
+	}
+	ch := make(chan response, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				ch <- response{err: fmt.Errorf("panic: %v", r)}
+			}
+		}()
+		result, err := hook.BeforeSend(ctx, &message)
+		ch <- response{result: result, err: err}
+	}()
+
+	select {
+	case rsp := <-ch:
+		if rsp.err == nil && rsp.result != nil && !isValidAction(rsp.result.Action) {
+			return nil, errors.New("无效的处理结果: " + rsp.result.Action)
+		}
+		return rsp.result, rsp.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func isValidAction(action string) bool {
+	return action == models.PreSendActionAllow || action == models.PreSendActionReject ||
+		action == models.PreSendActionReplace
+}
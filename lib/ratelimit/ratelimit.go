@@ -0,0 +1,103 @@
+// Package ratelimit 基于 redis 令牌桶的限流
+// 策略读取配置 rateLimit.ws / rateLimit.http，应用可以通过 App.RateLimits 按接口、维度覆盖
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/spf13/viper"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/lib/apps"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	KindWS   = "ws"   // WebSocket 命令
+	KindHTTP = "http" // HTTP 接口
+)
+
+// Subject 限流对象，ID 为空的对象不参与限流
+type Subject struct {
+	Dimension string // 维度 models.RateLimitUser 等
+	ID        string
+}
+
+var (
+	configOnce   sync.Once
+	configLimits *models.RateLimits
+)
+
+// loadConfig 加载配置文件中的限流策略
+func loadConfig() {
+	configLimits = &models.RateLimits{}
+	if err := viper.UnmarshalKey("rateLimit", configLimits); err != nil {
+		fmt.Println("读取限流配置失败", err)
+	}
+}
+
+// getPolicy 接口的限流策略，应用配置覆盖同一维度的全局配置
+func getPolicy(kind string, action string, appID string) (policy models.RateLimitPolicy) {
+	configOnce.Do(loadConfig)
+	policy = make(models.RateLimitPolicy)
+	for dimension, limit := range configLimits.GetPolicy(kind, action) {
+		policy[dimension] = limit
+	}
+	if appID == "" {
+		return
+	}
+	app, err := apps.GetApp(appID)
+	if err != nil || app == nil {
+		return
+	}
+	for dimension, limit := range app.RateLimits.GetPolicy(kind, action) {
+		policy[dimension] = limit
+	}
+	return
+}
+
+// getBucketKey 令牌桶的 key，应用和凭证本身属于某个应用，其他维度的ID在各应用间可能重复，需要按 appID 隔离
+func getBucketKey(kind string, action string, appID string, subject Subject) (key string) {
+	if subject.Dimension == models.RateLimitApp || subject.Dimension == models.RateLimitCredential {
+		key = fmt.Sprintf("%s.%s:%s:%s", kind, action, subject.Dimension, subject.ID)
+		return
+	}
+	key = fmt.Sprintf("%s.%s:%s:%s:%s", kind, action, subject.Dimension, appID, subject.ID)
+	return
+}
+
+// Check 检查是否超过限制，超过时返回 common.TooManyRequests 和需要等待的秒数
+// redis 不可用时默认放行，配置 rateLimit.failClosed 后拒绝
+func Check(kind string, action string, appID string, subjects ...Subject) (code uint32, retryAfter int64) {
+	policy := getPolicy(kind, action, appID)
+	if len(policy) == 0 {
+		return common.OK, 0
+	}
+
+	buckets := make([]cache.RateLimitBucket, 0, len(subjects))
+	for _, subject := range subjects {
+		limit, ok := policy[subject.Dimension]
+		if subject.ID == "" || !ok || !limit.IsEnabled() {
+			continue
+		}
+		buckets = append(buckets, cache.RateLimitBucket{
+			Key:   getBucketKey(kind, action, appID, subject),
+			Rate:  limit.GetRate(),
+			Burst: limit.GetBurst(),
+		})
+	}
+	wait, err := cache.TakeRateLimitTokens(buckets)
+	if err != nil {
+		if viper.GetBool("rateLimit.failClosed") {
+			return common.ServerError, 0
+		}
+		return common.OK, 0
+	}
+	if wait > 0 {
+		return common.TooManyRequests, int64(math.Ceil(wait.Seconds()))
+	}
+	return common.OK, 0
+}
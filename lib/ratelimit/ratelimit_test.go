@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"testing"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+// 没有需要检查的令牌桶时不访问 redis
+func TestCheckSkip(t *testing.T) {
+	configOnce.Do(func() {})
+	configLimits = &models.RateLimits{
+		WS: map[string]models.RateLimitPolicy{
+			"sendMessage": {
+				models.RateLimitUser:       {Limit: 10},
+				models.RateLimitConnection: {Limit: 0},
+			},
+		},
+	}
+	tests := []struct {
+		name     string
+		action   string
+		subjects []Subject
+	}{
+		{name: "接口未配置", action: "heartbeat", subjects: []Subject{{Dimension: models.RateLimitUser, ID: "1"}}},
+		{name: "没有限流对象", action: "sendMessage", subjects: nil},
+		{name: "限流对象ID为空", action: "sendMessage", subjects: []Subject{{Dimension: models.RateLimitUser}}},
+		{name: "维度未配置", action: "sendMessage", subjects: []Subject{{Dimension: models.RateLimitIP, ID: "1"}}},
+		{name: "limit 为 0 不限制", action: "sendMessage",
+			subjects: []Subject{{Dimension: models.RateLimitConnection, ID: "1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, retryAfter := Check(KindWS, tt.action, "", tt.subjects...)
+			if code != common.OK || retryAfter != 0 {
+				t.Fatalf("Check() = %d %d, want %d 0", code, retryAfter, common.OK)
+			}
+		})
+	}
+}
+
+func TestGetBucketKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    string
+		action  string
+		appID   string
+		subject Subject
+		want    string
+	}{
+		{name: "用户按应用隔离", kind: KindWS, action: "sendMessage", appID: "101",
+			subject: Subject{Dimension: models.RateLimitUser, ID: "1"}, want: "ws.sendMessage:user:101:1"},
+		{name: "不同应用的同一用户", kind: KindWS, action: "sendMessage", appID: "102",
+			subject: Subject{Dimension: models.RateLimitUser, ID: "1"}, want: "ws.sendMessage:user:102:1"},
+		{name: "IP按应用隔离", kind: KindHTTP, action: "messageSend", appID: "101",
+			subject: Subject{Dimension: models.RateLimitIP, ID: "127.0.0.1"},
+			want:    "http.messageSend:ip:101:127.0.0.1"},
+		{name: "未登录的IP", kind: KindHTTP, action: "login", appID: "",
+			subject: Subject{Dimension: models.RateLimitIP, ID: "127.0.0.1"}, want: "http.login:ip::127.0.0.1"},
+		{name: "连接", kind: KindWS, action: "sendMessage", appID: "101",
+			subject: Subject{Dimension: models.RateLimitConnection, ID: "c1"},
+			want:    "ws.sendMessage:connection:101:c1"},
+		{name: "应用", kind: KindWS, action: "sendMessage", appID: "101",
+			subject: Subject{Dimension: models.RateLimitApp, ID: "101"}, want: "ws.sendMessage:app:101"},
+		{name: "凭证", kind: KindHTTP, action: "sendMessageAll", appID: "101",
+			subject: Subject{Dimension: models.RateLimitCredential, ID: "key1"},
+			want:    "http.sendMessageAll:credential:key1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getBucketKey(tt.kind, tt.action, tt.appID, tt.subject); got != tt.want {
+				t.Fatalf("getBucketKey() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+// Package search 全文检索(分词、高亮)
+package search
+
+import (
+	"strings"
+)
+
+const (
+	// HighlightPre 高亮开始标记
+	HighlightPre = "<em>"
+	// HighlightPost 高亮结束标记
+	HighlightPost = "</em>"
+
+	snippetContext = 20 // 摘要中命中词前后保留的字符数
+)
+
+// Highlight 用 <em></em> 标记命中的关键词，内容较长时截取第一个命中位置附近的摘要
+func Highlight(content string, keywords []string) (highlight string) {
+	runes := []rune(content)
+	lower := []rune(strings.ToLower(content))
+	if len(lower) != len(runes) {
+		// 大小写转换改变了长度，退化为原文匹配
+		lower = runes
+	}
+
+	// 标记命中区间
+	marks := make([]bool, len(runes))
+	first := -1
+	for _, keyword := range keywords {
+		key := []rune(keyword)
+		if len(key) == 0 {
+			continue
+		}
+		for i := 0; i+len(key) <= len(lower); i++ {
+			if string(lower[i:i+len(key)]) != keyword {
+				continue
+			}
+			for j := i; j < i+len(key); j++ {
+				marks[j] = true
+			}
+			if first == -1 || i < first {
+				first = i
+			}
+		}
+	}
+
+	start, end := 0, len(runes)
+	if first > snippetContext {
+		start = first - snippetContext
+	}
+	if first >= 0 && end-start > snippetContext*4 {
+		end = start + snippetContext*4
+	}
+
+	builder := strings.Builder{}
+	if start > 0 {
+		builder.WriteString("...")
+	}
+	for i := start; i < end; i++ {
+		if marks[i] && (i == start || !marks[i-1]) {
+			builder.WriteString(HighlightPre)
+		}
+		builder.WriteRune(runes[i])
+		if marks[i] && (i+1 == end || !marks[i+1]) {
+			builder.WriteString(HighlightPost)
+		}
+	}
+	if end < len(runes) {
+		builder.WriteString("...")
+	}
+	highlight = builder.String()
+	return
+}
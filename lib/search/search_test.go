@@ -0,0 +1,105 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "空", text: "", want: nil},
+		{name: "英文按词并转小写", text: "Hello, World hello", want: []string{"hello", "world"}},
+		{name: "数字", text: "room 101", want: []string{"room", "101"}},
+		{name: "中文单字和二元组", text: "你好吗", want: []string{"你", "你好", "好", "好吗", "吗"}},
+		{name: "单个汉字", text: "好", want: []string{"好"}},
+		{name: "中英混合", text: "买iPhone手机", want: []string{"买", "iphone", "手", "手机", "机"}},
+		{name: "标点分隔", text: "明天，见", want: []string{"明", "明天", "天", "见"}},
+		{name: "日文", text: "ひらがな", want: []string{"ひ", "ひら", "ら", "らが", "が", "がな", "な"}},
+		{name: "韩文", text: "한국", want: []string{"한", "한국", "국"}},
+		{name: "超长单词截断", text: strings.Repeat("a", maxTermLen+8), want: []string{strings.Repeat("a", maxTermLen)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryTerms(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		wantTerms    []string
+		wantKeywords []string
+	}{
+		{name: "空", query: "  ", wantTerms: nil, wantKeywords: nil},
+		{name: "单个汉字查单字", query: "好", wantTerms: []string{"好"}, wantKeywords: []string{"好"}},
+		{name: "多字查二元组", query: "你好吗", wantTerms: []string{"你好", "好吗"}, wantKeywords: []string{"你好吗"}},
+		{name: "英文", query: "Go Lang", wantTerms: []string{"go", "lang"}, wantKeywords: []string{"go", "lang"}},
+		{name: "重复词去重", query: "go go", wantTerms: []string{"go"}, wantKeywords: []string{"go", "go"}},
+		{name: "中英混合", query: "明天meeting", wantTerms: []string{"明天", "meeting"},
+			wantKeywords: []string{"明天", "meeting"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terms, keywords := QueryTerms(tt.query)
+			if !reflect.DeepEqual(terms, tt.wantTerms) || !reflect.DeepEqual(keywords, tt.wantKeywords) {
+				t.Fatalf("QueryTerms(%q) = %q %q, want %q %q", tt.query, terms, keywords, tt.wantTerms,
+					tt.wantKeywords)
+			}
+		})
+	}
+}
+
+// 查询词都是索引词的子集，保证建索引和查询的分词一致
+func TestQueryTermsIndexed(t *testing.T) {
+	content := "周五下午三点开会 Meeting at 3pm"
+	indexed := make(map[string]bool)
+	for _, term := range Tokenize(content) {
+		indexed[term] = true
+	}
+	for _, query := range []string{"开会", "下午三点", "meeting", "3pm", "五"} {
+		terms, _ := QueryTerms(query)
+		for _, term := range terms {
+			if !indexed[term] {
+				t.Fatalf("查询 %q 的词 %q 没有被索引", query, term)
+			}
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	long := strings.Repeat("一", 30) + "关键词" + strings.Repeat("二", 100)
+	tests := []struct {
+		name     string
+		content  string
+		keywords []string
+		want     string
+	}{
+		{name: "没有关键词", content: "你好", keywords: nil, want: "你好"},
+		{name: "没有命中", content: "你好", keywords: []string{"再见"}, want: "你好"},
+		{name: "中文命中", content: "明天开会", keywords: []string{"开会"}, want: "明天<em>开会</em>"},
+		{name: "忽略大小写并保留原文", content: "Hello World", keywords: []string{"world"},
+			want: "Hello <em>World</em>"},
+		{name: "多次命中", content: "go and go", keywords: []string{"go"}, want: "<em>go</em> and <em>go</em>"},
+		{name: "相邻命中合并", content: "ab", keywords: []string{"a", "b"}, want: "<em>ab</em>"},
+		{name: "重叠命中合并", content: "abc", keywords: []string{"ab", "bc"}, want: "<em>abc</em>"},
+		{name: "空关键词忽略", content: "abc", keywords: []string{""}, want: "abc"},
+		{name: "长内容截取摘要", content: long, keywords: []string{"关键词"},
+			want: "..." + strings.Repeat("一", 20) + "<em>关键词</em>" + strings.Repeat("二", 57) + "..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Highlight(tt.content, tt.keywords); got != tt.want {
+				t.Fatalf("Highlight(%q, %q) = %q, want %q", tt.content, tt.keywords, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// Package search 全文检索(分词、高亮)
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	maxTermLen = 32 // 单个词最大长度(字符)
+)
+
+// segment 文本片段
+type segment struct {
+	text []rune
+	cjk  bool
+}
+
+// isCJK 是否为中日韩文字，按字切分
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
+
+// split 按字符类型切分为连续片段，标点和空白作为分隔符
+func split(text string) (segments []segment) {
+	var current []rune
+	currentCJK := false
+	flush := func() {
+		if len(current) > 0 {
+			segments = append(segments, segment{text: current, cjk: currentCJK})
+			current = nil
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case isCJK(r):
+			if !currentCJK {
+				flush()
+			}
+			currentCJK = true
+			current = append(current, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if currentCJK {
+				flush()
+			}
+			currentCJK = false
+			current = append(current, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return
+}
+
+// Tokenize 建索引分词：英文数字按词，中日韩文字输出单字和二元组
+func Tokenize(text string) (terms []string) {
+	seen := make(map[string]bool)
+	add := func(term string) {
+		if term != "" && !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	for _, seg := range split(text) {
+		if !seg.cjk {
+			add(truncate(seg.text))
+			continue
+		}
+		for i := range seg.text {
+			add(string(seg.text[i]))
+			if i+1 < len(seg.text) {
+				add(string(seg.text[i : i+2]))
+			}
+		}
+	}
+	return
+}
+
+// QueryTerms 查询分词：中日韩文字单字查单字，多字查二元组，全部命中才算匹配
+func QueryTerms(query string) (terms []string, keywords []string) {
+	seen := make(map[string]bool)
+	add := func(term string) {
+		if term != "" && !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	for _, seg := range split(query) {
+		keywords = append(keywords, string(seg.text))
+		if !seg.cjk {
+			add(truncate(seg.text))
+			continue
+		}
+		if len(seg.text) == 1 {
+			add(string(seg.text))
+			continue
+		}
+		for i := 0; i+1 < len(seg.text); i++ {
+			add(string(seg.text[i : i+2]))
+		}
+	}
+	return
+}
+
+func truncate(text []rune) string {
+	if len(text) > maxTermLen {
+		text = text[:maxTermLen]
+	}
+	return string(text)
+}
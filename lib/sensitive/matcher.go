@@ -0,0 +1,130 @@
+// Package sensitive 敏感词过滤
+package sensitive
+
+import (
+	"unicode"
+)
+
+// node Aho-Corasick 自动机节点
+type node struct {
+	children map[rune]int32
+	fail     int32
+	length   int32 // 以该节点结尾的词的长度(字符)，0 表示不是词尾
+	next     int32 // 沿失败指针找到的下一个词尾节点
+}
+
+// Matcher 多模式匹配，一次扫描找出全部命中的词
+type Matcher struct {
+	nodes []node
+	count int
+}
+
+// match 命中的位置 [start, end)，单位为字符
+type match struct {
+	start int
+	end   int
+}
+
+// normalize 统一大小写和全角字符，避免 Ａ、a 这类写法绕过
+func normalize(r rune) rune {
+	if r >= 0xFF01 && r <= 0xFF5E {
+		r -= 0xFEE0
+	} else if r == 0x3000 {
+		r = ' '
+	}
+	return unicode.ToLower(r)
+}
+
+// NewMatcher 构建自动机
+func NewMatcher(words []string) (m *Matcher) {
+	m = &Matcher{nodes: []node{{}}}
+	for _, word := range words {
+		m.add(word)
+	}
+	m.build()
+	return
+}
+
+// Len 词的数量
+func (m *Matcher) Len() int {
+	return m.count
+}
+
+func (m *Matcher) add(word string) {
+	current := int32(0)
+	length := int32(0)
+	for _, r := range word {
+		r = normalize(r)
+		if m.nodes[current].children == nil {
+			m.nodes[current].children = make(map[rune]int32)
+		}
+		child, ok := m.nodes[current].children[r]
+		if !ok {
+			m.nodes = append(m.nodes, node{})
+			child = int32(len(m.nodes) - 1)
+			m.nodes[current].children[r] = child
+		}
+		current = child
+		length++
+	}
+	if current != 0 && m.nodes[current].length == 0 {
+		m.nodes[current].length = length
+		m.count++
+	}
+}
+
+// build 按层计算失败指针
+func (m *Matcher) build() {
+	queue := make([]int32, 0, len(m.nodes))
+	for _, child := range m.nodes[0].children {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[current].children {
+			fail := m.nodes[current].fail
+			for fail != 0 && !m.hasChild(fail, r) {
+				fail = m.nodes[fail].fail
+			}
+			if target, ok := m.nodes[fail].children[r]; ok && target != child {
+				m.nodes[child].fail = target
+			}
+			failNode := m.nodes[m.nodes[child].fail]
+			if failNode.length > 0 {
+				m.nodes[child].next = m.nodes[child].fail
+			} else {
+				m.nodes[child].next = failNode.next
+			}
+			queue = append(queue, child)
+		}
+	}
+}
+
+func (m *Matcher) hasChild(n int32, r rune) bool {
+	_, ok := m.nodes[n].children[r]
+	return ok
+}
+
+// find 找出全部命中的位置，包括互相重叠的词
+func (m *Matcher) find(text []rune) (matches []match) {
+	current := int32(0)
+	for i, r := range text {
+		r = normalize(r)
+		for current != 0 && !m.hasChild(current, r) {
+			current = m.nodes[current].fail
+		}
+		if child, ok := m.nodes[current].children[r]; ok {
+			current = child
+		}
+		n := current
+		if m.nodes[n].length == 0 {
+			n = m.nodes[n].next
+		}
+		for n != 0 {
+			matches = append(matches, match{start: i + 1 - int(m.nodes[n].length), end: i + 1})
+			n = m.nodes[n].next
+		}
+	}
+	return
+}
@@ -0,0 +1,128 @@
+package sensitive
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+func TestMatcherFind(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []string
+		text  string
+		want  []match
+	}{
+		{name: "没有词", words: nil, text: "你好", want: nil},
+		{name: "没有命中", words: []string{"坏蛋"}, text: "你好世界", want: nil},
+		{name: "单个命中", words: []string{"坏蛋"}, text: "你是坏蛋", want: []match{{2, 4}}},
+		{name: "多次命中", words: []string{"ab"}, text: "abxab", want: []match{{0, 2}, {3, 5}}},
+		{name: "前缀与完整词", words: []string{"he", "hers"}, text: "hers", want: []match{{0, 2}, {0, 4}}},
+		{name: "后缀重叠", words: []string{"she", "he"}, text: "ushe", want: []match{{1, 4}, {2, 4}}},
+		{name: "经典用例", words: []string{"he", "she", "his", "hers"}, text: "ahishers",
+			want: []match{{1, 4}, {3, 6}, {4, 6}, {4, 8}}},
+		{name: "失败指针回退", words: []string{"abcd", "bc"}, text: "abce", want: []match{{1, 3}}},
+		{name: "连续重叠", words: []string{"aa"}, text: "aaaa", want: []match{{0, 2}, {1, 3}, {2, 4}}},
+		{name: "大小写", words: []string{"Bad"}, text: "BAD bad", want: []match{{0, 3}, {4, 7}}},
+		{name: "全角字符", words: []string{"ab"}, text: "ＡＢ", want: []match{{0, 2}}},
+		{name: "全角空格", words: []string{"a b"}, text: "a　b", want: []match{{0, 3}}},
+		{name: "中英混合", words: []string{"卖v"}, text: "出售卖Ｖ号", want: []match{{2, 4}}},
+		{name: "空词忽略", words: []string{"", "x"}, text: "x", want: []match{{0, 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewMatcher(tt.words).find([]rune(tt.text))
+			sort.Slice(got, func(i, j int) bool {
+				if got[i].start != got[j].start {
+					return got[i].start < got[j].start
+				}
+				return got[i].end < got[j].end
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("find(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatcherLen(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []string
+		want  int
+	}{
+		{name: "空", words: nil, want: 0},
+		{name: "去重", words: []string{"a", "A", "ａ"}, want: 1},
+		{name: "前缀", words: []string{"ab", "abc", "b"}, want: 3},
+		{name: "空词", words: []string{""}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewMatcher(tt.words).Len(); got != tt.want {
+				t.Fatalf("Len() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	newSet := func(name, action string, scopes []string, words ...string) *ruleSet {
+		set := &ruleSet{
+			config:   models.SensitiveRuleSet{Name: name, Action: action},
+			scopes:   make(map[string]bool),
+			matcher:  NewMatcher(words),
+			loadedAt: time.Now(),
+		}
+		for _, scope := range scopes {
+			set.scopes[scope] = true
+		}
+		return set
+	}
+	ruleSetsRWMutex.Lock()
+	ruleSets = []*ruleSet{
+		newSet("mask", models.SensitiveActionMask, nil, "笨蛋", "fool"),
+		newSet("review", models.SensitiveActionReview, []string{models.SensitiveScopeNickname}, "管理员"),
+		newSet("reject", models.SensitiveActionReject, []string{models.SensitiveScopeMessage}, "赌博"),
+	}
+	ruleSetsRWMutex.Unlock()
+	defer func() {
+		ruleSetsRWMutex.Lock()
+		ruleSets = nil
+		ruleSetsRWMutex.Unlock()
+	}()
+
+	tests := []struct {
+		name       string
+		scope      string
+		text       string
+		wantText   string
+		wantAction string
+		wantWords  []string
+	}{
+		{name: "空内容", scope: models.SensitiveScopeMessage, text: "", wantText: ""},
+		{name: "没有命中", scope: models.SensitiveScopeMessage, text: "你好", wantText: "你好"},
+		{name: "打码", scope: models.SensitiveScopeMessage, text: "你这个笨蛋", wantText: "你这个**",
+			wantAction: models.SensitiveActionMask, wantWords: []string{"笨蛋"}},
+		{name: "大小写打码保留长度", scope: models.SensitiveScopeMessage, text: "FOOL!", wantText: "****!",
+			wantAction: models.SensitiveActionMask, wantWords: []string{"FOOL"}},
+		{name: "取最严重的处理方式", scope: models.SensitiveScopeMessage, text: "笨蛋赌博", wantText: "**赌博",
+			wantAction: models.SensitiveActionReject, wantWords: []string{"笨蛋", "赌博"}},
+		{name: "规则集限定范围", scope: models.SensitiveScopeMessage, text: "我是管理员", wantText: "我是管理员"},
+		{name: "昵称审核", scope: models.SensitiveScopeNickname, text: "管理员", wantText: "管理员",
+			wantAction: models.SensitiveActionReview, wantWords: []string{"管理员"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Check(tt.scope, tt.text)
+			sort.Strings(result.Words)
+			if result.Text != tt.wantText || result.Action != tt.wantAction ||
+				!reflect.DeepEqual(result.Words, tt.wantWords) {
+				t.Fatalf("Check(%q) = %q %q %v, want %q %q %v", tt.text, result.Text, result.Action, result.Words,
+					tt.wantText, tt.wantAction, tt.wantWords)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+// Package sensitive 敏感词过滤
+// 规则集读取配置 sensitive.ruleSets，每个规则集有自己的词库和处理方式(打码、人工审核、拒绝)
+// 词库文件修改后由定时任务自动重新加载，也可以通过管理接口立即重新加载
+package sensitive
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/helper"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/lib/presend"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	defaultMask = '*'
+)
+
+// ruleSet 加载后的规则集
+type ruleSet struct {
+	config   models.SensitiveRuleSet
+	scopes   map[string]bool
+	matcher  *Matcher
+	modTime  time.Time // 词库文件的修改时间
+	loadedAt time.Time
+}
+
+// RuleSetInfo 规则集状态，供管理接口查看
+type RuleSetInfo struct {
+	Name      string   `json:"name"`
+	Action    string   `json:"action"`
+	Scopes    []string `json:"scopes"`
+	File      string   `json:"file"`
+	WordCount int      `json:"wordCount"`
+	LoadedAt  int64    `json:"loadedAt"`
+}
+
+// Result 检查结果
+type Result struct {
+	Text     string   // 打码后的内容
+	Action   string   // 命中的最严重的处理方式，为空表示没有命中
+	Words    []string // 命中的词
+	RuleSets []string // 命中的规则集
+}
+
+var (
+	ruleSetsRWMutex sync.RWMutex
+	ruleSets        []*ruleSet
+	mask            = defaultMask
+)
+
+// Init 加载规则集，并注册为发送前回调过滤文本消息
+func Init() {
+	if value := []rune(viper.GetString("sensitive.mask")); len(value) > 0 {
+		mask = value[0]
+	}
+	if err := Reload(); err != nil {
+		fmt.Println("加载敏感词失败", err)
+	}
+	presend.Register(presend.HookFunc(beforeSend))
+}
+
+// Reload 重新读取配置和词库文件
+func Reload() (err error) {
+	var configs []models.SensitiveRuleSet
+	if err = viper.UnmarshalKey("sensitive.ruleSets", &configs); err != nil {
+		return
+	}
+	list := make([]*ruleSet, 0, len(configs))
+	for _, config := range configs {
+		if models.SensitiveSeverity(config.Action) == 0 {
+			fmt.Println("敏感词规则集 处理方式无效，已忽略", config.Name, config.Action)
+			continue
+		}
+		set, err := loadRuleSet(config)
+		if err != nil {
+			return err
+		}
+		list = append(list, set)
+	}
+
+	ruleSetsRWMutex.Lock()
+	ruleSets = list
+	ruleSetsRWMutex.Unlock()
+	return
+}
+
+// loadRuleSet 读取规则集的词库
+func loadRuleSet(config models.SensitiveRuleSet) (set *ruleSet, err error) {
+	set = &ruleSet{
+		config:   config,
+		scopes:   make(map[string]bool),
+		loadedAt: time.Now(),
+	}
+	for _, scope := range config.Scopes {
+		set.scopes[scope] = true
+	}
+	words := make([]string, 0, len(config.Words))
+	for _, word := range config.Words {
+		if word = strings.TrimSpace(word); word != "" {
+			words = append(words, word)
+		}
+	}
+	if config.File != "" {
+		var fileWords []string
+		fileWords, set.modTime, err = readWords(config.File)
+		if err != nil {
+			return nil, fmt.Errorf("读取词库 %s 失败: %w", config.File, err)
+		}
+		words = append(words, fileWords...)
+	}
+	set.matcher = NewMatcher(words)
+	fmt.Println("加载敏感词规则集", config.Name, config.Action, set.matcher.Len())
+	return
+}
+
+// readWords 读取词库文件，每行一个词，忽略空行和 # 开头的注释
+func readWords(file string) (words []string, modTime time.Time, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	stat, err := f.Stat()
+	if err != nil {
+		return
+	}
+	modTime = stat.ModTime()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		words = append(words, word)
+	}
+	err = scanner.Err()
+	return
+}
+
+// CheckReload 词库文件有修改时重新加载，由定时任务调用
+func CheckReload() {
+	ruleSetsRWMutex.RLock()
+	changed := false
+	for _, set := range ruleSets {
+		if set.config.File == "" {
+			continue
+		}
+		stat, err := os.Stat(set.config.File)
+		if err == nil && !stat.ModTime().Equal(set.modTime) {
+			changed = true
+			break
+		}
+	}
+	ruleSetsRWMutex.RUnlock()
+	if !changed {
+		return
+	}
+	if err := Reload(); err != nil {
+		// 保留之前加载的词库
+		fmt.Println("重新加载敏感词失败", err)
+	}
+}
+
+// GetRuleSets 当前生效的规则集
+func GetRuleSets() (list []*RuleSetInfo) {
+	ruleSetsRWMutex.RLock()
+	defer ruleSetsRWMutex.RUnlock()
+	list = make([]*RuleSetInfo, 0, len(ruleSets))
+	for _, set := range ruleSets {
+		scopes := set.config.Scopes
+		if scopes == nil {
+			scopes = []string{}
+		}
+		list = append(list, &RuleSetInfo{
+			Name:      set.config.Name,
+			Action:    set.config.Action,
+			Scopes:    scopes,
+			File:      set.config.File,
+			WordCount: set.matcher.Len(),
+			LoadedAt:  set.loadedAt.Unix(),
+		})
+	}
+	return
+}
+
+// Check 检查内容，返回打码后的内容和命中的最严重的处理方式
+// 只有处理方式为 mask 的规则集命中的词会被打码
+func Check(scope string, text string) (result *Result) {
+	result = &Result{Text: text}
+	if text == "" {
+		return
+	}
+	// 全部规则集都在原文上匹配，打码不影响其他规则集
+	runes := []rune(text)
+	output := []rune(text)
+	masked := false
+	words := make(map[string]bool)
+
+	ruleSetsRWMutex.RLock()
+	defer ruleSetsRWMutex.RUnlock()
+	for _, set := range ruleSets {
+		if len(set.scopes) > 0 && !set.scopes[scope] {
+			continue
+		}
+		matches := set.matcher.find(runes)
+		if len(matches) == 0 {
+			continue
+		}
+		result.RuleSets = append(result.RuleSets, set.config.Name)
+		if models.SensitiveSeverity(set.config.Action) > models.SensitiveSeverity(result.Action) {
+			result.Action = set.config.Action
+		}
+		for _, m := range matches {
+			words[string(runes[m.start:m.end])] = true
+		}
+		if set.config.Action == models.SensitiveActionMask {
+			for _, m := range matches {
+				for i := m.start; i < m.end; i++ {
+					output[i] = mask
+				}
+			}
+			masked = true
+		}
+	}
+	for word := range words {
+		result.Words = append(result.Words, word)
+	}
+	if masked {
+		result.Text = string(output)
+	}
+	return
+}
+
+// Filter 检查内容并按规则集处理: 拒绝时返回 common.SensitiveContent，需要审核时加入审核队列，返回打码后的内容
+// userID 为提交内容的用户，targetID 为消息接收人或分组ID
+func Filter(scope string, appID string, userID string, targetID string, text string) (content string, code uint32) {
+	result := Check(scope, text)
+	switch result.Action {
+	case models.SensitiveActionReject:
+		fmt.Println("敏感词 拒绝", scope, appID, userID, result.RuleSets, result.Words)
+		return "", common.SensitiveContent
+	case models.SensitiveActionReview:
+		_ = cache.AddReviewItem(&models.ReviewItem{
+			ReviewID:  helper.GetRandomID(16),
+			AppID:     appID,
+			Scope:     scope,
+			UserID:    userID,
+			TargetID:  targetID,
+			Content:   result.Text,
+			Words:     result.Words,
+			RuleSets:  result.RuleSets,
+			Status:    models.ReviewStatusPending,
+			CreatedAt: time.Now().Unix(),
+		})
+	}
+	return result.Text, common.OK
+}
+
+// beforeSend 过滤文本消息
+func beforeSend(ctx context.Context, message *models.PreSendMessage) (result *models.PreSendResult, err error) {
+	if message.MessageType != models.MessageTypeText {
+		return nil, nil
+	}
+	content, code := Filter(models.SensitiveScopeMessage, message.AppID, message.FromUserID, message.ToUserID,
+		message.Content)
+	if code != common.OK {
+		return &models.PreSendResult{Action: models.PreSendActionReject, Code: code}, nil
+	}
+	if content != message.Content {
+		return &models.PreSendResult{Action: models.PreSendActionReplace, Content: content}, nil
+	}
+	return nil, nil
+}
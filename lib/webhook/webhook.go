@@ -0,0 +1,229 @@
+// Package webhook 向应用配置的地址推送 IM 事件
+// 事件先写入 redis 投递队列，由定时任务异步投递，失败按指数退避重试，超过重试次数后移入死信队列，可以通过管理接口重放
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/link1st/gowebsocket/v2/helper"
+	"github.com/link1st/gowebsocket/v2/lib/apps"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	defaultTimeout        = 5       // 默认单次请求超时时间(秒)
+	defaultMaxAttempts    = 8       // 默认最多投递次数
+	defaultRetryBaseDelay = 10      // 默认第一次重试的间隔(秒)，之后每次翻倍
+	defaultRetryMaxDelay  = 3600    // 默认最长重试间隔(秒)
+	defaultDeadTTL        = 604800  // 默认死信保存时间(秒)，7天
+	defaultConcurrency    = 10      // 默认每个节点同时投递的数量
+	defaultBatchSize      = 100     // 默认每次领取的数量
+	maxErrorLength        = 512     // 记录的错误信息最大长度
+	maxResponseSize       = 4 << 10 // 读取响应体的最大长度
+)
+
+var (
+	clientOnce sync.Once
+	httpClient *http.Client
+)
+
+func getConfigInt(key string, defaultValue int) (value int) {
+	value = viper.GetInt(key)
+	if value <= 0 {
+		value = defaultValue
+	}
+	return
+}
+
+// getTimeout 单次请求超时时间
+func getTimeout() (timeout time.Duration) {
+	return time.Duration(getConfigInt("webhook.timeout", defaultTimeout)) * time.Second
+}
+
+// getHTTPClient 回调使用的 http 客户端，不跟随重定向
+func getHTTPClient() *http.Client {
+	clientOnce.Do(func() {
+		httpClient = &http.Client{
+			Timeout: getTimeout(),
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	})
+	return httpClient
+}
+
+// Emit 产生一个事件，应用订阅了该事件时按回调地址加入投递队列
+// 不会阻塞调用方，失败只记录日志
+func Emit(appID string, event string, data interface{}) {
+	app, err := apps.GetApp(appID)
+	if err != nil || app == nil || !app.IsWebhookEventEnabled(event) {
+		return
+	}
+
+	now := time.Now()
+	webhookEvent := &models.WebhookEvent{
+		EventID: helper.GetRandomID(16),
+		AppID:   appID,
+		Event:   event,
+		Time:    now.UnixMilli(),
+		Data:    data,
+	}
+	payload, err := json.Marshal(webhookEvent)
+	if err != nil {
+		fmt.Println("回调事件序列化失败", appID, event, err)
+		return
+	}
+	deliveries := make([]*models.WebhookDelivery, 0, len(app.WebhookURLs))
+	for _, url := range app.WebhookURLs {
+		deliveries = append(deliveries, &models.WebhookDelivery{
+			DeliveryID: helper.GetRandomID(16),
+			EventID:    webhookEvent.EventID,
+			AppID:      appID,
+			Event:      event,
+			URL:        url,
+			Payload:    string(payload),
+			NextAt:     now.Unix(),
+			CreatedAt:  now.Unix(),
+		})
+	}
+	_ = cache.EnqueueWebhookDeliveries(deliveries)
+}
+
+// Sign 回调签名: hex(HMAC-SHA256(secret, timestamp + "\n" + body))
+func Sign(secret string, timestamp string, body []byte) (signature string) {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// getRetryDelay 第 attempts 次投递失败后的重试间隔
+func getRetryDelay(attempts int) (delay int64) {
+	delay = int64(getConfigInt("webhook.retryBaseDelay", defaultRetryBaseDelay))
+	maxDelay := int64(getConfigInt("webhook.retryMaxDelay", defaultRetryMaxDelay))
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return
+}
+
+// Deliver 投递到期的回调，由定时任务调用，投递完本批次后返回
+func Deliver() {
+	// 租约要比单次请求时间长，避免还在投递时被其他节点重复领取
+	lease := getTimeout()*2 + 10*time.Second
+	deliveryIDs, err := cache.ClaimWebhookDeliveries(time.Now().Unix(), lease,
+		getConfigInt("webhook.batchSize", defaultBatchSize))
+	if err != nil || len(deliveryIDs) == 0 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, getConfigInt("webhook.concurrency", defaultConcurrency))
+	for _, deliveryID := range deliveryIDs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(deliveryID string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			deliver(deliveryID)
+		}(deliveryID)
+	}
+	wg.Wait()
+}
+
+// deliver 投递一个回调并记录结果
+func deliver(deliveryID string) {
+	delivery, err := cache.GetWebhookDelivery(deliveryID)
+	if err != nil {
+		return
+	}
+	if delivery == nil {
+		_ = cache.CompleteWebhookDelivery(deliveryID)
+		return
+	}
+	app, err := apps.GetApp(delivery.AppID)
+	if err != nil {
+		return
+	}
+	if app == nil {
+		fmt.Println("回调 应用已删除，丢弃", delivery.AppID, delivery.DeliveryID)
+		_ = cache.CompleteWebhookDelivery(deliveryID)
+		return
+	}
+
+	delivery.Attempts++
+	delivery.LastStatus, err = send(delivery, app.WebhookSecret)
+	if err == nil {
+		_ = cache.CompleteWebhookDelivery(deliveryID)
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if len(delivery.LastError) > maxErrorLength {
+		delivery.LastError = strings.ToValidUTF8(delivery.LastError[:maxErrorLength], "")
+	}
+	now := time.Now().Unix()
+	if delivery.Attempts >= getConfigInt("webhook.maxAttempts", defaultMaxAttempts) {
+		fmt.Println("回调 投递失败，移入死信队列", delivery.AppID, delivery.DeliveryID, delivery.URL, delivery.LastError)
+		delivery.DeadAt = now
+		ttl := time.Duration(getConfigInt("webhook.deadTTL", defaultDeadTTL)) * time.Second
+		_ = cache.DeadWebhookDelivery(delivery, ttl)
+		return
+	}
+	delivery.NextAt = now + getRetryDelay(delivery.Attempts)
+	_ = cache.RetryWebhookDelivery(delivery)
+}
+
+// send 发送回调请求，2xx 表示成功
+func send(delivery *models.WebhookDelivery, secret string) (status int, err error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-ID", delivery.EventID)
+	req.Header.Set("X-Webhook-Delivery", delivery.DeliveryID)
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Attempt", strconv.Itoa(delivery.Attempts))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	if secret != "" {
+		req.Header.Set("X-Webhook-Signature", Sign(secret, timestamp, body))
+	}
+
+	rsp, err := getHTTPClient().Do(req)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = rsp.Body.Close()
+	}()
+	status = rsp.StatusCode
+	if status < 200 || status >= 300 {
+		content, _ := io.ReadAll(io.LimitReader(rsp.Body, maxResponseSize))
+		err = fmt.Errorf("http status %d: %s", status, content)
+	}
+	return
+}
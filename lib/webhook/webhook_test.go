@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"message.sent"}`)
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      []byte
+		want      string
+	}{
+		{name: "签名", secret: "secret", timestamp: "1700000000", body: body,
+			want: "87039ecd52459e9a35d3011190a1c874ee42a000b4113d1707e66fc79b413194"},
+		{name: "时间戳参与签名", secret: "secret", timestamp: "1700000001", body: body,
+			want: "73ef59b07ef03e22a05d0c4fe9d1d6c4062280559eb92f17a2f22bad89fd77be"},
+		{name: "空密钥和空内容", secret: "", timestamp: "0", body: nil,
+			want: "43db443c33e9a8f9c06ce4f67cd26e5bc7c70526dc28d1c903f89735eb1bea20"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sign(tt.secret, tt.timestamp, tt.body); got != tt.want {
+				t.Fatalf("Sign() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetRetryDelay(t *testing.T) {
+	tests := []struct {
+		name      string
+		baseDelay int
+		maxDelay  int
+		attempts  int
+		want      int64
+	}{
+		{name: "第一次失败", attempts: 1, want: 10},
+		{name: "第二次失败翻倍", attempts: 2, want: 20},
+		{name: "第五次失败", attempts: 5, want: 160},
+		{name: "不超过默认上限", attempts: 10, want: 3600},
+		{name: "次数很大不溢出", attempts: 1000, want: 3600},
+		{name: "次数为 0", attempts: 0, want: 10},
+		{name: "自定义间隔", baseDelay: 3, maxDelay: 100, attempts: 3, want: 12},
+		{name: "自定义上限", baseDelay: 3, maxDelay: 100, attempts: 7, want: 100},
+		{name: "上限小于初始间隔", baseDelay: 60, maxDelay: 30, attempts: 1, want: 30},
+	}
+	defer viper.Reset()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("webhook.retryBaseDelay", tt.baseDelay)
+			viper.Set("webhook.retryMaxDelay", tt.maxDelay)
+			if got := getRetryDelay(tt.attempts); got != tt.want {
+				t.Fatalf("getRetryDelay(%d) = %d, want %d", tt.attempts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signature := r.Header.Get("X-Webhook-Signature")
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+			return
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("busy"))
+			return
+		}
+		if r.Method != http.MethodPost || r.Header.Get("X-Webhook-Event") != "message.sent" ||
+			r.Header.Get("X-Webhook-Attempt") != "2" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.URL.Path == "/unsigned" {
+			if signature != "" {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+			return
+		}
+		if signature != Sign("secret", r.Header.Get("X-Webhook-Timestamp"), body) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		path       string
+		secret     string
+		wantStatus int
+		wantErr    string
+	}{
+		{name: "签名通过", path: "/ok", secret: "secret", wantStatus: http.StatusOK},
+		{name: "密钥错误", path: "/ok", secret: "other", wantStatus: http.StatusUnauthorized, wantErr: "401"},
+		{name: "没有密钥不签名", path: "/unsigned", secret: "", wantStatus: http.StatusOK},
+		{name: "不跟随重定向", path: "/redirect", secret: "secret", wantStatus: http.StatusFound, wantErr: "302"},
+		{name: "记录响应内容", path: "/error", secret: "secret", wantStatus: http.StatusInternalServerError,
+			wantErr: "busy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delivery := &models.WebhookDelivery{
+				DeliveryID: "d1",
+				EventID:    "e1",
+				Event:      "message.sent",
+				URL:        server.URL + tt.path,
+				Payload:    `{"event":"message.sent"}`,
+				Attempts:   2,
+			}
+			status, err := send(delivery, tt.secret)
+			if status != tt.wantStatus {
+				t.Fatalf("send() status = %d, want %d", status, tt.wantStatus)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("send() err = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("send() err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -12,7 +12,11 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
 
+	"github.com/link1st/gowebsocket/v2/lib/jwtlib"
+	"github.com/link1st/gowebsocket/v2/lib/media"
+	"github.com/link1st/gowebsocket/v2/lib/oidc"
 	"github.com/link1st/gowebsocket/v2/lib/redislib"
+	"github.com/link1st/gowebsocket/v2/lib/sensitive"
 	"github.com/link1st/gowebsocket/v2/routers"
 	"github.com/link1st/gowebsocket/v2/servers/grpcserver"
 	"github.com/link1st/gowebsocket/v2/servers/task"
@@ -23,6 +27,10 @@ func main() {
 	initConfig()
 	initFile()
 	initRedis()
+	initJWT()
+	initOIDC()
+	initMedia()
+	initSensitive()
 	router := gin.Default()
 
 	// 初始化路由
@@ -31,6 +39,9 @@ func main() {
 
 	// 定时任务
 	task.Init()
+	task.MessageInit()
+	task.WebhookInit()
+	task.SensitiveInit()
 
 	// 服务注册
 	task.ServerInit()
@@ -70,6 +81,22 @@ func initRedis() {
 	redislib.NewClient()
 }
 
+func initJWT() {
+	jwtlib.Init()
+}
+
+func initOIDC() {
+	oidc.Init()
+}
+
+func initMedia() {
+	media.Init()
+}
+
+func initSensitive() {
+	sensitive.Init()
+}
+
 func open() {
 	time.Sleep(1000 * time.Millisecond)
 	httpUrl := viper.GetString("app.httpUrl")
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	// 上下文中管理员账号的 key
+	adminContextKey = "adminAccount"
+	// 上下文中审计日志内容的 key
+	auditDetailKey = "auditDetail"
+
+	maxAuditDetailLen = 1024 // 审计日志内容的最大长度
+)
+
+/**
+ * 管理接口中间件，action 为审计日志中的操作名称，role 为需要的最低角色
+ * 请求头 Authorization: Bearer {管理员登录token}
+ * 无论成功失败都会记录审计日志，账号角色和停用状态实时生效
+ */
+func AdminMiddleware(action string, role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		account, code, msg := authenticateAdmin(c)
+		if code == common.OK && !account.HasRole(role) {
+			code, msg = common.PermissionDenied, "需要"+role+"权限"
+		}
+		if code != common.OK {
+			controllers.Response(c, code, msg, nil)
+			c.Abort()
+			RecordAudit(c, action, account)
+			return
+		}
+		c.Set(adminContextKey, account)
+
+		c.Next()
+
+		RecordAudit(c, action, account)
+	}
+}
+
+/**
+ * 验证管理员登录token
+ */
+func authenticateAdmin(c *gin.Context) (account *models.AdminAccount, code uint32, msg string) {
+	token := extractToken(c)
+	if token == "" {
+		return nil, common.Unauthorized, "缺少token"
+	}
+	username, err := cache.GetAdminSession(token)
+	if err != nil {
+		return nil, common.ServerError, ""
+	}
+	if username == "" {
+		return nil, common.Unauthorized, "登录已失效"
+	}
+	account, err = cache.GetAdminAccount(username)
+	if err != nil {
+		return nil, common.ServerError, ""
+	}
+	if account == nil || account.Disabled {
+		return nil, common.Unauthorized, "账号不存在或已停用"
+	}
+	return account, common.OK, ""
+}
+
+/**
+ * 设置本次操作的审计日志内容，例如被踢的用户、公告内容
+ */
+func SetAuditDetail(c *gin.Context, format string, args ...interface{}) {
+	c.Set(auditDetailKey, fmt.Sprintf(format, args...))
+}
+
+/**
+ * 记录审计日志，管理员登录等不经过 AdminMiddleware 的接口直接调用
+ */
+func RecordAudit(c *gin.Context, action string, account *models.AdminAccount) {
+	log := &models.AuditLog{
+		Time:   time.Now().Unix(),
+		Action: action,
+		Method: c.Request.Method,
+		Path:   c.FullPath(),
+		IP:     c.ClientIP(),
+		Detail: c.GetString(auditDetailKey),
+	}
+	if account != nil {
+		log.Admin, log.Role = account.Username, account.Role
+	}
+	params := make([]string, 0, len(c.Params)+1)
+	for _, param := range c.Params {
+		params = append(params, param.Key+"="+param.Value)
+	}
+	// 去掉可能携带的登录凭证，审计日志可以被其他管理员查看
+	query := c.Request.URL.Query()
+	query.Del("token")
+	if len(query) > 0 {
+		params = append(params, query.Encode())
+	}
+	log.Params = strings.Join(params, "&")
+	if code, ok := c.Get(controllers.ResponseCodeKey); ok {
+		log.Code, _ = code.(uint32)
+	}
+	if len(log.Detail) > maxAuditDetailLen {
+		log.Detail = strings.ToValidUTF8(log.Detail[:maxAuditDetailLen], "")
+	}
+	_ = cache.AddAuditLog(log)
+}
+
+/**
+ * 从Gin上下文中获取当前管理员
+ */
+func GetCurrentAdmin(c *gin.Context) *models.AdminAccount {
+	if account, exists := c.Get(adminContextKey); exists {
+		if a, ok := account.(*models.AdminAccount); ok {
+			return a
+		}
+	}
+	return nil
+}
+
+/**
+ * 从请求中获取管理员登录token
+ */
+func GetAdminToken(c *gin.Context) string {
+	return extractToken(c)
+}
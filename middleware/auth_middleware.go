@@ -7,51 +7,106 @@ import (
 
 	"github.com/link1st/gowebsocket/v2/common"
 	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/lib/apps"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
 	"github.com/link1st/gowebsocket/v2/lib/jwtlib"
 )
 
+// 上下文中认证信息的 key
+const authContextKey = "auth"
+
 /**
- * JWT认证中间件
- * 验证请求头中的Authorization token
+ * 认证上下文
+ * 所有需要登录的接口都通过 GetAuth 或 GetCurrentUserID 等方法读取，不再自行解析token
  */
-func JWTAuthMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 从请求头获取token
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			controllers.Response(c, common.Unauthorized, "缺少Authorization头", nil)
-			c.Abort()
-			return
-		}
+type AuthContext struct {
+	UserID    string         // 用户ID
+	AppID     string         // 登录的平台ID
+	Token     string         // 原始token
+	Claims    *jwtlib.Claims // token声明
+	SessionID string         // 会话ID，即token的 jti，用于吊销和关联长连接
+	ExpiresAt int64          // token过期时间
+}
 
-		// 检查Bearer前缀
-		const bearerPrefix = "Bearer "
-		if !strings.HasPrefix(authHeader, bearerPrefix) {
-			controllers.Response(c, common.Unauthorized, "Authorization格式错误", nil)
-			c.Abort()
-			return
-		}
+/**
+ * 从请求中提取token
+ * 优先使用 Authorization: Bearer {token}，兼容直接传token的老客户端
+ * 不从 URL 参数读取，避免token出现在访问日志、代理日志和审计日志中
+ */
+func extractToken(c *gin.Context) string {
+	const bearerPrefix = "Bearer "
+	authHeader := strings.TrimSpace(c.GetHeader("Authorization"))
+	if strings.HasPrefix(authHeader, bearerPrefix) {
+		return strings.TrimSpace(authHeader[len(bearerPrefix):])
+	}
+	return authHeader
+}
 
-		// 提取token
-		tokenString := authHeader[len(bearerPrefix):]
-		if tokenString == "" {
-			controllers.Response(c, common.Unauthorized, "token不能为空", nil)
-			c.Abort()
-			return
+/**
+ * 验证token并生成认证上下文
+ */
+func authenticate(c *gin.Context) (auth *AuthContext, code uint32, msg string) {
+	tokenString := extractToken(c)
+	if tokenString == "" {
+		return nil, common.Unauthorized, "缺少token"
+	}
+
+	// 验证token
+	claims, err := jwtlib.ValidateToken(tokenString)
+	if err != nil {
+		return nil, common.Unauthorized, "无效的token: " + err.Error()
+	}
+
+	// 检查token是否已被吊销(登出、修改密码)
+	revoked, err := cache.IsClaimsRevoked(claims)
+	if err != nil {
+		return nil, common.ServerError, ""
+	}
+	if revoked {
+		return nil, common.Unauthorized, "token已失效"
+	}
+
+	// 应用停用后已签发的token立即失效
+	if _, code = apps.CheckApp(claims.AppID); code != common.OK {
+		if code == common.ServerError {
+			return nil, code, ""
 		}
+		return nil, common.Unauthorized, "应用不可用"
+	}
+
+	auth = &AuthContext{
+		UserID:    claims.UserID,
+		AppID:     claims.AppID,
+		Token:     tokenString,
+		Claims:    claims,
+		SessionID: claims.ID,
+	}
+	if claims.ExpiresAt != nil {
+		auth.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	return auth, common.OK, ""
+}
 
-		// 验证token
-		claims, err := jwtlib.ValidateToken(tokenString)
-		if err != nil {
-			controllers.Response(c, common.Unauthorized, "无效的token: "+err.Error(), nil)
+/**
+ * 将认证信息存储到上下文中
+ */
+func setAuth(c *gin.Context, auth *AuthContext) {
+	c.Set(authContextKey, auth)
+}
+
+/**
+ * JWT认证中间件
+ * 验证请求中的token，失败时直接返回未授权
+ */
+func JWTAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auth, code, msg := authenticate(c)
+		if code != common.OK {
+			controllers.Response(c, code, msg, nil)
 			c.Abort()
 			return
 		}
-
-		// 将用户信息存储到上下文中
-		c.Set("userID", claims.UserID)
-		c.Set("appID", claims.AppID)
-		c.Set("token", tokenString)
+		setAuth(c, auth)
 
 		c.Next()
 	}
@@ -59,55 +114,36 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 
 /**
  * 可选的JWT认证中间件
- * 如果有token则验证，没有token则跳过
+ * 如果有token则验证，没有token或验证失败则跳过
  */
 func OptionalJWTAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 从请求头获取token
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.Next()
-			return
-		}
-
-		// 检查Bearer前缀
-		const bearerPrefix = "Bearer "
-		if !strings.HasPrefix(authHeader, bearerPrefix) {
-			c.Next()
-			return
+		if auth, code, _ := authenticate(c); code == common.OK {
+			setAuth(c, auth)
 		}
 
-		// 提取token
-		tokenString := authHeader[len(bearerPrefix):]
-		if tokenString == "" {
-			c.Next()
-			return
-		}
+		c.Next()
+	}
+}
 
-		// 验证token
-		claims, err := jwtlib.ValidateToken(tokenString)
-		if err != nil {
-			c.Next()
-			return
+/**
+ * 从Gin上下文中获取认证信息，未认证返回 nil
+ */
+func GetAuth(c *gin.Context) *AuthContext {
+	if auth, exists := c.Get(authContextKey); exists {
+		if a, ok := auth.(*AuthContext); ok {
+			return a
 		}
-
-		// 将用户信息存储到上下文中
-		c.Set("userID", claims.UserID)
-		c.Set("appID", claims.AppID)
-		c.Set("token", tokenString)
-
-		c.Next()
 	}
+	return nil
 }
 
 /**
  * 从Gin上下文中获取当前用户ID
  */
 func GetCurrentUserID(c *gin.Context) string {
-	if userID, exists := c.Get("userID"); exists {
-		if uid, ok := userID.(string); ok {
-			return uid
-		}
+	if auth := GetAuth(c); auth != nil {
+		return auth.UserID
 	}
 	return ""
 }
@@ -116,10 +152,8 @@ func GetCurrentUserID(c *gin.Context) string {
  * 从Gin上下文中获取当前应用ID
  */
 func GetCurrentAppID(c *gin.Context) string {
-	if appID, exists := c.Get("appID"); exists {
-		if aid, ok := appID.(string); ok {
-			return aid
-		}
+	if auth := GetAuth(c); auth != nil {
+		return auth.AppID
 	}
 	return ""
 }
@@ -128,10 +162,18 @@ func GetCurrentAppID(c *gin.Context) string {
  * 从Gin上下文中获取当前token
  */
 func GetCurrentToken(c *gin.Context) string {
-	if token, exists := c.Get("token"); exists {
-		if t, ok := token.(string); ok {
-			return t
-		}
+	if auth := GetAuth(c); auth != nil {
+		return auth.Token
 	}
 	return ""
-}
\ No newline at end of file
+}
+
+/**
+ * 从Gin上下文中获取当前token的声明
+ */
+func GetCurrentClaims(c *gin.Context) *jwtlib.Claims {
+	if auth := GetAuth(c); auth != nil {
+		return auth.Claims
+	}
+	return nil
+}
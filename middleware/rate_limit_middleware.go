@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/lib/ratelimit"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+/**
+ * 限流中间件，action 对应配置 rateLimit.http.{action}
+ * 放在认证中间件之后，按客户端IP、登录用户、应用、服务端接口凭证限流
+ * 超过限制时返回 common.TooManyRequests，并通过 Retry-After 响应头和 retryAfter 字段告知需要等待的秒数
+ */
+func RateLimitMiddleware(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subjects := []ratelimit.Subject{{Dimension: models.RateLimitIP, ID: c.ClientIP()}}
+		appID := ""
+		if auth := GetAuth(c); auth != nil {
+			appID = auth.AppID
+			subjects = append(subjects, ratelimit.Subject{Dimension: models.RateLimitUser, ID: auth.UserID})
+		}
+		if credential := GetCurrentCredential(c); credential != nil {
+			appID = credential.AppID
+			subjects = append(subjects, ratelimit.Subject{Dimension: models.RateLimitCredential, ID: credential.AppKey})
+		}
+		subjects = append(subjects, ratelimit.Subject{Dimension: models.RateLimitApp, ID: appID})
+
+		code, retryAfter := ratelimit.Check(ratelimit.KindHTTP, action, appID, subjects...)
+		if code != common.OK {
+			data := make(map[string]interface{})
+			if code == common.TooManyRequests {
+				c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+				data["retryAfter"] = retryAfter
+			}
+			controllers.Response(c, code, "", data)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
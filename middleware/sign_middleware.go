@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/controllers"
+	"github.com/link1st/gowebsocket/v2/lib/apps"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	// 上下文中服务端凭证的 key
+	credentialContextKey = "apiCredential"
+
+	defaultTimestampSkew = 300     // 默认允许的时间误差(秒)
+	maxSignedBodySize    = 1 << 20 // 签名请求的最大请求体
+)
+
+/**
+ * 获取允许的时间误差
+ */
+func getTimestampSkew() int64 {
+	skew := viper.GetInt64("serverAPI.timestampSkew")
+	if skew <= 0 {
+		skew = defaultTimestampSkew
+	}
+	return skew
+}
+
+/**
+ * 计算请求签名
+ * 签名串: METHOD\nURI(含查询参数)\nTIMESTAMP\nNONCE\nHEX(SHA256(BODY))
+ * 签名: HEX(HMAC-SHA256(secret, 签名串))
+ */
+func Sign(secret, method, uri, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	payload := method + "\n" + uri + "\n" + timestamp + "\n" + nonce + "\n" + hex.EncodeToString(bodyHash[:])
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+/**
+ * 服务端接口签名认证中间件
+ * 请求头: X-App-Key、X-Timestamp(秒)、X-Nonce、X-Signature
+ * 校验签名、时间戳、nonce 防重放，以及凭证是否有 scope 权限
+ */
+func ServerSignMiddleware(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		credential, code, msg := verifySignature(c)
+		if code == common.OK && !credential.HasScope(scope) {
+			code, msg = common.PermissionDenied, "凭证没有权限: "+scope
+		}
+		if code != common.OK {
+			fmt.Println("服务端接口签名认证失败", c.Request.URL.Path, c.GetHeader("X-App-Key"), msg)
+			controllers.Response(c, code, msg, nil)
+			c.Abort()
+			return
+		}
+		c.Set(credentialContextKey, credential)
+
+		c.Next()
+	}
+}
+
+/**
+ * 校验请求签名
+ */
+func verifySignature(c *gin.Context) (credential *models.APICredential, code uint32, msg string) {
+	appKey := c.GetHeader("X-App-Key")
+	timestamp := c.GetHeader("X-Timestamp")
+	nonce := c.GetHeader("X-Nonce")
+	signature := c.GetHeader("X-Signature")
+	if appKey == "" || timestamp == "" || nonce == "" || signature == "" {
+		return nil, common.Unauthorized, "缺少签名参数"
+	}
+
+	credential, err := apps.GetCredential(appKey)
+	if err != nil {
+		return nil, common.ServerError, ""
+	}
+	if credential == nil || credential.Disabled {
+		return nil, common.Unauthorized, "appKey无效"
+	}
+	if _, code := apps.CheckApp(credential.AppID); code != common.OK {
+		return nil, code, ""
+	}
+
+	skew := getTimestampSkew()
+	requestTime, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, common.Unauthorized, "时间戳格式错误"
+	}
+	if diff := time.Now().Unix() - requestTime; diff > skew || diff < -skew {
+		return nil, common.Unauthorized, "请求已过期"
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxSignedBodySize))
+	if err != nil {
+		return nil, common.ParameterIllegal, "请求体过大"
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	expected := Sign(credential.Secret, c.Request.Method, c.Request.URL.RequestURI(), timestamp, nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return nil, common.Unauthorized, "签名错误"
+	}
+
+	// 签名通过后再登记 nonce，防止伪造请求占用 nonce
+	if cache.NonceDuplicates(appKey, nonce, int(2*skew)) {
+		return nil, common.Unauthorized, "nonce重复"
+	}
+	return credential, common.OK, ""
+}
+
+/**
+ * 从Gin上下文中获取当前服务端接口凭证
+ */
+func GetCurrentCredential(c *gin.Context) *models.APICredential {
+	if credential, exists := c.Get(credentialContextKey); exists {
+		if cr, ok := credential.(*models.APICredential); ok {
+			return cr
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+// Package models 数据模型
+package models
+
+// 管理员角色，权限依次增大，高级角色拥有低级角色的全部权限
+const (
+	AdminRoleViewer     = "viewer"      // 只读: 查询用户、会话、节点状态
+	AdminRoleOperator   = "operator"    // 运维: 踢人、系统公告、查看会话消息
+	AdminRoleSuperAdmin = "super-admin" // 超级管理员: 管理员账号、应用、清空会话、审计日志
+)
+
+var adminRoleLevels = map[string]int{
+	AdminRoleViewer:     1,
+	AdminRoleOperator:   2,
+	AdminRoleSuperAdmin: 3,
+}
+
+// IsAdminRole 是否为有效的管理员角色
+func IsAdminRole(role string) bool {
+	_, ok := adminRoleLevels[role]
+	return ok
+}
+
+// AdminAccount 管理员账号
+type AdminAccount struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"passwordHash,omitempty"`
+	Role         string `json:"role"`
+	Disabled     bool   `json:"disabled"`
+	CreatedAt    int64  `json:"createdAt"`
+	UpdatedAt    int64  `json:"updatedAt"`
+	LastLoginAt  int64  `json:"lastLoginAt"`
+}
+
+// HasRole 是否拥有 role 及以上的权限
+func (a *AdminAccount) HasRole(role string) bool {
+	return !a.Disabled && adminRoleLevels[a.Role] >= adminRoleLevels[role] && adminRoleLevels[role] > 0
+}
+
+// ToMap 返回给管理端的账号信息，不含密码哈希
+func (a *AdminAccount) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"username":    a.Username,
+		"role":        a.Role,
+		"disabled":    a.Disabled,
+		"createdAt":   a.CreatedAt,
+		"updatedAt":   a.UpdatedAt,
+		"lastLoginAt": a.LastLoginAt,
+	}
+}
+
+// AuditLog 管理操作审计日志，只能追加不能修改
+type AuditLog struct {
+	ID     string `json:"id"`     // 日志ID，按时间递增
+	Time   int64  `json:"time"`   // 操作时间
+	Admin  string `json:"admin"`  // 管理员，认证失败时为空
+	Role   string `json:"role"`   // 操作时的角色
+	Action string `json:"action"` // 操作名称，例如 user.kick
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Params string `json:"params"` // 路径参数和查询参数
+	IP     string `json:"ip"`
+	Code   uint32 `json:"code"`   // 操作结果错误码
+	Detail string `json:"detail"` // 操作内容
+}
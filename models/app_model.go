@@ -0,0 +1,118 @@
+// Package models 数据模型
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	DefaultMaxMessageSize = 64 * 1024 // 默认单条消息内容的最大字节数
+)
+
+// appIDPattern 应用ID 不能包含冒号，数据 key 和会话ID 以 appID 开头，用冒号分隔
+var appIDPattern = regexp.MustCompile(`^[A-Za-z0-9_\-]{1,32}$`)
+
+// IsValidAppID 应用ID 是否合法
+func IsValidAppID(appID string) bool {
+	return appIDPattern.MatchString(appID)
+}
+
+// LoginPolicy 应用的登录策略
+type LoginPolicy struct {
+	AllowPassword bool     `json:"allowPassword"` // 允许密码登录
+	AllowRegister bool     `json:"allowRegister"` // 允许注册
+	AutoProvision bool     `json:"autoProvision"` // 允许免密登录自动开通(内部可信应用)
+	OIDCProviders []string `json:"oidcProviders"` // 允许的外部身份提供方，为空表示不限制
+}
+
+// App 应用(租户)配置
+type App struct {
+	AppID          string      `json:"appID"`
+	Name           string      `json:"name"`
+	Disabled       bool        `json:"disabled"`
+	AllowedOrigins []string    `json:"allowedOrigins"` // 允许的浏览器来源，为空表示不限制
+	MaxMessageSize int         `json:"maxMessageSize"` // 单条消息内容的最大字节数，0 使用默认值
+	LoginPolicy    LoginPolicy `json:"loginPolicy"`
+	WebhookURLs    []string    `json:"webhookURLs"`
+	WebhookEvents  []string    `json:"webhookEvents"`           // 订阅的回调事件，支持 user.* 通配，为空表示全部
+	WebhookSecret  string      `json:"webhookSecret,omitempty"` // 回调签名密钥
+	PreSend        PreSendHook `json:"preSend"`                 // 发送前回调
+	RateLimits     RateLimits  `json:"rateLimits"`              // 限流策略，覆盖全局配置的同一维度
+	CreatedAt      int64       `json:"createdAt"`
+	UpdatedAt      int64       `json:"updatedAt"`
+}
+
+// NewApp 创建应用，默认允许密码登录和注册
+func NewApp(appID string, name string, now int64) (app *App) {
+	app = &App{
+		AppID:          appID,
+		Name:           name,
+		AllowedOrigins: []string{},
+		LoginPolicy: LoginPolicy{
+			AllowPassword: true,
+			AllowRegister: true,
+			OIDCProviders: []string{},
+		},
+		WebhookURLs:   []string{},
+		WebhookEvents: []string{},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	return
+}
+
+// GetMaxMessageSize 单条消息内容的最大字节数
+func (a *App) GetMaxMessageSize() int {
+	if a.MaxMessageSize <= 0 {
+		return DefaultMaxMessageSize
+	}
+	return a.MaxMessageSize
+}
+
+// IsOriginAllowed 浏览器来源是否允许，非浏览器请求没有 Origin 不做限制
+func (a *App) IsOriginAllowed(origin string) bool {
+	if origin == "" || len(a.AllowedOrigins) == 0 {
+		return true
+	}
+	origin = strings.TrimSuffix(strings.ToLower(origin), "/")
+	for _, value := range a.AllowedOrigins {
+		value = strings.TrimSuffix(strings.ToLower(value), "/")
+		if value == "*" || value == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOIDCAllowed 是否允许使用该外部身份提供方登录
+func (a *App) IsOIDCAllowed(provider string) bool {
+	if len(a.LoginPolicy.OIDCProviders) == 0 {
+		return true
+	}
+	for _, value := range a.LoginPolicy.OIDCProviders {
+		if value == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// IsWebhookEventEnabled 是否订阅了该回调事件
+func (a *App) IsWebhookEventEnabled(event string) bool {
+	if len(a.WebhookURLs) == 0 {
+		return false
+	}
+	if len(a.WebhookEvents) == 0 {
+		return true
+	}
+	for _, value := range a.WebhookEvents {
+		if value == event || value == "*" {
+			return true
+		}
+		if strings.HasSuffix(value, ".*") && strings.HasPrefix(event, strings.TrimSuffix(value, "*")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+// Package models 数据模型
+package models
+
+// 刷新令牌轮换结果
+const (
+	RefreshTokenRotated = "rotated" // 正常轮换
+	RefreshTokenInvalid = "invalid" // 不存在、已过期或所属令牌族已吊销
+	RefreshTokenReused  = "reused"  // 已使用过的令牌被再次使用，整个令牌族被吊销
+)
+
+// RefreshToken 刷新令牌，同一次登录轮换出来的令牌属于同一个令牌族(FamilyID)
+type RefreshToken struct {
+	UserID   string `json:"userID"`
+	AppID    string `json:"appID"`
+	FamilyID string `json:"familyID"`
+}
+
+// TokenRef 检查吊销状态时引用的访问令牌
+type TokenRef struct {
+	AppID    string
+	UserID   string
+	TokenID  string // jti
+	IssuedAt int64  // 签发时间(毫秒)
+}
+
+// OIDCState 授权码登录发起时保存的状态，回调时校验
+type OIDCState struct {
+	Provider     string `json:"provider"`
+	AppID        string `json:"appID"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"codeVerifier"`
+}
+
+// 服务端接口权限
+const (
+	ScopeSendOne       = "send-one"       // 给单个用户发送消息
+	ScopeBroadcast     = "broadcast"      // 给全员发送消息
+	ScopePresenceRead  = "presence-read"  // 查询在线用户
+	ScopePasswordReset = "password-reset" // 签发重置密码凭证
+)
+
+// APICredential 服务端接口凭证，每个 appID 可以有多个，按 appKey 区分
+type APICredential struct {
+	AppKey   string   `json:"appKey" mapstructure:"appKey"`
+	AppID    string   `json:"appID" mapstructure:"appID"`
+	Secret   string   `json:"secret,omitempty" mapstructure:"secret"`
+	Scopes   []string `json:"scopes" mapstructure:"scopes"`
+	Disabled bool     `json:"disabled" mapstructure:"disabled"`
+}
+
+// HasScope 是否有接口权限
+func (c *APICredential) HasScope(scope string) bool {
+	for _, value := range c.Scopes {
+		if value == scope {
+			return true
+		}
+	}
+	return false
+}
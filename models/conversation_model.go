@@ -0,0 +1,163 @@
+// Package models 数据模型
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// ExpirePolicyOff 不过期
+	ExpirePolicyOff = "off"
+	// ExpirePolicyDay 1 天后过期
+	ExpirePolicyDay = "1d"
+	// ExpirePolicyWeek 1 周后过期
+	ExpirePolicyWeek = "1w"
+	// ExpirePolicyBurn 阅后即焚
+	ExpirePolicyBurn = "burn"
+
+	// MessageCmdDelete 通知客户端删除本地消息
+	MessageCmdDelete = "delete"
+	// MessageCmdPolicy 通知客户端会话过期策略变更
+	MessageCmdPolicy = "policy"
+	// MessageCmdConversation 通知客户端会话列表项变更
+	MessageCmdConversation = "conversation"
+
+	// ConversationTypeC2C 单聊会话
+	ConversationTypeC2C = "c2c"
+	// ConversationTypeGroup 群聊会话
+	ConversationTypeGroup = "group"
+
+	groupConversationPrefix = "group:" // 群聊会话ID前缀
+)
+
+var expirePolicyTTL = map[string]int64{
+	ExpirePolicyOff:  0,
+	ExpirePolicyDay:  24 * 60 * 60,
+	ExpirePolicyWeek: 7 * 24 * 60 * 60,
+	ExpirePolicyBurn: 0,
+}
+
+// ExpirePolicy 会话消息过期策略
+type ExpirePolicy struct {
+	Policy    string `json:"policy"`    // off/1d/1w/burn
+	TTL       int64  `json:"ttl"`       // 消息存活时间(秒) 0 表示不过期
+	UpdatedBy string `json:"updatedBy"` // 修改人
+	UpdatedAt int64  `json:"updatedAt"` // 修改时间
+}
+
+// NewExpirePolicy 创建过期策略
+func NewExpirePolicy(policy string, updatedBy string, updatedAt int64) (expirePolicy *ExpirePolicy, err error) {
+	ttl, ok := expirePolicyTTL[policy]
+	if !ok {
+		err = fmt.Errorf("不支持的过期策略: %s", policy)
+		return
+	}
+	expirePolicy = &ExpirePolicy{
+		Policy:    policy,
+		TTL:       ttl,
+		UpdatedBy: updatedBy,
+		UpdatedAt: updatedAt,
+	}
+	return
+}
+
+// IsBurnAfterRead 是否阅后即焚
+func (p *ExpirePolicy) IsBurnAfterRead() bool {
+	return p != nil && p.Policy == ExpirePolicyBurn
+}
+
+// GetExpireAt 根据发送时间计算过期时间，0 表示不过期
+func (p *ExpirePolicy) GetExpireAt(timestamp int64) (expireAt int64) {
+	if p == nil || p.TTL <= 0 {
+		return
+	}
+	expireAt = timestamp + p.TTL
+	return
+}
+
+// DeleteNotice 消息删除通知
+type DeleteNotice struct {
+	ConversationID string   `json:"conversationID"` // 会话ID
+	MessageIDs     []string `json:"messageIDs"`     // 需要删除的消息
+	Reason         string   `json:"reason"`         // 删除原因 expired
+}
+
+// GetConversationID 获取单聊会话ID(保证两个用户之间一致)
+// 会话ID 以 appID 开头，不同应用的同名用户不会共用会话
+func GetConversationID(appID, userID, friendID string) (conversationID string) {
+	if userID < friendID {
+		conversationID = fmt.Sprintf("%s:%s:%s", appID, userID, friendID)
+	} else {
+		conversationID = fmt.Sprintf("%s:%s:%s", appID, friendID, userID)
+	}
+	return
+}
+
+// GetConversationAppID 会话所属的 appID
+func GetConversationAppID(conversationID string) (appID string) {
+	if index := strings.Index(conversationID, ":"); index >= 0 {
+		appID = conversationID[:index]
+	}
+	return
+}
+
+// IsConversationMember 用户是否为 appID 下单聊会话的参与者
+func IsConversationMember(conversationID, appID, userID string) bool {
+	if GetConversationAppID(conversationID) != appID {
+		return false
+	}
+	conversationType, peerID := GetConversationPeer(conversationID, userID)
+	if conversationType != ConversationTypeC2C {
+		return false
+	}
+	return GetConversationID(appID, userID, peerID) == conversationID
+}
+
+// GetGroupConversationID 获取群聊会话ID
+func GetGroupConversationID(appID, groupID string) (conversationID string) {
+	conversationID = fmt.Sprintf("%s:%s%s", appID, groupConversationPrefix, groupID)
+	return
+}
+
+// GetConversationPeer 获取会话类型和对方(单聊为对方用户ID，群聊为群ID)
+func GetConversationPeer(conversationID, userID string) (conversationType string, peerID string) {
+	index := strings.Index(conversationID, ":")
+	if index < 0 {
+		return
+	}
+	conversationID = conversationID[index+1:]
+	if strings.HasPrefix(conversationID, groupConversationPrefix) {
+		return ConversationTypeGroup, strings.TrimPrefix(conversationID, groupConversationPrefix)
+	}
+	users := strings.Split(conversationID, ":")
+	if len(users) != 2 {
+		return
+	}
+	conversationType = ConversationTypeC2C
+	peerID = users[0]
+	if peerID == userID {
+		peerID = users[1]
+	}
+	return
+}
+
+// ConversationSetting 用户对会话的个人设置
+type ConversationSetting struct {
+	Pinned    bool  `json:"pinned"`    // 置顶
+	Muted     bool  `json:"muted"`     // 免打扰
+	Archived  bool  `json:"archived"`  // 归档
+	UpdatedAt int64 `json:"updatedAt"` // 修改时间
+}
+
+// Conversation 会话列表项
+type Conversation struct {
+	ConversationID string         `json:"conversationID"` // 会话ID
+	Type           string         `json:"type"`           // c2c/group
+	PeerID         string         `json:"peerID"`         // 单聊为对方用户ID，群聊为群ID
+	LastMessage    *MessageRecord `json:"lastMessage"`    // 最后一条消息，可能已过期删除
+	ReadSeq        int64          `json:"readSeq"`        // 已读游标
+	Unread         int64          `json:"unread"`         // 未读数，由已读游标计算
+	ActiveAt       int64          `json:"activeAt"`       // 最后活跃时间
+	ConversationSetting
+}
@@ -0,0 +1,97 @@
+// Package models 数据模型
+package models
+
+const (
+	// FriendRequestPending 等待对方处理
+	FriendRequestPending = "pending"
+	// FriendRequestAccepted 已同意
+	FriendRequestAccepted = "accepted"
+	// FriendRequestRejected 已拒绝
+	FriendRequestRejected = "rejected"
+	// FriendRequestExpired 已过期(对方未处理)
+	FriendRequestExpired = "expired"
+
+	// MessageCmdFriendRequest 通知客户端收到好友申请
+	MessageCmdFriendRequest = "friendRequest"
+	// MessageCmdFriendAccepted 通知客户端好友申请已通过
+	MessageCmdFriendAccepted = "friendAccepted"
+)
+
+// FriendRequest 好友申请
+type FriendRequest struct {
+	RequestID  string `json:"requestID"`  // 申请ID
+	AppID      string `json:"appID"`      // appID
+	FromUserID string `json:"fromUserID"` // 申请人
+	ToUserID   string `json:"toUserID"`   // 被申请人
+	Greeting   string `json:"greeting"`   // 验证消息
+	Status     string `json:"status"`     // pending/accepted/rejected/expired
+	CreatedAt  int64  `json:"createdAt"`  // 申请时间
+	UpdatedAt  int64  `json:"updatedAt"`  // 处理时间
+	ExpireAt   int64  `json:"expireAt"`   // 未处理时的过期时间
+}
+
+// NewFriendRequest 创建好友申请
+func NewFriendRequest(requestID, appID, fromUserID, toUserID, greeting string, createdAt,
+	ttl int64) (request *FriendRequest) {
+	request = &FriendRequest{
+		RequestID:  requestID,
+		AppID:      appID,
+		FromUserID: fromUserID,
+		ToUserID:   toUserID,
+		Greeting:   greeting,
+		Status:     FriendRequestPending,
+		CreatedAt:  createdAt,
+		UpdatedAt:  createdAt,
+		ExpireAt:   createdAt + ttl,
+	}
+	return
+}
+
+// IsPending 是否等待处理(未过期)
+func (r *FriendRequest) IsPending(now int64) bool {
+	return r.Status == FriendRequestPending && r.ExpireAt > now
+}
+
+// Normalize 待处理但已超时的申请显示为已过期
+func (r *FriendRequest) Normalize(now int64) *FriendRequest {
+	if r.Status == FriendRequestPending && r.ExpireAt <= now {
+		r.Status = FriendRequestExpired
+	}
+	return r
+}
+
+// FriendMeta 用户对好友的私有信息，只有自己可见
+type FriendMeta struct {
+	Remark    string   `json:"remark"`    // 备注名
+	Tags      []string `json:"tags"`      // 标签
+	Starred   bool     `json:"starred"`   // 星标好友
+	Groups    []string `json:"groups"`    // 所属分组ID
+	UpdatedAt int64    `json:"updatedAt"` // 修改时间
+}
+
+// HasTag 是否有该标签
+func (m *FriendMeta) HasTag(tag string) bool {
+	for _, value := range m.Tags {
+		if value == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// InGroup 是否在该分组
+func (m *FriendMeta) InGroup(groupID string) bool {
+	for _, value := range m.Groups {
+		if value == groupID {
+			return true
+		}
+	}
+	return false
+}
+
+// ContactGroup 好友分组
+type ContactGroup struct {
+	GroupID   string `json:"groupID"`   // 分组ID
+	Name      string `json:"name"`      // 分组名称
+	CreatedAt int64  `json:"createdAt"` // 创建时间
+}
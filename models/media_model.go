@@ -0,0 +1,98 @@
+// Package models 数据模型
+package models
+
+const (
+	// MediaKindImage 图片
+	MediaKindImage = "image"
+	// MediaKindAudio 语音
+	MediaKindAudio = "audio"
+
+	// MediaStatusProcessing 处理中
+	MediaStatusProcessing = "processing"
+	// MediaStatusReady 处理完成
+	MediaStatusReady = "ready"
+	// MediaStatusFailed 处理失败
+	MediaStatusFailed = "failed"
+)
+
+// Thumbnail 缩略图信息
+type Thumbnail struct {
+	Size   int    `json:"size"`   // 配置的尺寸(最长边)
+	Width  int    `json:"width"`  // 实际宽度
+	Height int    `json:"height"` // 实际高度
+	File   string `json:"file"`   // 文件名
+}
+
+// MediaInfo 媒体文件元数据
+type MediaInfo struct {
+	MediaID     string       `json:"mediaID"`               // 媒体ID
+	Kind        string       `json:"kind"`                  // 媒体类型 image/audio
+	OwnerID     string       `json:"ownerID"`               // 上传者
+	AppID       string       `json:"appID"`                 // appID
+	ContentType string       `json:"contentType"`           // MIME 类型
+	Size        int64        `json:"size"`                  // 文件大小(字节)
+	Width       int          `json:"width,omitempty"`       // 图片宽度
+	Height      int          `json:"height,omitempty"`      // 图片高度
+	File        string       `json:"file"`                  // 原文件名
+	Thumbnails  []*Thumbnail `json:"thumbnails,omitempty"`  // 缩略图
+	AudioFormat string       `json:"audioFormat,omitempty"` // 音频格式
+	SampleRate  int          `json:"sampleRate,omitempty"`  // 音频采样率
+	Channels    int          `json:"channels,omitempty"`    // 音频声道数
+	Duration    int          `json:"duration,omitempty"`    // 音频时长(毫秒)，由服务端计算
+	Waveform    []int        `json:"waveform,omitempty"`    // 音频波形 0-100
+	Status      string       `json:"status"`                // 处理状态 processing/ready/failed
+	Avatar      bool         `json:"avatar,omitempty"`      // 用作头像，同一应用的用户都可以访问
+	CreatedAt   int64        `json:"createdAt"`             // 上传时间
+}
+
+// NewImageMedia 创建图片媒体信息
+func NewImageMedia(mediaID, ownerID, appID, contentType string, size int64, createdAt int64) *MediaInfo {
+	return &MediaInfo{
+		MediaID:     mediaID,
+		Kind:        MediaKindImage,
+		OwnerID:     ownerID,
+		AppID:       appID,
+		ContentType: contentType,
+		Size:        size,
+		Status:      MediaStatusProcessing,
+		CreatedAt:   createdAt,
+	}
+}
+
+// NewAudioMedia 创建语音媒体信息
+func NewAudioMedia(mediaID, ownerID, appID, audioFormat string, size int64, createdAt int64) *MediaInfo {
+	return &MediaInfo{
+		MediaID:     mediaID,
+		Kind:        MediaKindAudio,
+		OwnerID:     ownerID,
+		AppID:       appID,
+		ContentType: "application/octet-stream",
+		Size:        size,
+		AudioFormat: audioFormat,
+		Status:      MediaStatusReady,
+		CreatedAt:   createdAt,
+	}
+}
+
+// IsReady 是否处理完成
+func (m *MediaInfo) IsReady() bool {
+	return m.Status == MediaStatusReady
+}
+
+// GetThumbnail 获取不小于 size 的最小缩略图，没有则返回最大的
+func (m *MediaInfo) GetThumbnail(size int) (thumbnail *Thumbnail) {
+	for _, t := range m.Thumbnails {
+		if t.Size >= size && (thumbnail == nil || t.Size < thumbnail.Size) {
+			thumbnail = t
+		}
+	}
+	if thumbnail != nil {
+		return
+	}
+	for _, t := range m.Thumbnails {
+		if thumbnail == nil || t.Size > thumbnail.Size {
+			thumbnail = t
+		}
+	}
+	return
+}
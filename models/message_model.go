@@ -0,0 +1,69 @@
+// Package models 数据模型
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// MessageRecord 存储的聊天消息
+type MessageRecord struct {
+	MessageID      string `json:"messageID"`          // 消息ID
+	AppID          string `json:"appID"`              // appID
+	ConversationID string `json:"conversationID"`     // 会话ID
+	Seq            int64  `json:"seq"`                // 会话内递增序号，保存时分配
+	FromUserID     string `json:"fromUserID"`         // 发送者
+	ToUserID       string `json:"toUserID"`           // 接收者
+	Content        string `json:"content"`            // 消息内容
+	MessageType    string `json:"messageType"`        // 消息类型 text/audio/image
+	Timestamp      int64  `json:"timestamp"`          // 发送时间
+	IsRead         bool   `json:"isRead"`             // 是否已读
+	ExpireAt       int64  `json:"expireAt,omitempty"` // 过期时间 0 表示不过期
+}
+
+// ToMap 转换为 redis hash 字段
+func (m *MessageRecord) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"messageID":      m.MessageID,
+		"appID":          m.AppID,
+		"conversationID": m.ConversationID,
+		"seq":            m.Seq,
+		"fromUserID":     m.FromUserID,
+		"toUserID":       m.ToUserID,
+		"content":        m.Content,
+		"messageType":    m.MessageType,
+		"timestamp":      m.Timestamp,
+		"isRead":         m.IsRead,
+		"expireAt":       m.ExpireAt,
+	}
+}
+
+// NewMessageRecordFromMap 从 redis hash 字段解析
+func NewMessageRecordFromMap(fields map[string]string) (record *MessageRecord) {
+	seq, _ := strconv.ParseInt(fields["seq"], 10, 64)
+	timestamp, _ := strconv.ParseInt(fields["timestamp"], 10, 64)
+	expireAt, _ := strconv.ParseInt(fields["expireAt"], 10, 64)
+	record = &MessageRecord{
+		MessageID:      fields["messageID"],
+		AppID:          fields["appID"],
+		ConversationID: fields["conversationID"],
+		Seq:            seq,
+		FromUserID:     fields["fromUserID"],
+		ToUserID:       fields["toUserID"],
+		Content:        fields["content"],
+		MessageType:    fields["messageType"],
+		Timestamp:      timestamp,
+		IsRead:         fields["isRead"] == "true" || fields["isRead"] == "1",
+		ExpireAt:       expireAt,
+	}
+	if record.ConversationID == "" {
+		record.ConversationID = GetConversationID(record.AppID, record.FromUserID, record.ToUserID)
+	}
+	return
+}
+
+// NewMessageID 生成消息ID
+func NewMessageID(fromUserID, toUserID string) string {
+	return fmt.Sprintf("msg_%d_%s_%s", time.Now().UnixNano(), fromUserID, toUserID)
+}
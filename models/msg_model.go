@@ -8,38 +8,50 @@ const (
 	MessageTypeText = "text"
 	// MessageTypeAudio 音频类型消息
 	MessageTypeAudio = "audio"
+	// MessageTypeImage 图片类型消息
+	MessageTypeImage = "image"
 	// MessageCmdMsg 文本类型消息
 	MessageCmdMsg = "msg"
 	// MessageCmdAudio 音频消息
 	MessageCmdAudio = "audio"
+	// MessageCmdImage 图片消息
+	MessageCmdImage = "image"
 	// MessageCmdEnter 用户进入类型消息
 	MessageCmdEnter = "enter"
 	// MessageCmdExit 用户退出类型消息
 	MessageCmdExit = "exit"
+	// MessageCmdAnnouncement 系统公告
+	MessageCmdAnnouncement = "announcement"
 )
 
 // Message 消息的定义
 type Message struct {
-	Target string `json:"target"` // 目标
-	Type   string `json:"type"`   // 消息类型 text/audio
-	Msg    string `json:"msg"`    // 消息内容
-	From   string `json:"from"`   // 发送者
+	Target      string     `json:"target"`                // 目标
+	Type        string     `json:"type"`                  // 消息类型 text/audio/image
+	Msg         string     `json:"msg"`                   // 消息内容
+	From        string     `json:"from"`                  // 发送者
+	AudioFormat string     `json:"audioFormat,omitempty"` // 音频消息实际下发的格式(可能已转码)
+	Media       *MediaInfo `json:"media,omitempty"`       // 媒体元数据，客户端可在下载前渲染占位图
+
+	MessageID      string `json:"messageID,omitempty"`      // 已保存消息的ID
+	ConversationID string `json:"conversationID,omitempty"` // 会话ID
+	Seq            int64  `json:"seq,omitempty"`            // 会话内序号，客户端据此增量同步
 }
 
 // ChatMessage 聊天消息结构
 type ChatMessage struct {
-	ToUserID    string `json:"toUserID"`    // 接收者用户ID
-	MessageType string `json:"messageType"` // 消息类型: text/audio
-	Content     string `json:"content"`     // 消息内容（文本消息直接存储，音频消息存储base64编码）
-	AudioFormat string `json:"audioFormat,omitempty"` // 音频格式，如 "pcm_16k"
-	Timestamp   int64  `json:"timestamp"`   // 消息时间戳
+	ToUserID    string `json:"toUserID"`              // 接收者用户ID
+	MessageType string `json:"messageType"`           // 消息类型: text/audio/image
+	Content     string `json:"content"`               // 消息内容（文本消息直接存储，音频消息存储base64编码，图片消息为mediaID）
+	AudioFormat string `json:"audioFormat,omitempty"` // 音频格式 pcm_8k/pcm_16k/pcm_48k/wav，opus 需要服务端注册解码器
+	Timestamp   int64  `json:"timestamp"`             // 消息时间戳
 }
 
 // AudioMessage 音频消息结构
 type AudioMessage struct {
 	ToUserID    string `json:"toUserID"`    // 接收者用户ID
-	AudioData   string `json:"audioData"`   // 音频数据（base64编码的PCM数据）
-	AudioFormat string `json:"audioFormat"` // 音频格式 "pcm_16k"
+	AudioData   string `json:"audioData"`   // 音频数据（base64编码）
+	AudioFormat string `json:"audioFormat"` // 音频格式 pcm_8k/pcm_16k/pcm_48k/wav，opus 需要服务端注册解码器
 	Duration    int    `json:"duration"`    // 音频时长（毫秒）
 	Timestamp   int64  `json:"timestamp"`   // 消息时间戳
 }
@@ -55,15 +67,45 @@ func NewMsg(from string, Msg string) (message *Message) {
 }
 
 // NewAudioMsg 创建新的音频消息
-func NewAudioMsg(from string, audioData string) (message *Message) {
+func NewAudioMsg(from string, audioData string, audioFormat string, mediaInfo *MediaInfo) (message *Message) {
 	message = &Message{
-		Type: MessageTypeAudio,
-		From: from,
-		Msg:  audioData,
+		Type:        MessageTypeAudio,
+		From:        from,
+		Msg:         audioData,
+		AudioFormat: audioFormat,
+		Media:       mediaInfo,
+	}
+	return
+}
+
+// NewImageMsg 创建新的图片消息
+func NewImageMsg(from string, mediaInfo *MediaInfo) (message *Message) {
+	message = &Message{
+		Type:  MessageTypeImage,
+		From:  from,
+		Msg:   mediaInfo.MediaID,
+		Media: mediaInfo,
 	}
 	return
 }
 
+// WithRecord 附带已保存消息的ID和序号
+func (m *Message) WithRecord(record *MessageRecord) *Message {
+	if record != nil {
+		m.MessageID = record.MessageID
+		m.ConversationID = record.ConversationID
+		m.Seq = record.Seq
+	}
+	return m
+}
+
+// GetMessageData 组装下发的消息
+func GetMessageData(cmd, msgID string, message *Message) string {
+	head := NewResponseHead(msgID, cmd, common.OK, "Ok", message)
+
+	return head.String()
+}
+
 func getTextMsgData(cmd, uuID, msgID, message string) string {
 	textMsg := NewMsg(uuID, message)
 	head := NewResponseHead(msgID, cmd, common.OK, "Ok", textMsg)
@@ -71,13 +113,21 @@ func getTextMsgData(cmd, uuID, msgID, message string) string {
 	return head.String()
 }
 
-func getAudioMsgData(cmd, uuID, msgID, audioData string) string {
-	audioMsg := NewAudioMsg(uuID, audioData)
+func getAudioMsgData(cmd, uuID, msgID, audioData, audioFormat string, mediaInfo *MediaInfo) string {
+	audioMsg := NewAudioMsg(uuID, audioData, audioFormat, mediaInfo)
 	head := NewResponseHead(msgID, cmd, common.OK, "Ok", audioMsg)
 
 	return head.String()
 }
 
+// GetImageMsgData 图片消息
+func GetImageMsgData(uuID, msgID string, mediaInfo *MediaInfo) string {
+	imageMsg := NewImageMsg(uuID, mediaInfo)
+	head := NewResponseHead(msgID, MessageCmdImage, common.OK, "Ok", imageMsg)
+
+	return head.String()
+}
+
 // GetMsgData 文本消息
 func GetMsgData(uuID, msgID, cmd, message string) string {
 	return getTextMsgData(cmd, uuID, msgID, message)
@@ -88,9 +138,9 @@ func GetTextMsgData(uuID, msgID, message string) string {
 	return getTextMsgData("msg", uuID, msgID, message)
 }
 
-// GetAudioMsgData 音频消息
-func GetAudioMsgData(uuID, msgID, audioData string) string {
-	return getAudioMsgData("audio", uuID, msgID, audioData)
+// GetAudioMsgData 音频消息，mediaInfo 包含服务端计算的时长和波形
+func GetAudioMsgData(uuID, msgID, audioData, audioFormat string, mediaInfo *MediaInfo) string {
+	return getAudioMsgData("audio", uuID, msgID, audioData, audioFormat, mediaInfo)
 }
 
 // GetTextMsgDataEnter 用户进入消息
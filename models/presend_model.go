@@ -0,0 +1,42 @@
+// Package models 数据模型
+package models
+
+// 发送前回调的处理结果
+const (
+	PreSendActionAllow   = "allow"   // 放行
+	PreSendActionReject  = "reject"  // 拒绝发送
+	PreSendActionReplace = "replace" // 替换消息内容后发送，仅文本消息
+)
+
+// 消息来源
+const (
+	PreSendSourceWS        = "ws"        // 长连接发送
+	PreSendSourceAPI       = "api"       // 用户通过 HTTP 接口发送
+	PreSendSourceServer    = "server"    // 服务端接口推送给单个用户
+	PreSendSourceBroadcast = "broadcast" // 服务端接口推送给全员
+)
+
+// PreSendHook 应用的发送前回调配置
+type PreSendHook struct {
+	URL        string `json:"url"`        // 回调地址，为空时使用配置 preSend.url
+	Timeout    int    `json:"timeout"`    // 超时时间(毫秒)，0 使用配置
+	FailClosed bool   `json:"failClosed"` // 回调失败或超时时拒绝发送，默认放行
+}
+
+// PreSendMessage 发送前的消息，图片、语音消息的内容为 mediaID
+type PreSendMessage struct {
+	AppID       string `json:"appID"`
+	Source      string `json:"source"`
+	FromUserID  string `json:"fromUserID"` // 服务端推送时为推送接口传入的 userID
+	ToUserID    string `json:"toUserID"`   // 全员推送时为空
+	MessageType string `json:"messageType"`
+	Content     string `json:"content"`
+}
+
+// PreSendResult 发送前回调的返回值
+type PreSendResult struct {
+	Action  string `json:"action"`
+	Code    uint32 `json:"code"`    // 拒绝时返回给发送方的错误码，为空使用 1019
+	Msg     string `json:"msg"`     // 拒绝时返回给发送方的提示
+	Content string `json:"content"` // 替换后的内容
+}
@@ -0,0 +1,46 @@
+// Package models 数据模型
+package models
+
+const (
+	// MessageCmdProfile 通知好友资料变更
+	MessageCmdProfile = "profile"
+)
+
+// UserProfile 用户资料，存储在 user:profile:{userID} hash 中
+type UserProfile struct {
+	UserID           string `json:"userID"`           // 用户ID
+	Nickname         string `json:"nickname"`         // 昵称
+	Avatar           string `json:"avatar"`           // 头像地址
+	AvatarMediaID    string `json:"avatarMediaID"`    // 头像媒体ID
+	Signature        string `json:"signature"`        // 个性签名
+	SearchByID       bool   `json:"searchByID"`       // 允许通过ID搜索到自己
+	SearchByNickname bool   `json:"searchByNickname"` // 允许通过昵称搜索到自己
+	CreatedAt        string `json:"createdAt"`        // 注册时间
+	LastLoginAt      string `json:"lastLoginAt"`      // 最后登录时间
+}
+
+// NewUserProfileFromMap 从 redis hash 字段解析，隐私设置未设置时默认允许搜索
+func NewUserProfileFromMap(fields map[string]string) (profile *UserProfile) {
+	profile = &UserProfile{
+		UserID:           fields["userID"],
+		Nickname:         fields["nickname"],
+		Avatar:           fields["avatar"],
+		AvatarMediaID:    fields["avatarMediaID"],
+		Signature:        fields["signature"],
+		SearchByID:       fields["searchByID"] != "0",
+		SearchByNickname: fields["searchByNickname"] != "0",
+		CreatedAt:        fields["createdAt"],
+		LastLoginAt:      fields["lastLoginAt"],
+	}
+	return
+}
+
+// Public 其他用户可见的资料
+func (p *UserProfile) Public() map[string]interface{} {
+	return map[string]interface{}{
+		"userID":    p.UserID,
+		"nickname":  p.Nickname,
+		"avatar":    p.Avatar,
+		"signature": p.Signature,
+	}
+}
@@ -0,0 +1,71 @@
+// Package models 数据模型
+package models
+
+import (
+	"strings"
+)
+
+// 限流维度
+const (
+	RateLimitConnection = "connection" // 单个长连接
+	RateLimitUser       = "user"       // 用户
+	RateLimitApp        = "app"        // 应用
+	RateLimitCredential = "credential" // 服务端接口凭证
+	RateLimitIP         = "ip"         // 客户端IP
+)
+
+// RateLimit 令牌桶限制，每 period 秒补充 limit 个令牌，最多累积 burst 个
+// period 设置为 86400 时即为每日配额
+type RateLimit struct {
+	Limit  int64 `json:"limit" mapstructure:"limit"`
+	Period int64 `json:"period" mapstructure:"period"` // 秒，默认 1
+	Burst  int64 `json:"burst" mapstructure:"burst"`   // 默认等于 limit
+}
+
+// IsEnabled limit 为 0 表示不限制
+func (r RateLimit) IsEnabled() bool {
+	return r.Limit > 0
+}
+
+// GetRate 每秒补充的令牌数
+func (r RateLimit) GetRate() float64 {
+	period := r.Period
+	if period <= 0 {
+		period = 1
+	}
+	return float64(r.Limit) / float64(period)
+}
+
+// GetBurst 最多累积的令牌数
+func (r RateLimit) GetBurst() int64 {
+	if r.Burst < r.Limit {
+		return r.Limit
+	}
+	return r.Burst
+}
+
+// RateLimitPolicy 一个接口的限流策略，维度 => 限制
+type RateLimitPolicy map[string]RateLimit
+
+// RateLimits 全部接口的限流策略，WS 按 cmd，HTTP 按路由名称
+type RateLimits struct {
+	WS   map[string]RateLimitPolicy `json:"ws" mapstructure:"ws"`
+	HTTP map[string]RateLimitPolicy `json:"http" mapstructure:"http"`
+}
+
+// GetPolicy 获取接口的限流策略，kind 为 ws 或 http，接口名称不区分大小写
+func (r *RateLimits) GetPolicy(kind string, action string) (policy RateLimitPolicy) {
+	policies := r.HTTP
+	if kind == "ws" {
+		policies = r.WS
+	}
+	if policy, ok := policies[action]; ok {
+		return policy
+	}
+	for key, value := range policies {
+		if strings.EqualFold(key, action) {
+			return value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestRateLimit(t *testing.T) {
+	tests := []struct {
+		name        string
+		limit       RateLimit
+		wantEnabled bool
+		wantRate    float64
+		wantBurst   int64
+	}{
+		{name: "不限制", limit: RateLimit{}, wantEnabled: false, wantRate: 0, wantBurst: 0},
+		{name: "默认每秒", limit: RateLimit{Limit: 10}, wantEnabled: true, wantRate: 10, wantBurst: 10},
+		{name: "每分钟", limit: RateLimit{Limit: 30, Period: 60}, wantEnabled: true, wantRate: 0.5, wantBurst: 30},
+		{name: "每日配额", limit: RateLimit{Limit: 864, Period: 86400}, wantEnabled: true, wantRate: 0.01,
+			wantBurst: 864},
+		{name: "负周期按 1 秒", limit: RateLimit{Limit: 5, Period: -1}, wantEnabled: true, wantRate: 5, wantBurst: 5},
+		{name: "突发容量", limit: RateLimit{Limit: 5, Burst: 20}, wantEnabled: true, wantRate: 5, wantBurst: 20},
+		{name: "突发容量不小于 limit", limit: RateLimit{Limit: 5, Burst: 2}, wantEnabled: true, wantRate: 5,
+			wantBurst: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.limit.IsEnabled(); got != tt.wantEnabled {
+				t.Fatalf("IsEnabled() = %v, want %v", got, tt.wantEnabled)
+			}
+			if got := tt.limit.GetRate(); got != tt.wantRate {
+				t.Fatalf("GetRate() = %v, want %v", got, tt.wantRate)
+			}
+			if got := tt.limit.GetBurst(); got != tt.wantBurst {
+				t.Fatalf("GetBurst() = %d, want %d", got, tt.wantBurst)
+			}
+		})
+	}
+}
+
+func TestRateLimitsGetPolicy(t *testing.T) {
+	limits := &RateLimits{
+		WS: map[string]RateLimitPolicy{
+			"sendMessage": {RateLimitUser: {Limit: 10}},
+		},
+		HTTP: map[string]RateLimitPolicy{
+			"login":    {RateLimitIP: {Limit: 5, Period: 60}},
+			"LOGIN_V2": {RateLimitIP: {Limit: 1}},
+		},
+	}
+	tests := []struct {
+		name   string
+		kind   string
+		action string
+		want   *RateLimit
+	}{
+		{name: "ws", kind: "ws", action: "sendMessage", want: &RateLimit{Limit: 10}},
+		{name: "不区分大小写", kind: "ws", action: "SENDMESSAGE", want: &RateLimit{Limit: 10}},
+		{name: "http", kind: "http", action: "login", want: &RateLimit{Limit: 5, Period: 60}},
+		{name: "http 不区分大小写", kind: "http", action: "login_v2", want: &RateLimit{Limit: 1}},
+		{name: "ws 和 http 分开", kind: "http", action: "sendMessage", want: nil},
+		{name: "未配置", kind: "ws", action: "heartbeat", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := limits.GetPolicy(tt.kind, tt.action)
+			if tt.want == nil {
+				if policy != nil {
+					t.Fatalf("GetPolicy(%s, %s) = %v, want nil", tt.kind, tt.action, policy)
+				}
+				return
+			}
+			for _, limit := range policy {
+				if limit != *tt.want {
+					t.Fatalf("GetPolicy(%s, %s) = %v, want %v", tt.kind, tt.action, policy, *tt.want)
+				}
+				return
+			}
+			t.Fatalf("GetPolicy(%s, %s) 为空", tt.kind, tt.action)
+		})
+	}
+}
@@ -10,10 +10,31 @@ type Request struct {
 
 // Login 登录请求数据
 type Login struct {
-	ServiceToken string `json:"serviceToken"` // JWT token，包含用户登录信息
+	ServiceToken string   `json:"serviceToken"`           // JWT token，包含用户登录信息
+	AudioFormats []string `json:"audioFormats,omitempty"` // 客户端可接收的音频格式，按优先级排序，不传默认 pcm_16k
+}
+
+// Reauth 连接中更换认证令牌请求数据
+type Reauth struct {
+	ServiceToken string `json:"serviceToken"` // 刷新后的 JWT token
 }
 
 // HeartBeat 心跳请求数据
 type HeartBeat struct {
 	UserID string `json:"userID,omitempty"`
 }
+
+// SyncMessages 同步消息请求数据
+// 传 Conversations 时按 seq 向后增量同步；传 ConversationID 时从 BeforeSeq 向前翻页
+type SyncMessages struct {
+	Conversations  map[string]int64 `json:"conversations,omitempty"`  // 会话ID => 客户端已有的最大 seq
+	ConversationID string           `json:"conversationID,omitempty"` // 向前翻页的会话ID
+	BeforeSeq      int64            `json:"beforeSeq,omitempty"`      // 向前翻页游标，返回 seq 小于它的消息，0 表示从最新开始
+	Limit          int64            `json:"limit,omitempty"`          // 每个会话返回的最大消息数
+}
+
+// MarkRead 上报已读游标请求数据
+type MarkRead struct {
+	ConversationID string `json:"conversationID"` // 会话ID
+	Seq            int64  `json:"seq,omitempty"`  // 已读到的消息序号，不传表示全部已读
+}
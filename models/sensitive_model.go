@@ -0,0 +1,62 @@
+// Package models 数据模型
+package models
+
+// 敏感词处理方式
+const (
+	SensitiveActionMask   = "mask"   // 替换为 *
+	SensitiveActionReview = "review" // 原样发送，进入人工审核队列
+	SensitiveActionReject = "reject" // 拒绝
+)
+
+// 敏感词检查范围
+const (
+	SensitiveScopeMessage  = "message"  // 文本消息
+	SensitiveScopeNickname = "nickname" // 昵称
+	SensitiveScopeGroup    = "group"    // 分组名称
+)
+
+// 审核状态
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusApproved = "approved"
+	ReviewStatusRejected = "rejected"
+)
+
+// SensitiveRuleSet 敏感词规则集配置
+type SensitiveRuleSet struct {
+	Name   string   `json:"name" mapstructure:"name"`
+	Action string   `json:"action" mapstructure:"action"` // mask/review/reject
+	Scopes []string `json:"scopes" mapstructure:"scopes"` // 检查范围，为空表示全部
+	File   string   `json:"file" mapstructure:"file"`     // 词库文件，每行一个词，# 开头为注释，修改后自动重新加载
+	Words  []string `json:"words" mapstructure:"words"`   // 配置文件中的词
+}
+
+// SensitiveSeverity 处理方式的严重程度，同时命中多个规则集时按最严重的处理
+func SensitiveSeverity(action string) int {
+	switch action {
+	case SensitiveActionMask:
+		return 1
+	case SensitiveActionReview:
+		return 2
+	case SensitiveActionReject:
+		return 3
+	}
+	return 0
+}
+
+// ReviewItem 待人工审核的内容
+type ReviewItem struct {
+	ReviewID   string   `json:"reviewID"`
+	AppID      string   `json:"appID"`
+	Scope      string   `json:"scope"`
+	UserID     string   `json:"userID"`   // 发送人、修改昵称或分组的用户
+	TargetID   string   `json:"targetID"` // 消息接收人或分组ID
+	Content    string   `json:"content"`  // 打码后实际保存的内容
+	Words      []string `json:"words"`
+	RuleSets   []string `json:"ruleSets"`
+	Status     string   `json:"status"`
+	CreatedAt  int64    `json:"createdAt"`
+	ReviewedBy string   `json:"reviewedBy,omitempty"`
+	ReviewedAt int64    `json:"reviewedAt,omitempty"`
+	Note       string   `json:"note,omitempty"`
+}
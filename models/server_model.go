@@ -7,6 +7,11 @@ import (
 	"strings"
 )
 
+const (
+	// MetadataFromUserID 节点间转发消息时 grpc metadata 中的发送者
+	MetadataFromUserID = "from-user-id"
+)
+
 // Server 服务器结构体
 type Server struct {
 	Ip   string `json:"ip"`   // ip
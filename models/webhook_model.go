@@ -0,0 +1,40 @@
+// Package models 数据模型
+package models
+
+// 回调事件
+const (
+	WebhookEventUserLogin     = "user.login"     // 登录签发 token
+	WebhookEventUserLogout    = "user.logout"    // 退出登录
+	WebhookEventUserOnline    = "user.online"    // 长连接登录
+	WebhookEventUserOffline   = "user.offline"   // 长连接断开
+	WebhookEventMessageSend   = "message.send"   // 发送聊天消息
+	WebhookEventFriendRequest = "friend.request" // 发送好友申请
+	WebhookEventFriendAccept  = "friend.accept"  // 同意好友申请
+	WebhookEventFriendReject  = "friend.reject"  // 拒绝好友申请
+	WebhookEventFriendDelete  = "friend.delete"  // 删除好友
+)
+
+// WebhookEvent 回调请求体
+type WebhookEvent struct {
+	EventID string      `json:"eventID"` // 事件ID，同一事件重试和投递到多个地址时相同，用于幂等
+	AppID   string      `json:"appID"`
+	Event   string      `json:"event"`
+	Time    int64       `json:"time"`
+	Data    interface{} `json:"data"`
+}
+
+// WebhookDelivery 一次回调投递，每个事件按回调地址分别投递
+type WebhookDelivery struct {
+	DeliveryID string `json:"deliveryID"`
+	EventID    string `json:"eventID"`
+	AppID      string `json:"appID"`
+	Event      string `json:"event"`
+	URL        string `json:"url"`
+	Payload    string `json:"payload"`    // 请求体
+	Attempts   int    `json:"attempts"`   // 已投递次数
+	NextAt     int64  `json:"nextAt"`     // 下次投递时间
+	LastStatus int    `json:"lastStatus"` // 最后一次的 HTTP 状态码，请求失败为 0
+	LastError  string `json:"lastError"`
+	CreatedAt  int64  `json:"createdAt"`
+	DeadAt     int64  `json:"deadAt"` // 进入死信队列的时间
+}
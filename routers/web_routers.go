@@ -4,11 +4,17 @@ package routers
 import (
 	"github.com/gin-gonic/gin"
 
+	"github.com/link1st/gowebsocket/v2/controllers/admin"
 	"github.com/link1st/gowebsocket/v2/controllers/auth"
+	"github.com/link1st/gowebsocket/v2/controllers/conversation"
 	"github.com/link1st/gowebsocket/v2/controllers/friend"
+	"github.com/link1st/gowebsocket/v2/controllers/media"
+	"github.com/link1st/gowebsocket/v2/controllers/message"
+	"github.com/link1st/gowebsocket/v2/controllers/profile"
 	"github.com/link1st/gowebsocket/v2/controllers/systems"
 	"github.com/link1st/gowebsocket/v2/controllers/user"
 	"github.com/link1st/gowebsocket/v2/middleware"
+	"github.com/link1st/gowebsocket/v2/models"
 )
 
 // Init http 接口路由
@@ -21,7 +27,14 @@ func Init(router *gin.Engine) {
 		// 认证接口
 		authRouter := apiRouter.Group("/auth")
 		{
-			authRouter.POST("/login", auth.Login)
+			authRouter.POST("/register", middleware.RateLimitMiddleware("register"), auth.Register)
+			authRouter.POST("/login", middleware.RateLimitMiddleware("login"), auth.Login)
+			authRouter.POST("/refresh", middleware.RateLimitMiddleware("refresh"), auth.Refresh)
+			authRouter.GET("/oidc/:provider/login", auth.OIDCLogin)
+			authRouter.GET("/oidc/:provider/callback", auth.OIDCCallback)
+			authRouter.POST("/token/exchange", middleware.RateLimitMiddleware("tokenExchange"), auth.TokenExchange)
+			authRouter.POST("/password", middleware.JWTAuthMiddleware(), auth.ChangePassword)
+			authRouter.POST("/password/reset", middleware.RateLimitMiddleware("passwordReset"), auth.ResetPassword)
 			authRouter.POST("/logout", middleware.JWTAuthMiddleware(), auth.Logout)
 			authRouter.GET("/me", middleware.JWTAuthMiddleware(), auth.GetCurrentUser)
 		}
@@ -32,28 +45,134 @@ func Init(router *gin.Engine) {
 		{
 			friendRouter.GET("/list", friend.GetFriendList)
 			friendRouter.POST("/add", friend.AddFriend)
+			friendRouter.GET("/requests", friend.GetFriendRequests)
+			friendRouter.POST("/requests/:requestID/accept", friend.AcceptFriendRequest)
+			friendRouter.POST("/requests/:requestID/reject", friend.RejectFriendRequest)
+			friendRouter.GET("/block/list", friend.GetBlockList)
+			friendRouter.POST("/block", friend.BlockUser)
+			friendRouter.DELETE("/block/:userID", friend.UnblockUser)
+			friendRouter.PUT("/:friendID/meta", friend.UpdateFriendMeta)
+			friendRouter.GET("/groups", friend.GetContactGroups)
+			friendRouter.POST("/groups", friend.CreateContactGroup)
+			friendRouter.PUT("/groups/:groupID", friend.RenameContactGroup)
+			friendRouter.DELETE("/groups/:groupID", friend.DeleteContactGroup)
 			friendRouter.DELETE("/:friendID", friend.DeleteFriend)
 		}
 
-		// 消息接口
-		// messageRouter := apiRouter.Group("/message")
-		// {
-		// 	messageRouter.GET("/history", message.GetChatHistory)
-		// 	messageRouter.POST("/send", message.SendMessage)
-		// 	messageRouter.PUT("/read", message.MarkAsRead)
-		// 	messageRouter.GET("/unread", message.GetUnreadCount)
-		// }
+		// 用户资料接口 (需要认证)
+		profileRouter := apiRouter.Group("/profile")
+		profileRouter.Use(middleware.JWTAuthMiddleware())
+		{
+			profileRouter.GET("", profile.GetProfile)
+			profileRouter.PUT("", profile.UpdateProfile)
+			profileRouter.PUT("/privacy", profile.UpdatePrivacy)
+			profileRouter.GET("/search", profile.SearchUsers)
+		}
+
+		// 会话接口 (需要认证)
+		conversationRouter := apiRouter.Group("/conversation")
+		conversationRouter.Use(middleware.JWTAuthMiddleware())
+		{
+			conversationRouter.GET("/policy", conversation.GetExpirePolicy)
+			conversationRouter.PUT("/policy", conversation.SetExpirePolicy)
+			conversationRouter.GET("/list", conversation.GetConversationList)
+			conversationRouter.PUT("/setting", conversation.UpdateSetting)
+		}
+
+		// 媒体文件接口 (需要认证)
+		mediaRouter := apiRouter.Group("/media")
+		mediaRouter.Use(middleware.JWTAuthMiddleware())
+		{
+			mediaRouter.POST("/upload", middleware.RateLimitMiddleware("mediaUpload"), media.Upload)
+			mediaRouter.GET("/:mediaID", media.Info)
+			mediaRouter.GET("/:mediaID/file", media.Download)
+		}
+
+		// 消息接口 (需要认证)
+		messageRouter := apiRouter.Group("/message")
+		messageRouter.Use(middleware.JWTAuthMiddleware())
+		{
+			messageRouter.GET("/history", message.GetChatHistory)
+			messageRouter.POST("/send", middleware.RateLimitMiddleware("messageSend"), message.SendMessage)
+			messageRouter.PUT("/read", message.MarkAsRead)
+			messageRouter.GET("/unread", message.GetUnreadCount)
+			messageRouter.GET("/search", message.SearchMessages)
+			messageRouter.POST("/sync", message.SyncMessages)
+		}
 	}
 
-	// 用户组 (保留原有接口兼容性)
+	// 服务端接口 (需要签名，按凭证的 scope 授权)
 	userRouter := router.Group("/user")
 	{
-		userRouter.GET("/list", user.List)
-		userRouter.GET("/online", user.Online)
-		userRouter.POST("/sendMessage", user.SendMessage)
-		userRouter.POST("/sendMessageAll", user.SendMessageAll)
+		userRouter.GET("/list", middleware.ServerSignMiddleware(models.ScopePresenceRead),
+			middleware.RateLimitMiddleware("userList"), user.List)
+		userRouter.GET("/online", middleware.ServerSignMiddleware(models.ScopePresenceRead),
+			middleware.RateLimitMiddleware("userOnline"), user.Online)
+		userRouter.POST("/sendMessage", middleware.ServerSignMiddleware(models.ScopeSendOne),
+			middleware.RateLimitMiddleware("sendMessage"), user.SendMessage)
+		userRouter.POST("/sendMessageAll", middleware.ServerSignMiddleware(models.ScopeBroadcast),
+			middleware.RateLimitMiddleware("sendMessageAll"), user.SendMessageAll)
+		userRouter.POST("/password/resetToken", middleware.ServerSignMiddleware(models.ScopePasswordReset),
+			user.IssueResetToken)
 	}
 
+	// 管理接口，按管理员角色授权，全部操作记录审计日志
+	adminRouter := router.Group("/admin")
+	{
+		viewer, operator, superAdmin := models.AdminRoleViewer, models.AdminRoleOperator, models.AdminRoleSuperAdmin
+		adminRouter.POST("/login", middleware.RateLimitMiddleware("adminLogin"), admin.Login)
+		adminRouter.POST("/logout", middleware.AdminMiddleware("admin.logout", viewer), admin.Logout)
+		adminRouter.GET("/me", middleware.AdminMiddleware("admin.me", viewer), admin.Me)
+
+		adminRouter.GET("/accounts", middleware.AdminMiddleware("account.list", superAdmin), admin.ListAccounts)
+		adminRouter.POST("/accounts", middleware.AdminMiddleware("account.create", superAdmin), admin.CreateAccount)
+		adminRouter.PUT("/accounts/:username", middleware.AdminMiddleware("account.update", superAdmin),
+			admin.UpdateAccount)
+		adminRouter.DELETE("/accounts/:username", middleware.AdminMiddleware("account.delete", superAdmin),
+			admin.DeleteAccount)
+
+		adminRouter.GET("/apps", middleware.AdminMiddleware("app.list", viewer), admin.ListApps)
+		adminRouter.POST("/apps", middleware.AdminMiddleware("app.create", superAdmin), admin.CreateApp)
+		adminRouter.GET("/apps/:appID", middleware.AdminMiddleware("app.get", viewer), admin.GetApp)
+		adminRouter.PUT("/apps/:appID", middleware.AdminMiddleware("app.update", superAdmin), admin.UpdateApp)
+		adminRouter.DELETE("/apps/:appID", middleware.AdminMiddleware("app.delete", superAdmin), admin.DeleteApp)
+		adminRouter.GET("/apps/:appID/credentials", middleware.AdminMiddleware("credential.list", superAdmin),
+			admin.ListCredentials)
+		adminRouter.POST("/apps/:appID/credentials", middleware.AdminMiddleware("credential.create", superAdmin),
+			admin.CreateCredential)
+		adminRouter.DELETE("/apps/:appID/credentials/:appKey",
+			middleware.AdminMiddleware("credential.delete", superAdmin), admin.DeleteCredential)
+		adminRouter.GET("/apps/:appID/webhooks/dead", middleware.AdminMiddleware("webhook.listDead", operator),
+			admin.ListDeadWebhooks)
+		adminRouter.POST("/apps/:appID/webhooks/dead/replay", middleware.AdminMiddleware("webhook.replay", operator),
+			admin.ReplayDeadWebhooks)
+		adminRouter.POST("/apps/:appID/webhooks/dead/:deliveryID/replay",
+			middleware.AdminMiddleware("webhook.replay", operator), admin.ReplayDeadWebhook)
+
+		adminRouter.GET("/users", middleware.AdminMiddleware("user.list", viewer), admin.ListUsers)
+		adminRouter.GET("/users/:userID", middleware.AdminMiddleware("user.get", viewer), admin.GetUser)
+		adminRouter.POST("/users/:userID/kick", middleware.AdminMiddleware("user.kick", operator), admin.KickUser)
+		adminRouter.GET("/sessions", middleware.AdminMiddleware("session.list", viewer), admin.ListSessions)
+		adminRouter.GET("/nodes", middleware.AdminMiddleware("node.list", viewer), admin.ListNodes)
+		adminRouter.POST("/announcements", middleware.AdminMiddleware("announcement.send", operator),
+			admin.SendAnnouncement)
+		adminRouter.GET("/conversations/:conversationID/messages",
+			middleware.AdminMiddleware("conversation.inspect", operator), admin.GetConversationMessages)
+		adminRouter.DELETE("/conversations/:conversationID",
+			middleware.AdminMiddleware("conversation.purge", superAdmin), admin.PurgeConversation)
+		adminRouter.GET("/sensitive/ruleSets", middleware.AdminMiddleware("sensitive.list", viewer),
+			admin.ListSensitiveRuleSets)
+		adminRouter.POST("/sensitive/reload", middleware.AdminMiddleware("sensitive.reload", superAdmin),
+			admin.ReloadSensitiveWords)
+		adminRouter.GET("/reviews", middleware.AdminMiddleware("review.list", operator), admin.ListReviews)
+		adminRouter.POST("/reviews/:reviewID", middleware.AdminMiddleware("review.resolve", operator),
+			admin.ResolveReview)
+		adminRouter.GET("/audit", middleware.AdminMiddleware("audit.list", superAdmin), admin.ListAuditLogs)
+	}
+
+	// 签名公钥，供其他服务验证 token
+	router.GET("/.well-known/jwks.json", auth.JWKS)
+
 	// 系统
 	systemRouter := router.Group("/system")
 	{
@@ -9,6 +9,7 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 
 	"github.com/link1st/gowebsocket/v2/common"
 	"github.com/link1st/gowebsocket/v2/models"
@@ -85,10 +86,10 @@ func GetUserList(server *models.Server, appID string) (userIDs []string, err err
 	return
 }
 
-// SendMsg 发送消息
+// SendMsg 发送消息，fromUserID 不为空时通过 metadata 传递发送者，接收节点据此检查黑名单
 // link::https://github.com/grpc/grpc-go/blob/master/examples/helloworld/greeter_client/main.go
-func SendMsg(server *models.Server, seq string, appID string, userID string, cmd string, msgType string,
-	message string) (sendMsgID string, err error) {
+func SendMsg(server *models.Server, seq string, appID string, fromUserID string, userID string, cmd string,
+	msgType string, message string) (sendMsgID string, err error) {
 	// Set up a connection to the server.
 	conn, err := grpc.Dial(server.String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
@@ -99,6 +100,9 @@ func SendMsg(server *models.Server, seq string, appID string, userID string, cmd
 	c := protobuf.NewAccServerClient(conn)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
+	if fromUserID != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, models.MetadataFromUserID, fromUserID)
+	}
 	req := protobuf.SendMsgReq{
 		Seq:     seq,
 		AppID:   appID,
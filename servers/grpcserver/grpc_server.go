@@ -15,6 +15,7 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/spf13/viper"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 type server struct {
@@ -65,6 +66,14 @@ func (s *server) SendMsg(c context.Context, req *protobuf.SendMsgReq) (rsp *prot
 		setErr(rsp, common.ParameterIllegal, "")
 		return
 	}
+	// 代用户转发的消息，在接收方所在节点再检查一次黑名单
+	if values := metadata.ValueFromIncomingContext(c, models.MetadataFromUserID); len(values) > 0 {
+		if code := websocket.CheckBlocked(req.GetAppID(), values[0], req.GetUserID()); code != common.OK {
+			fmt.Println("黑名单拦截", values[0], req.GetUserID(), code)
+			setErr(rsp, code, "")
+			return rsp, nil
+		}
+	}
 	data := models.GetMsgData(req.GetUserID(), req.GetSeq(), req.GetCms(), req.GetMsg())
 	sendResults, err := websocket.SendUserMessageLocal(req.GetAppID(), req.GetUserID(), data)
 	if err != nil {
@@ -12,6 +12,8 @@ import (
 // Init 初始化
 func Init() {
 	Timer(3*time.Second, 30*time.Second, cleanConnection, "", nil, nil)
+	Timer(5*time.Second, 5*time.Second, cleanRevokedConnection, "", nil, nil)
+	Timer(10*time.Second, 10*time.Second, cleanDisabledAppConnection, "", nil, nil)
 
 }
 
@@ -27,3 +29,27 @@ func cleanConnection(param interface{}) (result bool) {
 	websocket.ClearTimeoutConnections()
 	return
 }
+
+// cleanRevokedConnection 断开令牌已被吊销的连接
+func cleanRevokedConnection(param interface{}) (result bool) {
+	result = true
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("ClearRevokedConnections stop", r, string(debug.Stack()))
+		}
+	}()
+	websocket.ClearRevokedConnections()
+	return
+}
+
+// cleanDisabledAppConnection 断开已停用应用的连接
+func cleanDisabledAppConnection(param interface{}) (result bool) {
+	result = true
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("ClearDisabledAppConnections stop", r, string(debug.Stack()))
+		}
+	}()
+	websocket.ClearDisabledAppConnections()
+	return
+}
@@ -0,0 +1,80 @@
+// Package task 定时任务
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/link1st/gowebsocket/v2/helper"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/models"
+	"github.com/link1st/gowebsocket/v2/servers/websocket"
+)
+
+const (
+	purgeBatchSize = 500 // 每次清理的最大消息数
+)
+
+// MessageInit 消息定时任务
+func MessageInit() {
+	Timer(5*time.Second, 10*time.Second, purgeExpiredMessages, "", nil, nil)
+}
+
+// purgeExpiredMessages 清理过期消息并通知客户端删除本地副本
+func purgeExpiredMessages(param interface{}) (result bool) {
+	result = true
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("清理过期消息 stop", r, string(debug.Stack()))
+		}
+	}()
+	now := time.Now().Unix()
+	members, err := cache.GetExpiredMessages(now, purgeBatchSize)
+	if err != nil {
+		fmt.Println("清理过期消息 获取失败", err)
+		return
+	}
+	if len(members) == 0 {
+		return
+	}
+
+	// 按会话分组通知
+	notices := make(map[string]*models.DeleteNotice)
+	records := make(map[string]*models.MessageRecord)
+	for _, member := range members {
+		if !cache.ClaimExpiredMessage(member) {
+			// 其它节点已经处理
+			continue
+		}
+		appID, messageID := cache.ParseMessageExpireMember(member)
+		record, err := cache.DeleteMessage(appID, messageID)
+		if err != nil {
+			// 放回队列，否则领取后不会再有节点清理这条消息
+			_ = cache.ReleaseExpiredMessage(member, now)
+			continue
+		}
+		if record == nil {
+			continue
+		}
+		notice, ok := notices[record.ConversationID]
+		if !ok {
+			notice = &models.DeleteNotice{ConversationID: record.ConversationID, Reason: "expired"}
+			notices[record.ConversationID] = notice
+			records[record.ConversationID] = record
+		}
+		notice.MessageIDs = append(notice.MessageIDs, messageID)
+	}
+	fmt.Println("定时任务，清理过期消息", len(members), "会话数", len(notices))
+
+	for conversationID, notice := range notices {
+		record := records[conversationID]
+		noticeByte, _ := json.Marshal(notice)
+		for _, userID := range []string{record.FromUserID, record.ToUserID} {
+			_, _ = websocket.SendUserCmdMessage(record.AppID, userID, helper.GetOrderIDTime(), models.MessageCmdDelete,
+				string(noticeByte))
+		}
+	}
+	return
+}
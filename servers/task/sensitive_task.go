@@ -0,0 +1,27 @@
+// Package task 定时任务
+package task
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/link1st/gowebsocket/v2/lib/sensitive"
+)
+
+// SensitiveInit 敏感词定时任务
+func SensitiveInit() {
+	Timer(10*time.Second, 10*time.Second, reloadSensitiveWords, "", nil, nil)
+}
+
+// reloadSensitiveWords 词库文件修改后重新加载
+func reloadSensitiveWords(param interface{}) (result bool) {
+	result = true
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("ReloadSensitiveWords stop", r, string(debug.Stack()))
+		}
+	}()
+	sensitive.CheckReload()
+	return
+}
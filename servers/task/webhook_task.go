@@ -0,0 +1,27 @@
+// Package task 定时任务
+package task
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/link1st/gowebsocket/v2/lib/webhook"
+)
+
+// WebhookInit 回调投递任务
+func WebhookInit() {
+	Timer(3*time.Second, 1*time.Second, deliverWebhook, "", nil, nil)
+}
+
+// deliverWebhook 投递到期的回调
+func deliverWebhook(param interface{}) (result bool) {
+	result = true
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("DeliverWebhook stop", r, string(debug.Stack()))
+		}
+	}()
+	webhook.Deliver()
+	return
+}
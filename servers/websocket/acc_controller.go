@@ -2,13 +2,18 @@
 package websocket
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/lib/apps"
+	"github.com/link1st/gowebsocket/v2/lib/audio"
 	"github.com/link1st/gowebsocket/v2/lib/cache"
 	"github.com/link1st/gowebsocket/v2/lib/jwtlib"
+	"github.com/link1st/gowebsocket/v2/lib/media"
+	"github.com/link1st/gowebsocket/v2/lib/webhook"
 	"github.com/link1st/gowebsocket/v2/models"
 )
 
@@ -47,6 +52,11 @@ func LoginController(client *Client, seq string, message []byte) (code uint32, m
 		fmt.Println("用户登录 token验证失败", seq, err)
 		return
 	}
+	if isClaimsRevoked(claims) {
+		code = common.Unauthorized
+		fmt.Println("用户登录 token已失效", seq, claims.UserID)
+		return
+	}
 
 	// 从token中获取用户信息
 	userID := claims.UserID
@@ -59,6 +69,18 @@ func LoginController(client *Client, seq string, message []byte) (code uint32, m
 		return
 	}
 
+	// 检查应用是否可用以及浏览器来源
+	app, code := apps.CheckApp(appID)
+	if code != common.OK {
+		fmt.Println("用户登录 应用不可用", seq, appID)
+		return
+	}
+	if !app.IsOriginAllowed(client.Origin) {
+		code = common.PermissionDenied
+		fmt.Println("用户登录 来源不允许", seq, appID, client.Origin)
+		return
+	}
+
 	// 检查用户是否已经登录
 	if client.IsLogin() {
 		fmt.Println("用户登录 用户已经登录", client.AppID, client.UserID, seq)
@@ -67,7 +89,9 @@ func LoginController(client *Client, seq string, message []byte) (code uint32, m
 	}
 
 	// 设置客户端登录状态
+	client.SetAudioFormats(request.AudioFormats)
 	client.Login(appID, userID, currentTime)
+	client.SetToken(claims)
 
 	// 存储用户在线数据
 	userOnline := models.UserLogin(serverIp, serverPort, appID, userID, client.Addr, currentTime)
@@ -90,10 +114,55 @@ func LoginController(client *Client, seq string, message []byte) (code uint32, m
 
 	// 返回登录成功的用户信息
 	data = map[string]interface{}{
-		"userID": userID,
-		"appID":  appID,
+		"userID":       userID,
+		"appID":        appID,
+		"audioFormats": client.GetAudioFormats(),
+		"expireAt":     client.TokenExpireAt,
+	}
+
+	return
+}
+
+// ReauthController 连接不断开的情况下更换认证令牌
+// 访问令牌过期的连接会被断开，客户端刷新令牌后通过该命令续期
+func ReauthController(client *Client, seq string, message []byte) (code uint32, msg string, data interface{}) {
+	code = common.OK
+	if !client.IsLogin() {
+		code = common.NotLoggedIn
+		return
+	}
+	request := &models.Reauth{}
+	if err := json.Unmarshal(message, request); err != nil {
+		code = common.ParameterIllegal
+		fmt.Println("重新认证 解析数据失败", seq, err)
+		return
 	}
 
+	claims, err := jwtlib.ValidateToken(request.ServiceToken)
+	if err != nil {
+		code = common.Unauthorized
+		fmt.Println("重新认证 token验证失败", seq, client.UserID, err)
+		return
+	}
+	if isClaimsRevoked(claims) {
+		code = common.Unauthorized
+		fmt.Println("重新认证 token已失效", seq, client.UserID)
+		return
+	}
+	// 只能续期同一个用户、同一个平台的登录
+	if claims.UserID != client.UserID || claims.AppID != client.AppID {
+		code = common.UnauthorizedUserID
+		fmt.Println("重新认证 用户不匹配", seq, client.AppID, client.UserID, claims.AppID, claims.UserID)
+		return
+	}
+
+	client.SetToken(claims)
+	fmt.Println("重新认证 成功", seq, client.Addr, client.UserID)
+
+	data = map[string]interface{}{
+		"userID":   client.UserID,
+		"expireAt": client.TokenExpireAt,
+	}
 	return
 }
 
@@ -157,32 +226,100 @@ func SendMessageController(client *Client, seq string, message []byte) (code uin
 		return
 	}
 
+	// 检查消息长度
+	if request.MessageType == models.MessageTypeText {
+		if code = CheckMessageSize(client.AppID, request.Content); code != common.OK {
+			fmt.Println("发送消息 消息内容过长", seq, len(request.Content))
+			return
+		}
+	}
+
 	// 验证消息类型
-	if request.MessageType != models.MessageTypeText && request.MessageType != models.MessageTypeAudio {
+	if request.MessageType != models.MessageTypeText && request.MessageType != models.MessageTypeAudio &&
+		request.MessageType != models.MessageTypeImage {
 		code = common.ParameterIllegal
 		fmt.Println("发送消息 不支持的消息类型", seq, request.MessageType)
 		return
 	}
 
+	// 检查黑名单
+	if code = CheckBlocked(client.AppID, client.UserID, request.ToUserID); code != common.OK {
+		fmt.Println("发送消息 黑名单拦截", seq, client.UserID, request.ToUserID)
+		return
+	}
+
 	// 如果是音频消息，验证音频格式
 	if request.MessageType == models.MessageTypeAudio {
 		if request.AudioFormat == "" {
-			request.AudioFormat = "pcm_16k" // 默认格式
+			request.AudioFormat = audio.FormatPCM16k // 默认格式
 		}
-		if request.AudioFormat != "pcm_16k" {
+		if !audio.IsDecodable(request.AudioFormat) {
 			code = common.ParameterIllegal
 			fmt.Println("发送消息 不支持的音频格式", seq, request.AudioFormat)
 			return
 		}
 	}
 
+	// 如果是音频消息，由服务端解码计算时长和波形，确定可以发送后再保存
+	var (
+		mediaInfo *models.MediaInfo
+		clip      *audio.Clip
+		rawAudio  []byte
+	)
+	if request.MessageType == models.MessageTypeAudio {
+		mediaInfo, clip, rawAudio, code, msg = parseAudio(client, request.AudioFormat, request.Content, 0)
+		if code != common.OK {
+			fmt.Println("发送消息 音频处理失败", seq, msg)
+			return
+		}
+	}
+
+	// 如果是图片消息，内容为上传后的 mediaID
+	if request.MessageType == models.MessageTypeImage {
+		var err error
+		mediaInfo, err = cache.GetMediaInfo(request.Content)
+		if err != nil {
+			code = common.ParameterIllegal
+			fmt.Println("发送消息 图片不存在", seq, request.Content, err)
+			return
+		}
+		// 只能发送自己上传的图片，否则引用他人的 mediaID 就能获得下载权限
+		if mediaInfo.Kind != models.MediaKindImage || mediaInfo.AppID != client.AppID ||
+			mediaInfo.OwnerID != client.UserID {
+			code = common.ParameterIllegal
+			fmt.Println("发送消息 不是自己上传的图片", seq, request.Content, client.UserID)
+			return
+		}
+	}
+
+	// 发送前回调，文本消息的内容可能被替换
+	preSendMessage := &models.PreSendMessage{
+		AppID:       client.AppID,
+		Source:      models.PreSendSourceWS,
+		FromUserID:  client.UserID,
+		ToUserID:    request.ToUserID,
+		MessageType: request.MessageType,
+		Content:     request.Content,
+	}
+	if mediaInfo != nil {
+		preSendMessage.Content = mediaInfo.MediaID
+	}
+	preSendContent, code, msg := BeforeSend(preSendMessage)
+	if code != common.OK {
+		fmt.Println("发送消息 被发送前回调拦截", seq, client.UserID, request.ToUserID, code, msg)
+		return
+	}
+	if request.MessageType == models.MessageTypeText {
+		request.Content = preSendContent
+	}
+
 	// 设置时间戳
 	if request.Timestamp == 0 {
 		request.Timestamp = time.Now().Unix()
 	}
 
 	// 查找目标用户的连接
-	targetClient := GetUserClient("", request.ToUserID)
+	targetClient := GetUserClient(client.AppID, request.ToUserID)
 	if targetClient == nil {
 		code = common.NotOnline
 		fmt.Println("发送消息 目标用户不在线", seq, request.ToUserID)
@@ -190,25 +327,55 @@ func SendMessageController(client *Client, seq string, message []byte) (code uin
 	}
 
 	// 构造转发消息
-	var forwardMessage string
+	var (
+		forwardMessage *models.Message
+		cmd            string
+		content        = request.Content
+	)
 	if request.MessageType == models.MessageTypeText {
-		forwardMessage = models.GetTextMsgData(client.UserID, seq, request.Content)
+		forwardMessage, cmd = models.NewMsg(client.UserID, request.Content), models.MessageCmdMsg
 	} else if request.MessageType == models.MessageTypeAudio {
-		forwardMessage = models.GetAudioMsgData(client.UserID, seq, request.Content)
+		audioData, audioFormat, adaptCode := adaptAudio(targetClient, clip, request.Content)
+		if adaptCode != common.OK {
+			code = adaptCode
+			fmt.Println("发送消息 接收方不支持该音频格式", seq, request.ToUserID, request.AudioFormat)
+			return
+		}
+		forwardMessage = models.NewAudioMsg(client.UserID, audioData, audioFormat, mediaInfo)
+		cmd, content = models.MessageCmdAudio, mediaInfo.MediaID
+	} else if request.MessageType == models.MessageTypeImage {
+		forwardMessage, cmd = models.NewImageMsg(client.UserID, mediaInfo), models.MessageCmdImage
+	}
+
+	// 接收方检查都通过后再保存语音
+	if request.MessageType == models.MessageTypeAudio {
+		if code = saveAudio(mediaInfo, rawAudio); code != common.OK {
+			fmt.Println("发送消息 保存音频失败", seq, mediaInfo.MediaID)
+			return
+		}
+	}
+
+	// 保存消息，分配会话内序号
+	record, code := saveChatMessage(client, request.ToUserID, request.MessageType, content, request.Timestamp)
+	if code != common.OK {
+		fmt.Println("发送消息 保存消息失败", seq, request.ToUserID)
+		return
 	}
 
 	// 发送消息给目标用户
-	targetClient.SendMsg([]byte(forwardMessage))
+	targetClient.SendMsg([]byte(models.GetMessageData(cmd, seq, forwardMessage.WithRecord(record))))
 
 	fmt.Println("发送消息 成功", seq, "from:", client.UserID, "to:", request.ToUserID, "type:", request.MessageType)
 
 	// 返回发送成功信息
 	data = map[string]interface{}{
-		"messageID":   seq,
-		"toUserID":    request.ToUserID,
-		"messageType": request.MessageType,
-		"timestamp":   request.Timestamp,
-		"status":      "sent",
+		"messageID":      record.MessageID,
+		"conversationID": record.ConversationID,
+		"seq":            record.Seq,
+		"toUserID":       request.ToUserID,
+		"messageType":    request.MessageType,
+		"timestamp":      request.Timestamp,
+		"status":         "sent",
 	}
 
 	return
@@ -245,47 +412,244 @@ func SendAudioMessageController(client *Client, seq string, message []byte) (cod
 		return
 	}
 
+	// 检查黑名单
+	if code = CheckBlocked(client.AppID, client.UserID, request.ToUserID); code != common.OK {
+		fmt.Println("发送音频消息 黑名单拦截", seq, client.UserID, request.ToUserID)
+		return
+	}
+
 	// 验证音频格式
 	if request.AudioFormat == "" {
-		request.AudioFormat = "pcm_16k" // 默认格式
+		request.AudioFormat = audio.FormatPCM16k // 默认格式
 	}
-	if request.AudioFormat != "pcm_16k" {
+	if !audio.IsDecodable(request.AudioFormat) {
 		code = common.ParameterIllegal
 		fmt.Println("发送音频消息 不支持的音频格式", seq, request.AudioFormat)
 		return
 	}
 
+	// 解码音频，校验客户端声明的时长，确定可以发送后再保存
+	mediaInfo, clip, rawAudio, code, msg := parseAudio(client, request.AudioFormat, request.AudioData,
+		request.Duration)
+	if code != common.OK {
+		fmt.Println("发送音频消息 音频处理失败", seq, msg, "duration:", request.Duration)
+		return
+	}
+	request.Duration = mediaInfo.Duration
+
+	// 发送前回调
+	_, code, msg = BeforeSend(&models.PreSendMessage{
+		AppID:       client.AppID,
+		Source:      models.PreSendSourceWS,
+		FromUserID:  client.UserID,
+		ToUserID:    request.ToUserID,
+		MessageType: models.MessageTypeAudio,
+		Content:     mediaInfo.MediaID,
+	})
+	if code != common.OK {
+		fmt.Println("发送音频消息 被发送前回调拦截", seq, client.UserID, request.ToUserID, code, msg)
+		return
+	}
+
 	// 设置时间戳
 	if request.Timestamp == 0 {
 		request.Timestamp = time.Now().Unix()
 	}
 
 	// 查找目标用户的连接
-	targetClient := GetUserClient("", request.ToUserID)
+	targetClient := GetUserClient(client.AppID, request.ToUserID)
 	if targetClient == nil {
 		code = common.NotOnline
 		fmt.Println("发送音频消息 目标用户不在线", seq, request.ToUserID)
 		return
 	}
 
+	// 按接收方能力转码
+	audioData, audioFormat, adaptCode := adaptAudio(targetClient, clip, request.AudioData)
+	if adaptCode != common.OK {
+		code = adaptCode
+		fmt.Println("发送音频消息 接收方不支持该音频格式", seq, request.ToUserID, request.AudioFormat)
+		return
+	}
+
+	// 接收方检查都通过后再保存语音
+	if code = saveAudio(mediaInfo, rawAudio); code != common.OK {
+		fmt.Println("发送音频消息 保存音频失败", seq, mediaInfo.MediaID)
+		return
+	}
+
+	// 保存消息，分配会话内序号
+	record, code := saveChatMessage(client, request.ToUserID, models.MessageTypeAudio, mediaInfo.MediaID,
+		request.Timestamp)
+	if code != common.OK {
+		fmt.Println("发送音频消息 保存消息失败", seq, request.ToUserID)
+		return
+	}
+
 	// 构造音频消息
-	forwardMessage := models.GetAudioMsgData(client.UserID, seq, request.AudioData)
+	forwardMessage := models.NewAudioMsg(client.UserID, audioData, audioFormat, mediaInfo).WithRecord(record)
 
 	// 发送消息给目标用户
-	targetClient.SendMsg([]byte(forwardMessage))
+	targetClient.SendMsg([]byte(models.GetMessageData(models.MessageCmdAudio, seq, forwardMessage)))
 
 	fmt.Println("发送音频消息 成功", seq, "from:", client.UserID, "to:", request.ToUserID, "duration:", request.Duration, "ms")
 
 	// 返回发送成功信息
 	data = map[string]interface{}{
-		"messageID":   seq,
-		"toUserID":    request.ToUserID,
-		"messageType": "audio",
-		"audioFormat": request.AudioFormat,
-		"duration":    request.Duration,
-		"timestamp":   request.Timestamp,
-		"status":      "sent",
+		"messageID":      record.MessageID,
+		"conversationID": record.ConversationID,
+		"seq":            record.Seq,
+		"toUserID":       request.ToUserID,
+		"messageType":    "audio",
+		"audioFormat":    request.AudioFormat,
+		"duration":       request.Duration,
+		"waveform":       mediaInfo.Waveform,
+		"mediaID":        mediaInfo.MediaID,
+		"timestamp":      request.Timestamp,
+		"status":         "sent",
+	}
+
+	return
+}
+
+// saveChatMessage 保存单聊消息，音频和图片消息的 content 为 mediaID
+func saveChatMessage(client *Client, toUserID, messageType, content string, timestamp int64) (
+	record *models.MessageRecord, code uint32) {
+	code = common.OK
+	record = &models.MessageRecord{
+		MessageID:      models.NewMessageID(client.UserID, toUserID),
+		AppID:          client.AppID,
+		ConversationID: models.GetConversationID(client.AppID, client.UserID, toUserID),
+		FromUserID:     client.UserID,
+		ToUserID:       toUserID,
+		Content:        content,
+		MessageType:    messageType,
+		Timestamp:      timestamp,
+	}
+	if err := cache.SaveMessage(record); err != nil {
+		code = common.ServerError
+		return
+	}
+	go NotifyConversation(client.AppID, record)
+	go webhook.Emit(client.AppID, models.WebhookEventMessageSend, record)
+	return
+}
+
+// parseAudio 解码语音，返回服务端计算的时长和波形，此时还没有保存
+func parseAudio(client *Client, audioFormat, audioData string, claimedDuration int) (mediaInfo *models.MediaInfo,
+	clip *audio.Clip, data []byte, code uint32, msg string) {
+	code = common.OK
+	mediaInfo, clip, data, err := media.ParseAudio(client.UserID, client.AppID, audioFormat, audioData,
+		claimedDuration)
+	if err != nil {
+		if audio.IsInvalid(err) {
+			code = common.ParameterIllegal
+			msg = err.Error()
+			return
+		}
+		code = common.ServerError
+		msg = "解析音频失败"
+	}
+	return
+}
+
+// saveAudio 保存 parseAudio 解析的语音，接收方检查都通过后调用，避免被拒绝的语音留在存储中
+func saveAudio(mediaInfo *models.MediaInfo, data []byte) (code uint32) {
+	code = common.OK
+	if err := media.SaveAudio(mediaInfo, data); err != nil {
+		fmt.Println("保存音频失败", mediaInfo.MediaID, err)
+		code = common.ServerError
+	}
+	return
+}
+
+// adaptAudio 按接收方声明的格式下发音频
+// 接收方支持原格式时原样转发，否则按声明顺序转码为接收方支持的第一个 PCM 或 WAV 格式
+// 未声明格式的老客户端按 pcm_16k 处理
+func adaptAudio(targetClient *Client, clip *audio.Clip, audioData string) (data string, format string, code uint32) {
+	code = common.OK
+	formats := targetClient.GetAudioFormats()
+	for _, value := range formats {
+		if value == clip.Format {
+			return audioData, clip.Format, code
+		}
+	}
+	for _, value := range formats {
+		var (
+			converted []byte
+			err       error
+		)
+		if audio.IsPCM(value) {
+			converted, err = clip.ToPCM(value)
+		} else if value == audio.FormatWAV {
+			converted, err = clip.ToWAV()
+		} else {
+			continue
+		}
+		if err != nil {
+			fmt.Println("音频转码失败", clip.Format, value, err)
+			continue
+		}
+		return base64.StdEncoding.EncodeToString(converted), value, code
+	}
+	code = common.AudioNotSupported
+	return
+}
+
+// SyncMessagesController 按会话 seq 同步消息
+func SyncMessagesController(client *Client, seq string, message []byte) (code uint32, msg string, data interface{}) {
+	code = common.OK
+
+	if !client.IsLogin() {
+		code = common.Unauthorized
+		fmt.Println("同步消息 用户未登录", seq)
+		return
+	}
+
+	request := &models.SyncMessages{}
+	if err := json.Unmarshal(message, request); err != nil {
+		code = common.ParameterIllegal
+		fmt.Println("同步消息 解析数据失败", seq, err)
+		return
+	}
+
+	results, code := SyncMessages(client.AppID, client.UserID, request)
+	if code != common.OK {
+		fmt.Println("同步消息 失败", seq, client.UserID, code)
+		return
+	}
+	data = map[string]interface{}{
+		"conversations": results,
+	}
+	return
+}
+
+// MarkReadController 上报会话已读游标
+func MarkReadController(client *Client, seq string, message []byte) (code uint32, msg string, data interface{}) {
+	code = common.OK
+
+	if !client.IsLogin() {
+		code = common.Unauthorized
+		fmt.Println("上报已读 用户未登录", seq)
+		return
+	}
+
+	request := &models.MarkRead{}
+	if err := json.Unmarshal(message, request); err != nil || request.ConversationID == "" {
+		code = common.ParameterIllegal
+		fmt.Println("上报已读 参数错误", seq, err)
+		return
 	}
 
+	readSeq, burnCount, code := MarkConversationRead(client.AppID, client.UserID, request.ConversationID, request.Seq)
+	if code != common.OK {
+		fmt.Println("上报已读 失败", seq, client.UserID, request.ConversationID, code)
+		return
+	}
+	data = map[string]interface{}{
+		"conversationID": request.ConversationID,
+		"readSeq":        readSeq,
+		"burnCount":      burnCount,
+	}
 	return
 }
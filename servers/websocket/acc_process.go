@@ -4,9 +4,11 @@ package websocket
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"sync"
 
 	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/lib/ratelimit"
 	"github.com/link1st/gowebsocket/v2/models"
 )
 
@@ -66,7 +68,13 @@ func ProcessData(client *Client, message []byte) {
 
 	// 采用 map 注册的方式
 	if value, ok := getHandlers(cmd); ok {
-		code, msg, data = value(client, seq, requestData)
+		var retryAfter int64
+		if code, retryAfter = checkRateLimit(client, cmd); code == common.OK {
+			code, msg, data = value(client, seq, requestData)
+		} else if code == common.TooManyRequests {
+			data = map[string]interface{}{"retryAfter": retryAfter}
+			fmt.Println("处理数据 请求过于频繁", client.Addr, client.UserID, "cmd", cmd, retryAfter)
+		}
 	} else {
 		code = common.RoutingNotExist
 		fmt.Println("处理数据 路由不存在", client.Addr, "cmd", cmd)
@@ -83,10 +91,28 @@ func ProcessData(client *Client, message []byte) {
 	return
 }
 
+// checkRateLimit 按连接、客户端IP、用户、应用限流，配置 rateLimit.ws.{cmd}
+func checkRateLimit(client *Client, cmd string) (code uint32, retryAfter int64) {
+	host, _, err := net.SplitHostPort(client.Addr)
+	if err != nil {
+		host = client.Addr
+	}
+	connectionID := fmt.Sprintf("%s:%s/%s/%d", serverIp, serverPort, client.Addr, client.FirstTime)
+	return ratelimit.Check(ratelimit.KindWS, cmd, client.AppID,
+		ratelimit.Subject{Dimension: models.RateLimitConnection, ID: connectionID},
+		ratelimit.Subject{Dimension: models.RateLimitIP, ID: host},
+		ratelimit.Subject{Dimension: models.RateLimitUser, ID: client.UserID},
+		ratelimit.Subject{Dimension: models.RateLimitApp, ID: client.AppID},
+	)
+}
+
 func init() {
 	Register("ping", PingController)
 	Register("login", LoginController)
+	Register("auth", ReauthController)
 	Register("heartbeat", HeartbeatController)
 	Register("sendMessage", SendMessageController)
 	Register("sendAudioMessage", SendAudioMessageController)
+	Register("syncMessages", SyncMessagesController)
+	Register("markRead", MarkReadController)
 }
@@ -0,0 +1,67 @@
+// Package websocket 处理
+package websocket
+
+import (
+	"unicode/utf8"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/lib/apps"
+	"github.com/link1st/gowebsocket/v2/lib/presend"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+// CheckMessageSize 检查应用是否可用以及消息内容是否超过应用限制的长度
+func CheckMessageSize(appID string, content string) (code uint32) {
+	app, code := apps.CheckApp(appID)
+	if code != common.OK {
+		return
+	}
+	if !utf8.ValidString(content) {
+		return common.ParameterIllegal
+	}
+	if len(content) > app.GetMaxMessageSize() {
+		return common.MessageTooLarge
+	}
+	return common.OK
+}
+
+// BeforeSend 执行发送前回调，返回实际发送的内容，内容被替换时重新检查长度
+func BeforeSend(message *models.PreSendMessage) (content string, code uint32, msg string) {
+	original := message.Content
+	content, code, msg = presend.Run(message)
+	if code != common.OK || content == original {
+		return
+	}
+	if content == "" {
+		return "", common.MessageRejected, ""
+	}
+	if code = CheckMessageSize(message.AppID, content); code != common.OK {
+		return "", code, "替换后的消息内容无效"
+	}
+	return
+}
+
+// DisconnectApp 立即断开本机上该应用的全部连接，应用被停用或删除时调用
+// 其他节点上的连接由 ClearDisabledAppConnections 定时检查断开
+func DisconnectApp(appID string) {
+	for _, client := range clientManager.GetUserClients() {
+		if client.AppID == appID {
+			closeClient(client, "应用已停用")
+		}
+	}
+}
+
+// ClearDisabledAppConnections 定时断开已停用应用的连接
+func ClearDisabledAppConnections() {
+	checked := make(map[string]uint32)
+	for _, client := range clientManager.GetUserClients() {
+		code, ok := checked[client.AppID]
+		if !ok {
+			_, code = apps.CheckApp(client.AppID)
+			checked[client.AppID] = code
+		}
+		if code == common.AppUnavailable {
+			closeClient(client, "应用已停用")
+		}
+	}
+}
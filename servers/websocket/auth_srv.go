@@ -0,0 +1,68 @@
+// Package websocket 处理
+package websocket
+
+import (
+	"fmt"
+
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/lib/jwtlib"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+// isClaimsRevoked 令牌是否已被吊销，查询失败时按已吊销处理
+func isClaimsRevoked(claims *jwtlib.Claims) (revoked bool) {
+	revoked, err := cache.IsClaimsRevoked(claims)
+	if err != nil {
+		return true
+	}
+	return
+}
+
+// closeClient 断开连接并清除在线状态
+func closeClient(client *Client, reason string) {
+	if err := cache.DelUserOnlineInfo(client.GetKey()); err != nil {
+		fmt.Println("断开连接 清除在线状态失败", client.Addr, client.AppID, client.UserID, err)
+	}
+	fmt.Println(reason, "关闭连接", client.Addr, client.AppID, client.UserID)
+	_ = client.Socket.Close()
+}
+
+// DisconnectToken 立即断开本机上使用该令牌登录的连接
+// 其他节点上的连接由 ClearRevokedConnections 定时检查断开
+func DisconnectToken(appID string, userID string, tokenID string) {
+	client := GetUserClient(appID, userID)
+	if client == nil || tokenID == "" || client.TokenID != tokenID {
+		return
+	}
+	closeClient(client, "令牌已吊销")
+}
+
+// DisconnectUser 立即断开本机上该用户在 appID 下的连接
+func DisconnectUser(appID string, userID string) {
+	for _, client := range clientManager.GetUserClients() {
+		if client.AppID == appID && client.UserID == userID {
+			closeClient(client, "用户令牌已全部吊销")
+		}
+	}
+}
+
+// ClearRevokedConnections 定时断开令牌已被吊销的连接
+func ClearRevokedConnections() {
+	clients := clientManager.GetUserClients()
+	if len(clients) == 0 {
+		return
+	}
+	refs := make([]models.TokenRef, len(clients))
+	for i, client := range clients {
+		refs[i] = client.GetTokenRef()
+	}
+	revoked, err := cache.GetRevokedTokens(refs)
+	if err != nil {
+		return
+	}
+	for i, client := range clients {
+		if revoked[i] {
+			closeClient(client, "令牌已吊销")
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Package websocket 处理
+package websocket
+
+import (
+	"github.com/spf13/viper"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+)
+
+// GetBlockedCode 被对方拉黑时返回的错误码，默认为通用的操作失败，不暴露拉黑关系
+func GetBlockedCode() (code uint32) {
+	code = viper.GetUint32("block.errorCode")
+	if code == 0 {
+		code = common.OperationFailure
+	}
+	return
+}
+
+// CheckBlocked fromUserID 给 toUserID 发消息、申请好友前检查黑名单
+// 自己拉黑了对方返回 UserBlocked，被对方拉黑返回 GetBlockedCode()
+func CheckBlocked(appID string, fromUserID string, toUserID string) (code uint32) {
+	code = common.OK
+	if fromUserID == "" || toUserID == "" || fromUserID == toUserID {
+		return
+	}
+	blocked, err := cache.IsBlocked(appID, fromUserID, toUserID)
+	if err != nil {
+		return common.ServerError
+	}
+	if blocked {
+		return common.UserBlocked
+	}
+	blocked, err = cache.IsBlocked(appID, toUserID, fromUserID)
+	if err != nil {
+		return common.ServerError
+	}
+	if blocked {
+		return GetBlockedCode()
+	}
+	return
+}
+
+// IsPresenceHidden 任意一方拉黑了对方时，互相看不到在线状态
+func IsPresenceHidden(appID string, userID string, targetID string) bool {
+	if blocked, _ := cache.IsBlocked(appID, userID, targetID); blocked {
+		return true
+	}
+	blocked, _ := cache.IsBlocked(appID, targetID, userID)
+	return blocked
+}
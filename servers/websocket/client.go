@@ -6,6 +6,10 @@ import (
 	"runtime/debug"
 
 	"github.com/gorilla/websocket"
+
+	"github.com/link1st/gowebsocket/v2/lib/audio"
+	"github.com/link1st/gowebsocket/v2/lib/jwtlib"
+	"github.com/link1st/gowebsocket/v2/models"
 )
 
 const (
@@ -38,6 +42,11 @@ type Client struct {
 	FirstTime     uint64          // 首次连接事件
 	HeartbeatTime uint64          // 用户上次心跳时间
 	LoginTime     uint64          // 登录时间 登录以后才有
+	AudioFormats  []string        // 可接收的音频格式 登录时声明
+	TokenID       string          // 当前认证令牌的 jti
+	TokenIssuedAt int64           // 当前认证令牌的签发时间(毫秒)
+	TokenExpireAt uint64          // 当前认证令牌的过期时间，过期前需要用新令牌重新认证
+	Origin        string          // 建立连接时浏览器的来源，登录时按应用配置校验
 }
 
 // NewClient 初始化
@@ -134,6 +143,53 @@ func (c *Client) Login(appID string, userID string, loginTime uint64) {
 	c.Heartbeat(loginTime)
 }
 
+// SetToken 记录连接当前使用的认证令牌
+func (c *Client) SetToken(claims *jwtlib.Claims) {
+	c.TokenID = claims.ID
+	c.TokenIssuedAt = 0
+	if claims.IssuedAt != nil {
+		c.TokenIssuedAt = claims.IssuedAt.UnixMilli()
+	}
+	c.TokenExpireAt = 0
+	if claims.ExpiresAt != nil {
+		c.TokenExpireAt = uint64(claims.ExpiresAt.Unix())
+	}
+}
+
+// GetTokenRef 当前认证令牌，用于检查吊销状态
+func (c *Client) GetTokenRef() (ref models.TokenRef) {
+	return models.TokenRef{
+		AppID:    c.AppID,
+		UserID:   c.UserID,
+		TokenID:  c.TokenID,
+		IssuedAt: c.TokenIssuedAt,
+	}
+}
+
+// IsTokenExpired 认证令牌是否已过期
+func (c *Client) IsTokenExpired(currentTime uint64) (expired bool) {
+	return c.TokenExpireAt > 0 && c.TokenExpireAt <= currentTime
+}
+
+// SetAudioFormats 设置可接收的音频格式，忽略不支持的格式
+func (c *Client) SetAudioFormats(formats []string) {
+	audioFormats := make([]string, 0, len(formats))
+	for _, format := range formats {
+		if audio.IsSupported(format) {
+			audioFormats = append(audioFormats, format)
+		}
+	}
+	c.AudioFormats = audioFormats
+}
+
+// GetAudioFormats 可接收的音频格式，未声明的老客户端只支持 pcm_16k
+func (c *Client) GetAudioFormats() (formats []string) {
+	if len(c.AudioFormats) == 0 {
+		return []string{audio.FormatPCM16k}
+	}
+	return c.AudioFormats
+}
+
 // Heartbeat 用户心跳
 func (c *Client) Heartbeat(currentTime uint64) {
 	c.HeartbeatTime = currentTime
@@ -8,6 +8,7 @@ import (
 
 	"github.com/link1st/gowebsocket/v2/helper"
 	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/lib/webhook"
 	"github.com/link1st/gowebsocket/v2/models"
 )
 
@@ -36,10 +37,10 @@ func NewClientManager() (clientManager *ClientManager) {
 	return
 }
 
-// GetUserKey 获取用户key
+// GetUserKey 获取用户key，在线状态和连接路由按 appID 隔离
 func GetUserKey(appID string, userID string) (key string) {
-	// key = fmt.Sprintf("%s_%s", appID, userID)
-	return userID
+	key = fmt.Sprintf("%s_%s", appID, userID)
+	return
 }
 
 func (manager *ClientManager) InClient(client *Client) (ok bool) {
@@ -209,6 +210,10 @@ func (manager *ClientManager) EventLogin(login *login) {
 	fmt.Println("EventLogin 用户登录", client.Addr, login.AppID, login.UserID)
 	orderID := helper.GetOrderIDTime()
 	_, _ = SendUserMessageAll(login.AppID, login.UserID, orderID, models.MessageCmdEnter, "哈喽~")
+	go webhook.Emit(login.AppID, models.WebhookEventUserOnline, map[string]interface{}{
+		"userID": login.UserID,
+		"addr":   client.Addr,
+	})
 }
 
 // EventUnregister 用户断开连接
@@ -240,6 +245,10 @@ func (manager *ClientManager) EventUnregister(client *Client) {
 	if client.UserID != "" {
 		orderID := helper.GetOrderIDTime()
 		_, _ = SendUserMessageAll(client.AppID, client.UserID, orderID, models.MessageCmdExit, "用户已经离开~")
+		go webhook.Emit(client.AppID, models.WebhookEventUserOffline, map[string]interface{}{
+			"userID": client.UserID,
+			"addr":   client.Addr,
+		})
 	}
 }
 
@@ -304,12 +313,13 @@ func ClearTimeoutConnections() {
 	clients := clientManager.GetClients()
 	fmt.Println("clients", clients)
 	for client := range clients {
-		if client.IsHeartbeatTimeout(currentTime) {
+		if client.IsHeartbeatTimeout(currentTime) || client.IsTokenExpired(currentTime) {
 			err := cache.DelUserOnlineInfo(client.GetKey())
 			if err != nil {
 				fmt.Println("ClearTimeoutConnections 定时清理超时连接失败", client.Addr, client.AppID, client.UserID, err)
 			}
-			fmt.Println("心跳时间超时 关闭连接", client.Addr, client.UserID, client.LoginTime, client.HeartbeatTime)
+			fmt.Println("心跳超时或令牌过期 关闭连接", client.Addr, client.UserID, client.LoginTime, client.HeartbeatTime,
+				client.TokenExpireAt)
 			_ = client.Socket.Close()
 		}
 	}
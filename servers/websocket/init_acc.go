@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/link1st/gowebsocket/v2/helper"
+	"github.com/link1st/gowebsocket/v2/lib/apps"
 	"github.com/link1st/gowebsocket/v2/models"
 
 	"github.com/gorilla/websocket"
@@ -19,14 +20,13 @@ const (
 
 var (
 	clientManager = NewClientManager() // 管理者
-	appIDs        = []string{}         // 全部的平台(房间)
 	serverIp      string
 	serverPort    string
 )
 
 // GetAppIDs 所有平台
 func GetAppIDs() []string {
-	return appIDs
+	return apps.GetAppIDs()
 }
 
 // GetServer 获取服务器
@@ -88,6 +88,7 @@ func wsPage(w http.ResponseWriter, req *http.Request) {
 	fmt.Println("webSocket 建立连接:", conn.RemoteAddr().String())
 	currentTime := uint64(time.Now().Unix())
 	client := NewClient(conn.RemoteAddr().String(), conn, currentTime)
+	client.Origin = req.Header.Get("Origin")
 	go client.read()
 	go client.write()
 
@@ -0,0 +1,145 @@
+// Package websocket 处理
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/link1st/gowebsocket/v2/common"
+	"github.com/link1st/gowebsocket/v2/helper"
+	"github.com/link1st/gowebsocket/v2/lib/cache"
+	"github.com/link1st/gowebsocket/v2/models"
+)
+
+const (
+	syncDefaultLimit     = 50  // 每个会话默认返回的消息数
+	syncMaxLimit         = 200 // 每个会话最多返回的消息数
+	syncMaxConversations = 100 // 单次最多同步的会话数
+)
+
+// SyncResult 单个会话的同步结果
+type SyncResult struct {
+	ConversationID string                  `json:"conversationID"` // 会话ID
+	Messages       []*models.MessageRecord `json:"messages"`       // 消息，按 seq 升序
+	Cursor         int64                   `json:"cursor"`         // 下次同步使用的游标
+	HasMore        bool                    `json:"hasMore"`        // 是否还有更多消息
+	MaxSeq         int64                   `json:"maxSeq"`         // 会话当前最大 seq
+}
+
+// SyncMessages 按 seq 同步消息
+// 传 Conversations 时返回每个会话中 seq 大于客户端已有 seq 的消息，cursor 为下次同步的起点
+// 传 ConversationID 时返回 seq 小于 BeforeSeq 的消息，cursor 为继续向前翻页的 BeforeSeq
+func SyncMessages(appID string, userID string, request *models.SyncMessages) (results []*SyncResult, code uint32) {
+	code = common.OK
+	limit := request.Limit
+	if limit <= 0 {
+		limit = syncDefaultLimit
+	}
+	if limit > syncMaxLimit {
+		limit = syncMaxLimit
+	}
+
+	if request.ConversationID != "" {
+		if !models.IsConversationMember(request.ConversationID, appID, userID) {
+			code = common.ParameterIllegal
+			return
+		}
+		result := &SyncResult{ConversationID: request.ConversationID}
+		var err error
+		result.Messages, result.Cursor, result.HasMore, err = cache.GetMessagesBeforeSeq(request.ConversationID,
+			request.BeforeSeq, limit)
+		if err != nil {
+			code = common.ServerError
+			return
+		}
+		result.MaxSeq, _ = cache.GetConversationSeq(request.ConversationID)
+		results = []*SyncResult{result}
+		return
+	}
+
+	if len(request.Conversations) == 0 || len(request.Conversations) > syncMaxConversations {
+		code = common.ParameterIllegal
+		return
+	}
+	results = make([]*SyncResult, 0, len(request.Conversations))
+	for conversationID, lastSeq := range request.Conversations {
+		if !models.IsConversationMember(conversationID, appID, userID) {
+			fmt.Println("同步消息 不是会话成员", userID, conversationID)
+			code = common.ParameterIllegal
+			return nil, code
+		}
+		result := &SyncResult{ConversationID: conversationID}
+		var err error
+		result.Messages, result.Cursor, result.HasMore, err = cache.GetMessagesAfterSeq(conversationID, lastSeq, limit)
+		if err != nil {
+			code = common.ServerError
+			return nil, code
+		}
+		result.MaxSeq, _ = cache.GetConversationSeq(conversationID)
+		results = append(results, result)
+	}
+	return
+}
+
+// MarkConversationRead 上报已读游标，seq<=0 或超过会话最大 seq 时按全部已读处理
+// 游标变化会推送给用户的其他设备，阅后即焚会话的已读消息交给定时任务清理
+func MarkConversationRead(appID string, userID string, conversationID string, seq int64) (readSeq int64,
+	burnCount int, code uint32) {
+	code = common.OK
+	if !models.IsConversationMember(conversationID, appID, userID) {
+		code = common.ParameterIllegal
+		return
+	}
+	maxSeq, err := cache.GetConversationSeq(conversationID)
+	if err != nil {
+		code = common.ServerError
+		return
+	}
+	if seq <= 0 || seq > maxSeq {
+		seq = maxSeq
+	}
+	readSeq, err = cache.SetReadSeq(appID, userID, conversationID, seq)
+	if err != nil {
+		code = common.ServerError
+		return
+	}
+
+	policy, err := cache.GetExpirePolicy(conversationID)
+	if err == nil && policy.IsBurnAfterRead() {
+		burnCount, err = cache.BurnReadMessages(conversationID, userID, time.Now().Unix())
+		if err != nil {
+			fmt.Println("阅后即焚登记失败", conversationID, userID, err)
+		}
+	}
+
+	go PushConversation(appID, userID, conversationID)
+	return
+}
+
+// NotifyConversation 新消息保存后给会话双方推送最新的会话列表项
+func NotifyConversation(appID string, record *models.MessageRecord) {
+	userIDs := []string{record.FromUserID}
+	if record.ToUserID != "" && record.ToUserID != record.FromUserID {
+		userIDs = append(userIDs, record.ToUserID)
+	}
+	for _, userID := range userIDs {
+		PushConversation(appID, userID, record.ConversationID)
+	}
+}
+
+// PushConversation 给用户推送会话列表项
+func PushConversation(appID string, userID string, conversationID string) {
+	conversation, err := cache.GetConversation(appID, userID, conversationID)
+	if err != nil {
+		fmt.Println("推送会话 获取会话失败", userID, conversationID, err)
+		return
+	}
+	conversationByte, err := json.Marshal(conversation)
+	if err != nil {
+		fmt.Println("推送会话 json Marshal", userID, conversationID, err)
+		return
+	}
+	_, _ = SendUserCmdMessage(appID, userID, helper.GetOrderIDTime(), models.MessageCmdConversation,
+		string(conversationByte))
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/link1st/gowebsocket/v2/common"
 	"github.com/link1st/gowebsocket/v2/lib/cache"
 	"github.com/link1st/gowebsocket/v2/models"
 	"github.com/link1st/gowebsocket/v2/servers/grpcclient"
@@ -74,7 +75,27 @@ func checkUserOnline(appID string, userID string) (online bool, err error) {
 
 // SendUserMessage 给用户发送消息
 func SendUserMessage(appID string, userID string, msgID, message string) (sendResults bool, err error) {
-	data := models.GetTextMsgData(userID, msgID, message)
+	return SendUserCmdMessage(appID, userID, msgID, models.MessageCmdMsg, message)
+}
+
+// SendUserCmdMessage 给用户发送指定 cmd 的消息
+func SendUserCmdMessage(appID string, userID string, msgID, cmd, message string) (sendResults bool, err error) {
+	return sendUserMessage(appID, "", userID, msgID, cmd, message)
+}
+
+// SendUserMessageFrom 代 fromUserID 给用户发送消息，接收方所在节点会再次检查黑名单
+func SendUserMessageFrom(appID string, fromUserID string, userID string, msgID, message string) (sendResults bool,
+	err error) {
+	if code := CheckBlocked(appID, fromUserID, userID); code != common.OK {
+		fmt.Println("给用户发送消息 黑名单拦截", fromUserID, userID, code)
+		return false, nil
+	}
+	return sendUserMessage(appID, fromUserID, userID, msgID, models.MessageCmdMsg, message)
+}
+
+func sendUserMessage(appID string, fromUserID string, userID string, msgID, cmd, message string) (sendResults bool,
+	err error) {
+	data := models.GetMsgData(userID, msgID, cmd, message)
 	client := GetUserClient(appID, userID)
 	if client != nil {
 		// 在本机发送
@@ -95,7 +116,7 @@ func SendUserMessage(appID string, userID string, msgID, message string) (sendRe
 		return false, nil
 	}
 	server := models.NewServer(info.AccIp, info.AccPort)
-	msg, err := grpcclient.SendMsg(server, msgID, appID, userID, models.MessageCmdMsg, models.MessageCmdMsg, message)
+	msg, err := grpcclient.SendMsg(server, msgID, appID, fromUserID, userID, cmd, models.MessageCmdMsg, message)
 	if err != nil {
 		fmt.Println("给用户发送消息失败", key, err)
 		return false, err